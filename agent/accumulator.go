@@ -2,6 +2,7 @@ package agent
 
 import (
 	"log"
+	"sync"
 	"time"
 
 	"github.com/influxdata/telegraf"
@@ -167,3 +168,102 @@ func (a *trackingAccumulator) onDelivery(info telegraf.DeliveryInfo) {
 		panic("channel is full")
 	}
 }
+
+// abandonableAccumulator passes metrics to an accumulator until it is
+// abandoned, after which they are dropped.  It is used for gathers that
+// cannot be cancelled, so that a gather returning after it was given up on
+// does not add metrics once its input is stopped.
+type abandonableAccumulator struct {
+	telegraf.Accumulator
+
+	mu        sync.RWMutex
+	abandoned bool
+}
+
+// abandon drops all further metrics.  It waits for metrics being added, once
+// it returns the underlying accumulator is not used anymore.
+func (ac *abandonableAccumulator) abandon() {
+	ac.mu.Lock()
+	ac.abandoned = true
+	ac.mu.Unlock()
+}
+
+func (ac *abandonableAccumulator) AddFields(
+	measurement string,
+	fields map[string]interface{},
+	tags map[string]string,
+	t ...time.Time,
+) {
+	ac.mu.RLock()
+	defer ac.mu.RUnlock()
+	if !ac.abandoned {
+		ac.Accumulator.AddFields(measurement, fields, tags, t...)
+	}
+}
+
+func (ac *abandonableAccumulator) AddGauge(
+	measurement string,
+	fields map[string]interface{},
+	tags map[string]string,
+	t ...time.Time,
+) {
+	ac.mu.RLock()
+	defer ac.mu.RUnlock()
+	if !ac.abandoned {
+		ac.Accumulator.AddGauge(measurement, fields, tags, t...)
+	}
+}
+
+func (ac *abandonableAccumulator) AddCounter(
+	measurement string,
+	fields map[string]interface{},
+	tags map[string]string,
+	t ...time.Time,
+) {
+	ac.mu.RLock()
+	defer ac.mu.RUnlock()
+	if !ac.abandoned {
+		ac.Accumulator.AddCounter(measurement, fields, tags, t...)
+	}
+}
+
+func (ac *abandonableAccumulator) AddSummary(
+	measurement string,
+	fields map[string]interface{},
+	tags map[string]string,
+	t ...time.Time,
+) {
+	ac.mu.RLock()
+	defer ac.mu.RUnlock()
+	if !ac.abandoned {
+		ac.Accumulator.AddSummary(measurement, fields, tags, t...)
+	}
+}
+
+func (ac *abandonableAccumulator) AddHistogram(
+	measurement string,
+	fields map[string]interface{},
+	tags map[string]string,
+	t ...time.Time,
+) {
+	ac.mu.RLock()
+	defer ac.mu.RUnlock()
+	if !ac.abandoned {
+		ac.Accumulator.AddHistogram(measurement, fields, tags, t...)
+	}
+}
+
+func (ac *abandonableAccumulator) AddMetric(m telegraf.Metric) {
+	ac.mu.RLock()
+	defer ac.mu.RUnlock()
+	if !ac.abandoned {
+		ac.Accumulator.AddMetric(m)
+	}
+}
+
+func (ac *abandonableAccumulator) WithTracking(maxTracked int) telegraf.TrackingAccumulator {
+	return &trackingAccumulator{
+		Accumulator: ac,
+		delivered:   make(chan telegraf.DeliveryInfo, maxTracked),
+	}
+}
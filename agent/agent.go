@@ -2,6 +2,7 @@ package agent
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"runtime"
@@ -11,6 +12,7 @@ import (
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/internal"
 	"github.com/influxdata/telegraf/internal/config"
+	"github.com/influxdata/telegraf/internal/cron"
 	"github.com/influxdata/telegraf/internal/models"
 	"github.com/influxdata/telegraf/plugins/serializers/influx"
 )
@@ -18,6 +20,36 @@ import (
 // Agent runs a set of plugins.
 type Agent struct {
 	Config *config.Config
+
+	// RequestReload is called when a configuration reload is requested
+	// using the API.  Reloading is not supported when nil.
+	RequestReload func()
+
+	// pluginsMu protects the plugin lists of the Config while running, they
+	// are replaced when the configuration is reloaded.
+	pluginsMu sync.RWMutex
+
+	// reloadMu serializes reloads and protects the pipeline state below.
+	reloadMu       sync.Mutex
+	running        bool
+	startTime      time.Time
+	inputC         chan<- telegraf.Metric
+	aggC           chan telegraf.Metric
+	inputs         *pluginGroup
+	aggregators    *pluginGroup
+	outputs        *pluginGroup
+	hasProcessors  bool
+	hasAggregators bool
+	// Number of goroutines running the processors.
+	processorShards int
+
+	// router selects the outputs of each metric, nil when every metric is
+	// sent to all outputs.  Protected by pluginsMu.
+	router *router
+
+	// cardinality limits the series sent to the outputs, nil when they are
+	// not limited.  Only used by runOutputs.
+	cardinality *cardinalityGuard
 }
 
 // NewAgent returns an Agent for the given Config.
@@ -45,12 +77,46 @@ func (a *Agent) Run(ctx context.Context) error {
 		return err
 	}
 
+	err = models.LinkDeadLetterOutputs(a.Config.Outputs)
+	if err != nil {
+		return err
+	}
+
+	err = checkRoutes(a.Config.Agent.Routing, a.Config.Outputs)
+	if err != nil {
+		return err
+	}
+	a.router, err = newRouter(a.Config.Agent.Routing, a.Config.Outputs)
+	if err != nil {
+		return err
+	}
+	a.cardinality, err = newCardinalityGuard(a.Config.Agent.Cardinality)
+	if err != nil {
+		return err
+	}
+
 	log.Printf("D! [agent] Connecting outputs")
 	err = a.connectOutputs(ctx)
 	if err != nil {
 		return err
 	}
 
+	if a.Config.Agent.APIAddress != "" {
+		server, err := a.startAPI(a.Config.Agent.APIAddress)
+		if err != nil {
+			return fmt.Errorf("could not start API: %v", err)
+		}
+		defer stopServer(server)
+	}
+
+	if a.Config.Agent.MetricsAddress != "" {
+		server, err := startMetrics(a.Config.Agent.MetricsAddress)
+		if err != nil {
+			return fmt.Errorf("could not start metrics endpoint: %v", err)
+		}
+		defer stopServer(server)
+	}
+
 	inputC := make(chan telegraf.Metric, 100)
 	procC := make(chan telegraf.Metric, 100)
 	outputC := make(chan telegraf.Metric, 100)
@@ -63,6 +129,19 @@ func (a *Agent) Run(ctx context.Context) error {
 		return err
 	}
 
+	a.reloadMu.Lock()
+	a.startTime = startTime
+	a.inputC = inputC
+	a.aggC = make(chan telegraf.Metric, 100)
+	a.inputs = newPluginGroup(ctx)
+	a.aggregators = newPluginGroup(context.Background())
+	a.outputs = newPluginGroup(context.Background())
+	a.hasProcessors = len(a.Config.Processors) > 0
+	a.hasAggregators = len(a.Config.Aggregators) > 0
+	a.processorShards = maxConcurrency(a.Config.Processors)
+	a.running = true
+	a.reloadMu.Unlock()
+
 	var wg sync.WaitGroup
 
 	src := inputC
@@ -213,16 +292,16 @@ func (a *Agent) Test(ctx context.Context, waitDuration time.Duration) error {
 		case "cpu", "mongodb", "procstat":
 			nulAcc := NewAccumulator(input, nulC)
 			nulAcc.SetPrecision(a.Precision())
-			if err := input.Input.Gather(nulAcc); err != nil {
+			if err := input.Gather(ctx, nulAcc); err != nil {
 				acc.AddError(err)
 			}
 
 			time.Sleep(500 * time.Millisecond)
-			if err := input.Input.Gather(acc); err != nil {
+			if err := input.Gather(ctx, acc); err != nil {
 				acc.AddError(err)
 			}
 		default:
-			if err := input.Input.Gather(acc); err != nil {
+			if err := input.Gather(ctx, acc); err != nil {
 				acc.AddError(err)
 			}
 		}
@@ -244,43 +323,63 @@ func (a *Agent) Test(ctx context.Context, waitDuration time.Duration) error {
 // runInputs starts and triggers the periodic gather for Inputs.
 //
 // When the context is done the timers are stopped and this function returns
-// after all ongoing Gather calls complete.
+// after all ongoing Gather calls complete or, for inputs without
+// GatherContext, are abandoned.
 func (a *Agent) runInputs(
 	ctx context.Context,
 	startTime time.Time,
 	dst chan<- telegraf.Metric,
 ) error {
-	var wg sync.WaitGroup
+	a.reloadMu.Lock()
 	for _, input := range a.Config.Inputs {
-		interval := a.Config.Agent.Interval.Duration
-		jitter := a.Config.Agent.CollectionJitter.Duration
+		a.startInput(input, startTime, dst)
+	}
+	a.reloadMu.Unlock()
 
-		// Overwrite agent interval if this plugin has its own.
-		if input.Config.Interval != 0 {
-			interval = input.Config.Interval
-		}
+	<-ctx.Done()
 
-		acc := NewAccumulator(input, dst)
-		acc.SetPrecision(a.Precision())
+	// No reloads are applied once the agent is stopping.
+	a.reloadMu.Lock()
+	a.running = false
+	a.reloadMu.Unlock()
 
-		wg.Add(1)
-		go func(input *models.RunningInput) {
-			defer wg.Done()
+	a.inputs.Close()
+	return nil
+}
 
-			if a.Config.Agent.RoundInterval {
-				err := internal.SleepContext(
-					ctx, internal.AlignDuration(startTime, interval))
-				if err != nil {
-					return
-				}
-			}
+// startInput starts the periodic gather for a single Input.
+func (a *Agent) startInput(
+	input *models.RunningInput,
+	startTime time.Time,
+	dst chan<- telegraf.Metric,
+) bool {
+	interval := a.Config.Agent.Interval.Duration
+	jitter := a.Config.Agent.CollectionJitter.Duration
 
-			a.gatherOnInterval(ctx, acc, input, interval, jitter)
-		}(input)
+	// Overwrite agent interval if this plugin has its own.
+	if input.Config.Interval != 0 {
+		interval = input.Config.Interval
 	}
-	wg.Wait()
 
-	return nil
+	acc := NewAccumulator(input, dst)
+	acc.SetPrecision(a.Precision())
+
+	return a.inputs.Start(input, func(ctx context.Context) {
+		if input.Config.Schedule != nil {
+			a.gatherOnSchedule(ctx, acc, input, input.Config.Schedule)
+			return
+		}
+
+		if a.Config.Agent.RoundInterval {
+			err := internal.SleepContext(
+				ctx, internal.AlignDuration(startTime, interval))
+			if err != nil {
+				return
+			}
+		}
+
+		a.gatherOnInterval(ctx, acc, input, interval, jitter)
+	})
 }
 
 // gather runs an input's gather function periodically until the context is
@@ -294,75 +393,292 @@ func (a *Agent) gatherOnInterval(
 ) {
 	defer panicRecover(input)
 
-	ticker := time.NewTicker(interval)
+	ticker := NewTicker(interval, jitter)
 	defer ticker.Stop()
 
-	for {
-		err := internal.SleepContext(ctx, internal.RandomDuration(jitter))
-		if err != nil {
-			return
-		}
-
-		err = a.gatherOnce(acc, input, interval)
-		if err != nil {
-			acc.AddError(err)
-		}
-
-		select {
-		case <-ticker.C:
-			continue
-		case <-ctx.Done():
-			return
-		}
+	err := internal.SleepContext(ctx, internal.RandomDuration(jitter))
+	if err != nil {
+		return
 	}
+
+	a.gatherOnTicks(ctx, acc, input, ticker.C, true)
 }
 
-// gatherOnce runs the input's Gather function once, logging a warning each
-// interval it fails to complete before.
-func (a *Agent) gatherOnce(
+// gatherOnSchedule runs an input's gather function at the times matching its
+// schedule until the context is done.
+func (a *Agent) gatherOnSchedule(
+	ctx context.Context,
 	acc telegraf.Accumulator,
 	input *models.RunningInput,
-	timeout time.Duration,
-) error {
-	ticker := time.NewTicker(timeout)
+	schedule *cron.Schedule,
+) {
+	defer panicRecover(input)
+
+	ticker := NewScheduleTicker(schedule, func(next time.Time) {
+		log.Printf("I! [agent] [%s] next gather scheduled at %s",
+			input.LogName(), next.Format(time.RFC3339))
+	})
 	defer ticker.Stop()
 
-	done := make(chan error)
+	a.gatherOnTicks(ctx, acc, input, ticker.C, false)
+}
+
+// gather is a single running call of an input's Gather function.
+type gather struct {
+	done    chan error
+	cancel  context.CancelFunc
+	timeout <-chan time.Time
+
+	// cancelled is set when the gather was cancelled by the agent, its
+	// result has already been reported.
+	cancelled bool
+
+	// replies are the gather requests waiting for the result.
+	replies []chan error
+
+	// acc is set when the input does not implement GatherContext, the gather
+	// cannot be stopped and is abandoned instead when the input is stopped.
+	acc *abandonableAccumulator
+}
+
+// startGather calls the input's Gather function in a new goroutine.  The
+// context passed to the input is cancelled when the gather_timeout elapses.
+func (a *Agent) startGather(
+	ctx context.Context,
+	acc telegraf.Accumulator,
+	input *models.RunningInput,
+) *gather {
+	ctx, cancel := context.WithCancel(ctx)
+	g := &gather{
+		done:   make(chan error, 1),
+		cancel: cancel,
+	}
+	if input.Config.GatherTimeout > 0 {
+		g.timeout = time.After(input.Config.GatherTimeout)
+	}
+	if _, ok := input.Input.(telegraf.ContextGatherer); !ok {
+		g.acc = &abandonableAccumulator{Accumulator: acc}
+		acc = g.acc
+	}
+
 	go func() {
-		done <- input.Gather(acc)
+		g.done <- input.Gather(ctx, acc)
 	}()
+	return g
+}
+
+// gatherOnTicks runs the input's Gather function each time a tick is
+// received, and once at the start if gatherNow is true, until the context is
+// done.
+//
+// Ticks received while a gather is still running are handled according to
+// the overlap_policy of the input.  A new gather is never started before the
+// previous one returns, even if it was cancelled, as the input may not be
+// safe for concurrent use.  Only when the input is stopped is a gather of an
+// input without GatherContext abandoned, the metrics it adds once it returns
+// are dropped.
+func (a *Agent) gatherOnTicks(
+	ctx context.Context,
+	acc telegraf.Accumulator,
+	input *models.RunningInput,
+	ticks <-chan time.Time,
+	gatherNow bool,
+) {
+	var running *gather
+	var queued bool
+	var pending []chan error
+
+	if gatherNow {
+		running = a.startGather(ctx, acc, input)
+	}
+
+	// next starts the queued gather, if any, once the running one returned.
+	next := func() {
+		running = nil
+		if queued {
+			queued = false
+			running = a.startGather(ctx, acc, input)
+			running.replies = pending
+			pending = nil
+		}
+	}
 
 	for {
+		var done <-chan error
+		var timeout <-chan time.Time
+		if running != nil {
+			done = running.done
+			timeout = running.timeout
+		}
+
 		select {
-		case err := <-done:
-			return err
-		case <-ticker.C:
+		case <-ticks:
+			if running == nil {
+				running = a.startGather(ctx, acc, input)
+				continue
+			}
+
 			log.Printf("W! [agent] [%s] did not complete within its interval",
 				input.LogName())
+			switch input.Config.OverlapPolicy {
+			case models.OverlapSkip:
+				input.GatherSkipped.Incr(1)
+			case models.OverlapCancel:
+				if !running.cancelled {
+					running.cancelled = true
+					running.cancel()
+					a.reportGather(acc, running,
+						errors.New("gather cancelled by the next gather"))
+				}
+				queued = true
+			default:
+				queued = true
+			}
+		case reply := <-input.GatherRequest:
+			if running == nil {
+				running = a.startGather(ctx, acc, input)
+				running.replies = append(running.replies, reply)
+				continue
+			}
+			pending = append(pending, reply)
+			queued = true
+		case <-timeout:
+			running.timeout = nil
+			input.GatherTimeouts.Incr(1)
+			if !running.cancelled {
+				running.cancelled = true
+				running.cancel()
+				a.reportGather(acc, running, fmt.Errorf(
+					"gather did not complete within gather_timeout of %s",
+					input.Config.GatherTimeout))
+			}
+			if running.acc != nil {
+				log.Printf("W! [agent] [%s] gather cannot be cancelled, "+
+					"waiting for it to return before the next gather", input.LogName())
+			}
+		case err := <-done:
+			running.cancel()
+			if !running.cancelled {
+				a.reportGather(acc, running, err)
+			}
+			next()
+		case <-ctx.Done():
+			// Wait for the gather so that no metrics are added once the
+			// input is stopped, a gather which cannot be cancelled is
+			// abandoned instead so that it does not block the shutdown.
+			if running != nil {
+				running.cancel()
+				if running.acc != nil {
+					a.abandonGather(input, running)
+				} else {
+					<-running.done
+				}
+				for _, reply := range append(running.replies, pending...) {
+					reply <- ctx.Err()
+				}
+			}
+			return
 		}
 	}
 }
 
+// abandonGather stops waiting for a gather that cannot be cancelled when the
+// input is stopped, the metrics it adds from now on are dropped.
+func (a *Agent) abandonGather(input *models.RunningInput, g *gather) {
+	log.Printf("W! [agent] [%s] abandoning gather which cannot be cancelled",
+		input.LogName())
+	g.acc.abandon()
+}
+
+// reportGather reports the result of a gather to the accumulator and the
+// waiting gather requests.
+func (a *Agent) reportGather(
+	acc telegraf.Accumulator,
+	g *gather,
+	err error,
+) {
+	if err != nil {
+		acc.AddError(err)
+	}
+	for _, reply := range g.replies {
+		reply <- err
+	}
+	g.replies = nil
+}
+
 // runProcessors applies processors to metrics.
+//
+// When a processor has a concurrency greater than one, metrics are sharded by
+// series across that many goroutines, preserving the order of each series.
 func (a *Agent) runProcessors(
 	src <-chan telegraf.Metric,
 	agg chan<- telegraf.Metric,
 ) error {
-	for metric := range src {
-		metrics := a.applyProcessors(metric)
+	a.reloadMu.Lock()
+	shards := a.processorShards
+	a.reloadMu.Unlock()
 
-		for _, metric := range metrics {
-			agg <- metric
+	if shards <= 1 {
+		for metric := range src {
+			metrics := a.applyProcessors(metric)
+
+			for _, metric := range metrics {
+				agg <- metric
+			}
 		}
+
+		return nil
+	}
+
+	var wg sync.WaitGroup
+	workers := make([]chan telegraf.Metric, shards)
+	for i := range workers {
+		workers[i] = make(chan telegraf.Metric, 100)
+
+		wg.Add(1)
+		go func(src <-chan telegraf.Metric) {
+			defer wg.Done()
+			for metric := range src {
+				metrics := a.applyProcessors(metric)
+
+				for _, metric := range metrics {
+					agg <- metric
+				}
+			}
+		}(workers[i])
+	}
+
+	for metric := range src {
+		workers[metric.HashID()%uint64(shards)] <- metric
+	}
+
+	for _, worker := range workers {
+		close(worker)
 	}
+	wg.Wait()
 
 	return nil
 }
 
+// maxConcurrency returns the highest concurrency of the processors.
+func maxConcurrency(processors []*models.RunningProcessor) int {
+	max := 1
+	for _, processor := range processors {
+		if processor.Config.Concurrency > max {
+			max = processor.Config.Concurrency
+		}
+	}
+	return max
+}
+
 // applyProcessors applies all processors to a metric.
 func (a *Agent) applyProcessors(m telegraf.Metric) []telegraf.Metric {
+	a.pluginsMu.RLock()
+	processors := a.Config.Processors
+	a.pluginsMu.RUnlock()
+
 	metrics := []telegraf.Metric{m}
-	for _, processor := range a.Config.Processors {
+	for _, processor := range processors {
 		metrics = processor.Apply(metrics...)
 	}
 
@@ -395,22 +711,27 @@ func (a *Agent) runAggregators(
 	src <-chan telegraf.Metric,
 	dst chan<- telegraf.Metric,
 ) error {
-	ctx, cancel := context.WithCancel(context.Background())
-
 	// Before calling Add, initialize the aggregation window.  This ensures
 	// that any metric created after start time will be aggregated.
+	a.reloadMu.Lock()
 	for _, agg := range a.Config.Aggregators {
 		since, until := updateWindow(startTime, a.Config.Agent.RoundInterval, agg.Period())
 		agg.UpdateWindow(since, until)
+		a.startAggregator(agg)
 	}
+	a.reloadMu.Unlock()
 
 	var wg sync.WaitGroup
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
 		for metric := range src {
+			a.pluginsMu.RLock()
+			aggregators := a.Config.Aggregators
+			a.pluginsMu.RUnlock()
+
 			var dropOriginal bool
-			for _, agg := range a.Config.Aggregators {
+			for _, agg := range aggregators {
 				if ok := agg.Add(metric); ok {
 					dropOriginal = true
 				}
@@ -422,31 +743,12 @@ func (a *Agent) runAggregators(
 				metric.Drop()
 			}
 		}
-		cancel()
-	}()
-
-	aggregations := make(chan telegraf.Metric, 100)
-	wg.Add(1)
-	go func() {
-		defer wg.Done()
-
-		var aggWg sync.WaitGroup
-		for _, agg := range a.Config.Aggregators {
-			aggWg.Add(1)
-			go func(agg *models.RunningAggregator) {
-				defer aggWg.Done()
-
-				acc := NewAccumulator(agg, aggregations)
-				acc.SetPrecision(a.Precision())
-				a.push(ctx, agg, acc)
-			}(agg)
-		}
 
-		aggWg.Wait()
-		close(aggregations)
+		a.aggregators.Close()
+		close(a.aggC)
 	}()
 
-	for metric := range aggregations {
+	for metric := range a.aggC {
 		metrics := a.applyProcessors(metric)
 		for _, metric := range metrics {
 			dst <- metric
@@ -457,6 +759,16 @@ func (a *Agent) runAggregators(
 	return nil
 }
 
+// startAggregator starts the periodic push for a single Aggregator.
+func (a *Agent) startAggregator(aggregator *models.RunningAggregator) bool {
+	acc := NewAccumulator(aggregator, a.aggC)
+	acc.SetPrecision(a.Precision())
+
+	return a.aggregators.Start(aggregator, func(ctx context.Context) {
+		a.push(ctx, aggregator, acc)
+	})
+}
+
 // push runs the push for a single aggregator every period.
 func (a *Agent) push(
 	ctx context.Context,
@@ -490,44 +802,48 @@ func (a *Agent) runOutputs(
 	startTime time.Time,
 	src <-chan telegraf.Metric,
 ) error {
-	interval := a.Config.Agent.FlushInterval.Duration
-	jitter := a.Config.Agent.FlushJitter.Duration
-
-	ctx, cancel := context.WithCancel(context.Background())
-
-	var wg sync.WaitGroup
+	a.reloadMu.Lock()
 	for _, output := range a.Config.Outputs {
-		interval := interval
-		// Overwrite agent flush_interval if this plugin has its own.
-		if output.Config.FlushInterval != 0 {
-			interval = output.Config.FlushInterval
-		}
+		a.startOutput(output, startTime)
+	}
+	a.reloadMu.Unlock()
 
-		jitter := jitter
-		// Overwrite agent flush_jitter if this plugin has its own.
-		if output.Config.FlushJitter != nil {
-			jitter = *output.Config.FlushJitter
+	for metric := range src {
+		if a.cardinality != nil {
+			metric = a.cardinality.Apply(metric)
+			if metric == nil {
+				continue
+			}
 		}
 
-		wg.Add(1)
-		go func(output *models.RunningOutput) {
-			defer wg.Done()
+		a.pluginsMu.RLock()
+		outputs := a.Config.Outputs
+		router := a.router
+		a.pluginsMu.RUnlock()
 
-			if a.Config.Agent.RoundInterval {
-				err := internal.SleepContext(
-					ctx, internal.AlignDuration(startTime, interval))
-				if err != nil {
-					return
-				}
-			}
+		// Only the routed outputs receive a copy of the metric.
+		if router != nil {
+			outputs = router.Route(metric)
+		}
 
-			a.flush(ctx, output, interval, jitter)
-		}(output)
-	}
+		// Dead letter outputs only receive the metrics rejected by other
+		// outputs.
+		last := -1
+		for i, output := range outputs {
+			if !output.IsDeadLetter() {
+				last = i
+			}
+		}
+		if last == -1 {
+			metric.Drop()
+			continue
+		}
 
-	for metric := range src {
-		for i, output := range a.Config.Outputs {
-			if i == len(a.Config.Outputs)-1 {
+		for i, output := range outputs[:last+1] {
+			if output.IsDeadLetter() {
+				continue
+			}
+			if i == last {
 				output.AddMetric(metric)
 			} else {
 				output.AddMetric(metric.Copy())
@@ -536,12 +852,38 @@ func (a *Agent) runOutputs(
 	}
 
 	log.Println("I! [agent] Hang on, flushing any cached metrics before shutdown")
-	cancel()
-	wg.Wait()
+	a.outputs.Close()
 
 	return nil
 }
 
+// startOutput starts the periodic write for a single Output.
+func (a *Agent) startOutput(output *models.RunningOutput, startTime time.Time) bool {
+	interval := a.Config.Agent.FlushInterval.Duration
+	// Overwrite agent flush_interval if this plugin has its own.
+	if output.Config.FlushInterval != 0 {
+		interval = output.Config.FlushInterval
+	}
+
+	jitter := a.Config.Agent.FlushJitter.Duration
+	// Overwrite agent flush_jitter if this plugin has its own.
+	if output.Config.FlushJitter != nil {
+		jitter = *output.Config.FlushJitter
+	}
+
+	return a.outputs.Start(output, func(ctx context.Context) {
+		if a.Config.Agent.RoundInterval {
+			err := internal.SleepContext(
+				ctx, internal.AlignDuration(startTime, interval))
+			if err != nil {
+				return
+			}
+		}
+
+		a.flush(ctx, output, interval, jitter)
+	})
+}
+
 // flush runs an output's flush function periodically until the context is
 // done.
 func (a *Agent) flush(
@@ -556,33 +898,49 @@ func (a *Agent) flush(
 	defer ticker.Stop()
 
 	logError := func(err error) {
-		if err != nil {
+		if err != nil && err != context.Canceled {
 			log.Printf("E! [agent] Error writing to %s: %v", output.LogName(), err)
 		}
 	}
 
+	write := func() error { return output.Write(ctx) }
+	writeBatch := func() error { return output.WriteBatch(ctx) }
+
+	// The final write is not cancelled by the stop of the output, which
+	// interrupts the write in progress, but limited to the flush interval.
+	// It is attempted even when the output is backing off.
+	finalWrite := func() error {
+		ctx, cancel := context.WithTimeout(context.Background(), interval)
+		defer cancel()
+		return output.FinalWrite(ctx)
+	}
+
 	for {
 		// Favor shutdown over other methods.
 		select {
 		case <-ctx.Done():
-			logError(a.flushOnce(output, interval, output.Write))
+			logError(a.flushOnce(output, interval, finalWrite))
 			return
 		default:
 		}
 
 		select {
 		case <-ticker.C:
-			logError(a.flushOnce(output, interval, output.Write))
+			logError(a.flushOnce(output, interval, write))
 		case <-output.BatchReady:
 			// Favor the ticker over batch ready
 			select {
 			case <-ticker.C:
-				logError(a.flushOnce(output, interval, output.Write))
+				logError(a.flushOnce(output, interval, write))
 			default:
-				logError(a.flushOnce(output, interval, output.WriteBatch))
+				logError(a.flushOnce(output, interval, writeBatch))
 			}
+		case reply := <-output.FlushRequest:
+			err := a.flushOnce(output, interval, write)
+			logError(err)
+			reply <- err
 		case <-ctx.Done():
-			logError(a.flushOnce(output, interval, output.Write))
+			logError(a.flushOnce(output, interval, finalWrite))
 			return
 		}
 	}
@@ -619,29 +977,54 @@ func (a *Agent) flushOnce(
 
 // initPlugins runs the Init function on plugins.
 func (a *Agent) initPlugins() error {
-	for _, input := range a.Config.Inputs {
-		err := input.Init()
+	return initPlugins(a.Config, a.Config.Inputs, a.Config.Processors,
+		a.Config.Aggregators, a.Config.Outputs)
+}
+
+// initPlugins resolves the secrets of the plugins from the secret stores of
+// the config and runs their Init function.
+func initPlugins(
+	c *config.Config,
+	inputs []*models.RunningInput,
+	processors []*models.RunningProcessor,
+	aggregators []*models.RunningAggregator,
+	outputs []*models.RunningOutput,
+) error {
+	for _, input := range inputs {
+		err := c.ResolveSecrets(input)
+		if err == nil {
+			err = input.Init()
+		}
 		if err != nil {
 			return fmt.Errorf("could not initialize input %s: %v",
 				input.LogName(), err)
 		}
 	}
-	for _, processor := range a.Config.Processors {
-		err := processor.Init()
+	for _, processor := range processors {
+		err := c.ResolveSecrets(processor)
+		if err == nil {
+			err = processor.Init()
+		}
 		if err != nil {
 			return fmt.Errorf("could not initialize processor %s: %v",
 				processor.Config.Name, err)
 		}
 	}
-	for _, aggregator := range a.Config.Aggregators {
-		err := aggregator.Init()
+	for _, aggregator := range aggregators {
+		err := c.ResolveSecrets(aggregator)
+		if err == nil {
+			err = aggregator.Init()
+		}
 		if err != nil {
 			return fmt.Errorf("could not initialize aggregator %s: %v",
 				aggregator.Config.Name, err)
 		}
 	}
-	for _, output := range a.Config.Outputs {
-		err := output.Init()
+	for _, output := range outputs {
+		err := c.ResolveSecrets(output)
+		if err == nil {
+			err = output.Init()
+		}
 		if err != nil {
 			return fmt.Errorf("could not initialize output %s: %v",
 				output.Config.Name, err)
@@ -653,24 +1036,33 @@ func (a *Agent) initPlugins() error {
 // connectOutputs connects to all outputs.
 func (a *Agent) connectOutputs(ctx context.Context) error {
 	for _, output := range a.Config.Outputs {
-		log.Printf("D! [agent] Attempting connection to [%s]", output.LogName())
-		err := output.Output.Connect()
+		err := connectOutput(ctx, output)
 		if err != nil {
-			log.Printf("E! [agent] Failed to connect to [%s], retrying in 15s, "+
-				"error was '%s'", output.LogName(), err)
+			return err
+		}
+	}
+	return nil
+}
 
-			err := internal.SleepContext(ctx, 15*time.Second)
-			if err != nil {
-				return err
-			}
+// connectOutput connects to an output, retrying once on failure.
+func connectOutput(ctx context.Context, output *models.RunningOutput) error {
+	log.Printf("D! [agent] Attempting connection to [%s]", output.LogName())
+	err := output.Connect(ctx)
+	if err != nil {
+		log.Printf("E! [agent] Failed to connect to [%s], retrying in 15s, "+
+			"error was '%s'", output.LogName(), err)
 
-			err = output.Output.Connect()
-			if err != nil {
-				return err
-			}
+		err := internal.SleepContext(ctx, 15*time.Second)
+		if err != nil {
+			return err
+		}
+
+		err = output.Connect(ctx)
+		if err != nil {
+			return err
 		}
-		log.Printf("D! [agent] Successfully connected to %s", output.LogName())
 	}
+	log.Printf("D! [agent] Successfully connected to %s", output.LogName())
 	return nil
 }
 
@@ -718,7 +1110,11 @@ func (a *Agent) startServiceInputs(
 
 // stopServiceInputs stops all service inputs.
 func (a *Agent) stopServiceInputs() {
-	for _, input := range a.Config.Inputs {
+	a.pluginsMu.RLock()
+	inputs := a.Config.Inputs
+	a.pluginsMu.RUnlock()
+
+	for _, input := range inputs {
 		if si, ok := input.Input.(telegraf.ServiceInput); ok {
 			si.Stop()
 		}
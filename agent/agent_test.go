@@ -1,12 +1,18 @@
 package agent
 
 import (
+	"context"
+	"fmt"
 	"testing"
 	"time"
 
+	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/internal/config"
+	"github.com/influxdata/telegraf/internal/models"
+	"github.com/influxdata/telegraf/metric"
 	_ "github.com/influxdata/telegraf/plugins/inputs/all"
 	_ "github.com/influxdata/telegraf/plugins/outputs/all"
+	"github.com/influxdata/telegraf/testutil"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -166,3 +172,291 @@ func TestWindow(t *testing.T) {
 		})
 	}
 }
+
+type seqProcessor struct{}
+
+func (p *seqProcessor) SampleConfig() string { return "" }
+func (p *seqProcessor) Description() string  { return "" }
+func (p *seqProcessor) Apply(in ...telegraf.Metric) []telegraf.Metric {
+	return in
+}
+
+func TestAgent_ProcessorShardsPreserveSeriesOrder(t *testing.T) {
+	c := config.NewConfig()
+	rp := models.NewRunningProcessor(&seqProcessor{}, &models.ProcessorConfig{
+		Name:        "seq",
+		Concurrency: 4,
+	})
+	for i := 1; i < 4; i++ {
+		rp.AddWorker(&seqProcessor{})
+	}
+	c.Processors = models.RunningProcessors{rp}
+
+	a, err := NewAgent(c)
+	require.NoError(t, err)
+	a.processorShards = maxConcurrency(c.Processors)
+	require.Equal(t, 4, a.processorShards)
+
+	src := make(chan telegraf.Metric, 1000)
+	dst := make(chan telegraf.Metric, 1000)
+	for i := 0; i < 1000; i++ {
+		m, err := metric.New("cpu",
+			map[string]string{"host": fmt.Sprintf("host%d", i%10)},
+			map[string]interface{}{"seq": int64(i)},
+			time.Unix(0, 0))
+		require.NoError(t, err)
+		src <- m
+	}
+	close(src)
+
+	require.NoError(t, a.runProcessors(src, dst))
+	close(dst)
+
+	last := make(map[string]int64)
+	count := 0
+	for m := range dst {
+		host, _ := m.GetTag("host")
+		seq, _ := m.GetField("seq")
+		if prev, ok := last[host]; ok {
+			require.True(t, seq.(int64) > prev)
+		}
+		last[host] = seq.(int64)
+		count++
+	}
+	require.Equal(t, 1000, count)
+}
+
+// blockingInput blocks each gather until it is released or, when ctxAware is
+// set, its context is done.
+type blockingInput struct {
+	ctxAware bool
+	started  chan struct{}
+	release  chan struct{}
+}
+
+func newBlockingInput(ctxAware bool) *blockingInput {
+	return &blockingInput{
+		ctxAware: ctxAware,
+		started:  make(chan struct{}, 10),
+		release:  make(chan struct{}),
+	}
+}
+
+func (i *blockingInput) SampleConfig() string { return "" }
+func (i *blockingInput) Description() string  { return "" }
+func (i *blockingInput) Gather(acc telegraf.Accumulator) error {
+	i.started <- struct{}{}
+	<-i.release
+	return nil
+}
+
+func (i *blockingInput) GatherContext(ctx context.Context, acc telegraf.Accumulator) error {
+	if !i.ctxAware {
+		return i.Gather(acc)
+	}
+	i.started <- struct{}{}
+	select {
+	case <-i.release:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// runGatherOnTicks runs gatherOnTicks in the background, the returned
+// function stops it.
+func runGatherOnTicks(
+	a *Agent,
+	acc telegraf.Accumulator,
+	input *models.RunningInput,
+	ticks <-chan time.Time,
+) func() {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		a.gatherOnTicks(ctx, acc, input, ticks, false)
+		close(done)
+	}()
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
+func TestAgent_GatherTimeout(t *testing.T) {
+	a, err := NewAgent(config.NewConfig())
+	require.NoError(t, err)
+
+	plugin := newBlockingInput(true)
+	input := models.NewRunningInput(plugin, &models.InputConfig{
+		Name:          "timeout",
+		GatherTimeout: 10 * time.Millisecond,
+	})
+	timeouts := input.GatherTimeouts.Get()
+	acc := &testutil.Accumulator{}
+	ticks := make(chan time.Time)
+	stop := runGatherOnTicks(a, acc, input, ticks)
+	defer stop()
+
+	ticks <- time.Now()
+	<-plugin.started
+
+	require.Eventually(t, func() bool {
+		return input.GatherTimeouts.Get() == timeouts+1
+	}, time.Second, time.Millisecond)
+	acc.Lock()
+	require.Len(t, acc.Errors, 1)
+	require.Contains(t, acc.Errors[0].Error(), "gather_timeout")
+	acc.Unlock()
+
+	// The cancelled gather has returned, the next one starts right away.
+	ticks <- time.Now()
+	<-plugin.started
+	close(plugin.release)
+}
+
+// hungInput does not implement GatherContext, its gathers return only once
+// released.
+type hungInput struct {
+	started  chan struct{}
+	release  chan struct{}
+	returned chan struct{}
+}
+
+func (i *hungInput) SampleConfig() string { return "" }
+func (i *hungInput) Description() string  { return "" }
+func (i *hungInput) Gather(acc telegraf.Accumulator) error {
+	i.started <- struct{}{}
+	<-i.release
+	acc.AddFields("hung", map[string]interface{}{"value": 42}, nil)
+	i.returned <- struct{}{}
+	return nil
+}
+
+func TestAgent_GatherTimeoutWithoutContext(t *testing.T) {
+	a, err := NewAgent(config.NewConfig())
+	require.NoError(t, err)
+
+	plugin := &hungInput{
+		started:  make(chan struct{}, 10),
+		release:  make(chan struct{}),
+		returned: make(chan struct{}, 10),
+	}
+	input := models.NewRunningInput(plugin, &models.InputConfig{
+		Name:          "hung",
+		GatherTimeout: 10 * time.Millisecond,
+	})
+	acc := &testutil.Accumulator{}
+	ticks := make(chan time.Time)
+	stop := runGatherOnTicks(a, acc, input, ticks)
+
+	timeouts := input.GatherTimeouts.Get()
+	ticks <- time.Now()
+	<-plugin.started
+
+	// The gather cannot be cancelled, once timed out the next gather still
+	// waits for it to return.
+	require.Eventually(t, func() bool {
+		return input.GatherTimeouts.Get() == timeouts+1
+	}, time.Second, time.Millisecond)
+	acc.Lock()
+	require.Len(t, acc.Errors, 1)
+	acc.Unlock()
+	ticks <- time.Now()
+	ticks <- time.Now()
+	require.Len(t, plugin.started, 0)
+
+	// The queued ticks run a single gather once it returns.
+	plugin.release <- struct{}{}
+	<-plugin.returned
+	<-plugin.started
+	require.Len(t, plugin.started, 0)
+
+	// Stopping does not wait for the running gather, it is abandoned and
+	// its metrics are dropped.
+	stop()
+	close(plugin.release)
+	<-plugin.returned
+	require.Equal(t, uint64(1), acc.NMetrics())
+}
+
+func newOverlapInput(name string, policy string, ctxAware bool) (*blockingInput, *models.RunningInput) {
+	plugin := newBlockingInput(ctxAware)
+	input := models.NewRunningInput(plugin, &models.InputConfig{
+		Name:          name,
+		OverlapPolicy: policy,
+	})
+	return plugin, input
+}
+
+func TestAgent_OverlapQueue(t *testing.T) {
+	a, err := NewAgent(config.NewConfig())
+	require.NoError(t, err)
+
+	plugin, input := newOverlapInput("overlap_queue", models.OverlapQueue, false)
+	acc := &testutil.Accumulator{}
+	ticks := make(chan time.Time)
+	stop := runGatherOnTicks(a, acc, input, ticks)
+
+	ticks <- time.Now()
+	<-plugin.started
+	ticks <- time.Now()
+	ticks <- time.Now()
+	reply := make(chan error, 1)
+	input.GatherRequest <- reply
+
+	// The ticks and the request are combined into a single gather that
+	// starts once the first one returns.
+	plugin.release <- struct{}{}
+	<-plugin.started
+	plugin.release <- struct{}{}
+	require.NoError(t, <-reply)
+
+	stop()
+	require.Len(t, plugin.started, 0)
+	require.Len(t, acc.Errors, 0)
+}
+
+func TestAgent_OverlapSkip(t *testing.T) {
+	a, err := NewAgent(config.NewConfig())
+	require.NoError(t, err)
+
+	plugin, input := newOverlapInput("overlap_skip", models.OverlapSkip, false)
+	skipped := input.GatherSkipped.Get()
+	acc := &testutil.Accumulator{}
+	ticks := make(chan time.Time)
+	stop := runGatherOnTicks(a, acc, input, ticks)
+
+	ticks <- time.Now()
+	<-plugin.started
+	ticks <- time.Now()
+	ticks <- time.Now()
+	plugin.release <- struct{}{}
+
+	stop()
+	require.Len(t, plugin.started, 0)
+	require.Equal(t, skipped+2, input.GatherSkipped.Get())
+}
+
+func TestAgent_OverlapCancel(t *testing.T) {
+	a, err := NewAgent(config.NewConfig())
+	require.NoError(t, err)
+
+	plugin, input := newOverlapInput("overlap_cancel", models.OverlapCancel, true)
+	acc := &testutil.Accumulator{}
+	ticks := make(chan time.Time)
+	stop := runGatherOnTicks(a, acc, input, ticks)
+
+	ticks <- time.Now()
+	<-plugin.started
+	ticks <- time.Now()
+
+	// The next gather starts as soon as the cancelled one returns.
+	<-plugin.started
+	plugin.release <- struct{}{}
+
+	stop()
+	require.Len(t, plugin.started, 0)
+	require.Len(t, acc.Errors, 1)
+	require.Contains(t, acc.Errors[0].Error(), "cancelled")
+}
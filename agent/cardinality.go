@@ -0,0 +1,235 @@
+package agent
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal/config"
+	"github.com/influxdata/telegraf/selfstat"
+)
+
+// Actions taken on the metrics of a new series once a measurement reached
+// its max_series_per_measurement.
+const (
+	cardinalityDrop     = "drop"
+	cardinalityStripTag = "strip_tag"
+	cardinalityAlert    = "alert"
+)
+
+// maxSweepInterval is the longest time between removals of expired series.
+const maxSweepInterval = time.Minute
+
+// cardinalityGuard tracks the series of each measurement sent to the
+// outputs, and limits their number.
+type cardinalityGuard struct {
+	limit  int
+	action string
+	ttl    time.Duration
+
+	measurements map[string]*measurementSeries
+	lastSweep    time.Time
+}
+
+// measurementSeries is the set of series of a measurement.
+type measurementSeries struct {
+	name   string
+	series map[uint64]*seriesEntry
+	// Number of series with each tag value, by tag key.
+	values map[string]map[string]int
+	// Set while the measurement is at its limit, to only warn once.
+	limited bool
+
+	seriesCount    selfstat.Stat
+	metricsDropped selfstat.Stat
+	tagsStripped   selfstat.Stat
+	limitExceeded  selfstat.Stat
+}
+
+type seriesEntry struct {
+	seen time.Time
+	tags []telegraf.Tag
+}
+
+// newCardinalityGuard returns a guard for the configuration, or nil if the
+// number of series is not limited.
+func newCardinalityGuard(c config.CardinalityConfig) (*cardinalityGuard, error) {
+	if c.MaxSeriesPerMeasurement <= 0 {
+		return nil, nil
+	}
+
+	action := c.Action
+	switch action {
+	case "":
+		action = cardinalityDrop
+	case cardinalityDrop, cardinalityStripTag, cardinalityAlert:
+	default:
+		return nil, fmt.Errorf("cardinality: invalid action %q, must be %q, %q or %q",
+			action, cardinalityDrop, cardinalityStripTag, cardinalityAlert)
+	}
+
+	return &cardinalityGuard{
+		limit:        c.MaxSeriesPerMeasurement,
+		action:       action,
+		ttl:          c.SeriesTTL.Duration,
+		measurements: make(map[string]*measurementSeries),
+	}, nil
+}
+
+// Apply records the series of the metric.  It returns the metric, possibly
+// without a tag, or nil if the metric was dropped.
+func (g *cardinalityGuard) Apply(m telegraf.Metric) telegraf.Metric {
+	now := time.Now()
+	g.sweep(now)
+
+	ms := g.measurement(m.Name())
+	id := m.HashID()
+	if entry, ok := ms.series[id]; ok {
+		entry.seen = now
+		return m
+	}
+
+	if len(ms.series) < g.limit {
+		ms.add(id, m, now)
+		return m
+	}
+
+	ms.limitExceeded.Incr(1)
+	if !ms.limited {
+		ms.limited = true
+		log.Printf("W! [agent] Measurement %q reached max_series_per_measurement of %d, "+
+			"applying the %q action to new series", ms.name, g.limit, g.action)
+	}
+
+	switch g.action {
+	case cardinalityAlert:
+		// The series is not tracked, so that the memory used stays bounded
+		// while the metrics beyond the limit are passed on.
+		return m
+	case cardinalityStripTag:
+		// Tags are removed until the metric belongs to a tracked series, no
+		// series is added as the measurement is at its limit.  Metrics that
+		// still form a new series without any tags are dropped.
+		var stripped int64
+		for {
+			key, ok := ms.offendingTag(m)
+			if !ok {
+				break
+			}
+			m.RemoveTag(key)
+			stripped++
+
+			if entry, ok := ms.series[m.HashID()]; ok {
+				entry.seen = now
+				ms.tagsStripped.Incr(stripped)
+				return m
+			}
+		}
+	}
+
+	ms.metricsDropped.Incr(1)
+	m.Drop()
+	return nil
+}
+
+func (g *cardinalityGuard) measurement(name string) *measurementSeries {
+	ms, ok := g.measurements[name]
+	if !ok {
+		tags := cardinalityTags(name)
+		ms = &measurementSeries{
+			name:           name,
+			series:         make(map[uint64]*seriesEntry),
+			values:         make(map[string]map[string]int),
+			seriesCount:    selfstat.Register("cardinality", "series", tags),
+			metricsDropped: selfstat.Register("cardinality", "metrics_dropped", tags),
+			tagsStripped:   selfstat.Register("cardinality", "tags_stripped", tags),
+			limitExceeded:  selfstat.Register("cardinality", "limit_exceeded", tags),
+		}
+		g.measurements[name] = ms
+	}
+	return ms
+}
+
+// sweep removes the series not seen within the series_ttl.  Measurements
+// without series are removed along with their stats.
+func (g *cardinalityGuard) sweep(now time.Time) {
+	if g.ttl <= 0 {
+		return
+	}
+
+	interval := g.ttl
+	if interval > maxSweepInterval {
+		interval = maxSweepInterval
+	}
+	if now.Sub(g.lastSweep) < interval {
+		return
+	}
+	g.lastSweep = now
+
+	for _, ms := range g.measurements {
+		for id, entry := range ms.series {
+			if now.Sub(entry.seen) >= g.ttl {
+				ms.remove(id, entry)
+			}
+		}
+		if len(ms.series) == 0 {
+			selfstat.Unregister("cardinality", cardinalityTags(ms.name))
+			delete(g.measurements, ms.name)
+			continue
+		}
+		if len(ms.series) < g.limit {
+			ms.limited = false
+		}
+	}
+}
+
+// cardinalityTags returns the tags of the stats of a measurement.
+func cardinalityTags(name string) map[string]string {
+	return map[string]string{"measurement": name}
+}
+
+func (ms *measurementSeries) add(id uint64, m telegraf.Metric, now time.Time) {
+	entry := &seriesEntry{seen: now}
+	for _, tag := range m.TagList() {
+		entry.tags = append(entry.tags, *tag)
+
+		values, ok := ms.values[tag.Key]
+		if !ok {
+			values = make(map[string]int)
+			ms.values[tag.Key] = values
+		}
+		values[tag.Value]++
+	}
+	ms.series[id] = entry
+	ms.seriesCount.Set(int64(len(ms.series)))
+}
+
+func (ms *measurementSeries) remove(id uint64, entry *seriesEntry) {
+	for _, tag := range entry.tags {
+		values := ms.values[tag.Key]
+		values[tag.Value]--
+		if values[tag.Value] <= 0 {
+			delete(values, tag.Value)
+		}
+		if len(values) == 0 {
+			delete(ms.values, tag.Key)
+		}
+	}
+	delete(ms.series, id)
+	ms.seriesCount.Set(int64(len(ms.series)))
+}
+
+// offendingTag returns the tag of the metric with the most distinct values
+// in the series of the measurement.
+func (ms *measurementSeries) offendingTag(m telegraf.Metric) (string, bool) {
+	var key string
+	max := -1
+	for _, tag := range m.TagList() {
+		n := len(ms.values[tag.Key])
+		if n > max {
+			key, max = tag.Key, n
+		}
+	}
+	return key, max >= 0
+}
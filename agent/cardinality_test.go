@@ -0,0 +1,197 @@
+package agent
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/internal/config"
+	"github.com/influxdata/telegraf/selfstat"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func newSeriesMetric(name string, tags map[string]string) telegraf.Metric {
+	return testutil.MustMetric(name, tags,
+		map[string]interface{}{"value": 42.0}, time.Unix(0, 0))
+}
+
+func TestCardinalityGuard_Disabled(t *testing.T) {
+	g, err := newCardinalityGuard(config.CardinalityConfig{})
+	require.NoError(t, err)
+	require.Nil(t, g)
+}
+
+func TestCardinalityGuard_InvalidAction(t *testing.T) {
+	_, err := newCardinalityGuard(config.CardinalityConfig{
+		MaxSeriesPerMeasurement: 1,
+		Action:                  "ignore",
+	})
+	require.Error(t, err)
+}
+
+func TestCardinalityGuard_Drop(t *testing.T) {
+	g, err := newCardinalityGuard(config.CardinalityConfig{
+		MaxSeriesPerMeasurement: 2,
+	})
+	require.NoError(t, err)
+
+	// Stats persist across test runs.
+	dropped := selfstat.Register("cardinality", "metrics_dropped",
+		map[string]string{"measurement": "cpu"})
+	before := dropped.Get()
+
+	require.NotNil(t, g.Apply(newSeriesMetric("cpu", map[string]string{"cpu": "cpu0"})))
+	require.NotNil(t, g.Apply(newSeriesMetric("cpu", map[string]string{"cpu": "cpu1"})))
+	require.Nil(t, g.Apply(newSeriesMetric("cpu", map[string]string{"cpu": "cpu2"})))
+
+	// Known series and other measurements are not limited.
+	require.NotNil(t, g.Apply(newSeriesMetric("cpu", map[string]string{"cpu": "cpu0"})))
+	require.NotNil(t, g.Apply(newSeriesMetric("mem", map[string]string{})))
+
+	ms := g.measurements["cpu"]
+	require.Len(t, ms.series, 2)
+	require.Equal(t, int64(2), ms.seriesCount.Get())
+	require.Equal(t, before+1, dropped.Get())
+}
+
+func TestCardinalityGuard_StripTag(t *testing.T) {
+	g, err := newCardinalityGuard(config.CardinalityConfig{
+		MaxSeriesPerMeasurement: 2,
+		Action:                  "strip_tag",
+	})
+	require.NoError(t, err)
+
+	for _, id := range []string{"a", "b"} {
+		m := g.Apply(newSeriesMetric("requests",
+			map[string]string{"host": "server01", "request_id": id}))
+		require.NotNil(t, m)
+	}
+
+	// Without the tag the metric does not belong to a tracked series either,
+	// and no series is added at the limit.
+	require.Nil(t, g.Apply(newSeriesMetric("requests",
+		map[string]string{"host": "server01", "request_id": "c"})))
+	require.Len(t, g.measurements["requests"].series, 2)
+}
+
+func TestCardinalityGuard_StripTagUntilTracked(t *testing.T) {
+	g, err := newCardinalityGuard(config.CardinalityConfig{
+		MaxSeriesPerMeasurement: 3,
+		Action:                  "strip_tag",
+	})
+	require.NoError(t, err)
+
+	for _, tags := range []map[string]string{
+		{"host": "server01"},
+		{"host": "server01", "request_id": "a", "session": "x"},
+		{"host": "server01", "request_id": "b", "session": "y"},
+	} {
+		require.NotNil(t, g.Apply(newSeriesMetric("requests", tags)))
+	}
+	stripped := g.measurements["requests"].tagsStripped.Get()
+
+	// Both high cardinality tags are stripped until the metric belongs to
+	// the tracked series of the host.
+	for _, id := range []string{"c", "d", "e"} {
+		m := g.Apply(newSeriesMetric("requests",
+			map[string]string{"host": "server01", "request_id": id, "session": id}))
+		require.NotNil(t, m)
+		require.Equal(t, map[string]string{"host": "server01"}, m.Tags())
+	}
+	require.Len(t, g.measurements["requests"].series, 3)
+	require.Equal(t, stripped+6, g.measurements["requests"].tagsStripped.Get())
+
+	// Another host is a new series even without any other tags.
+	require.Nil(t, g.Apply(newSeriesMetric("requests",
+		map[string]string{"host": "server02", "request_id": "f"})))
+	require.Len(t, g.measurements["requests"].series, 3)
+}
+
+func TestCardinalityGuard_StripTagWithoutTags(t *testing.T) {
+	g, err := newCardinalityGuard(config.CardinalityConfig{
+		MaxSeriesPerMeasurement: 1,
+		Action:                  "strip_tag",
+	})
+	require.NoError(t, err)
+
+	require.NotNil(t, g.Apply(newSeriesMetric("net", map[string]string{"interface": "eth0"})))
+	require.Nil(t, g.Apply(newSeriesMetric("net", map[string]string{})))
+}
+
+func TestCardinalityGuard_Alert(t *testing.T) {
+	g, err := newCardinalityGuard(config.CardinalityConfig{
+		MaxSeriesPerMeasurement: 1,
+		Action:                  "alert",
+	})
+	require.NoError(t, err)
+
+	exceeded := selfstat.Register("cardinality", "limit_exceeded",
+		map[string]string{"measurement": "disk"})
+	before := exceeded.Get()
+
+	require.NotNil(t, g.Apply(newSeriesMetric("disk", map[string]string{"path": "/"})))
+	require.NotNil(t, g.Apply(newSeriesMetric("disk", map[string]string{"path": "/boot"})))
+	require.NotNil(t, g.Apply(newSeriesMetric("disk", map[string]string{"path": "/boot"})))
+
+	// Series beyond the limit are counted but not tracked.
+	ms := g.measurements["disk"]
+	require.Len(t, ms.series, 1)
+	require.Equal(t, before+2, exceeded.Get())
+}
+
+func TestCardinalityGuard_SeriesTTL(t *testing.T) {
+	g, err := newCardinalityGuard(config.CardinalityConfig{
+		MaxSeriesPerMeasurement: 1,
+		SeriesTTL:               internal.Duration{Duration: time.Minute},
+	})
+	require.NoError(t, err)
+
+	require.NotNil(t, g.Apply(newSeriesMetric("swap", map[string]string{"host": "a"})))
+	require.Nil(t, g.Apply(newSeriesMetric("swap", map[string]string{"host": "b"})))
+
+	// Age the series past the ttl.
+	ms := g.measurements["swap"]
+	for _, entry := range ms.series {
+		entry.seen = entry.seen.Add(-2 * time.Minute)
+	}
+	g.lastSweep = time.Time{}
+
+	require.NotNil(t, g.Apply(newSeriesMetric("swap", map[string]string{"host": "b"})))
+	ms = g.measurements["swap"]
+	require.Len(t, ms.series, 1)
+	require.Empty(t, ms.values["host"]["a"])
+}
+
+func TestCardinalityGuard_SeriesTTLRemovesMeasurement(t *testing.T) {
+	g, err := newCardinalityGuard(config.CardinalityConfig{
+		MaxSeriesPerMeasurement: 1,
+		SeriesTTL:               internal.Duration{Duration: time.Minute},
+	})
+	require.NoError(t, err)
+
+	require.NotNil(t, g.Apply(newSeriesMetric("expired", map[string]string{"host": "a"})))
+	require.True(t, hasCardinalityStats("expired"))
+
+	// Age the series past the ttl.
+	for _, entry := range g.measurements["expired"].series {
+		entry.seen = entry.seen.Add(-2 * time.Minute)
+	}
+	g.lastSweep = time.Time{}
+
+	// Once all of its series expired the measurement and its stats are
+	// removed.
+	require.NotNil(t, g.Apply(newSeriesMetric("other", map[string]string{})))
+	require.NotContains(t, g.measurements, "expired")
+	require.False(t, hasCardinalityStats("expired"))
+}
+
+func hasCardinalityStats(measurement string) bool {
+	for _, m := range selfstat.Metrics() {
+		if m.Name() == "internal_cardinality" && m.Tags()["measurement"] == measurement {
+			return true
+		}
+	}
+	return false
+}
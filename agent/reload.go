@@ -0,0 +1,269 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal/config"
+	"github.com/influxdata/telegraf/internal/models"
+)
+
+// ErrRestartRequired is returned by Reload when the new configuration cannot
+// be applied to the running agent and the agent must be restarted instead.
+var ErrRestartRequired = errors.New("configuration requires an agent restart")
+
+// Reload applies a new configuration to the running agent.
+//
+// Only the plugins whose table changed are stopped and started, all other
+// plugins keep running along with their state, such as the buffer and
+// connection of an output.  If the agent or global tags settings changed, if
+// processors or aggregators are added while none were configured, or if the
+// processor concurrency grows, ErrRestartRequired is returned and nothing is
+// changed.
+func (a *Agent) Reload(ctx context.Context, c *config.Config) error {
+	a.reloadMu.Lock()
+	defer a.reloadMu.Unlock()
+
+	if !a.running {
+		return ErrRestartRequired
+	}
+
+	diff := c.Diff(a.Config)
+	if diff.Restart {
+		return ErrRestartRequired
+	}
+	if len(diff.Processors) > 0 && !a.hasProcessors {
+		return ErrRestartRequired
+	}
+	if len(diff.Aggregators) > 0 && !a.hasAggregators {
+		return ErrRestartRequired
+	}
+	if maxConcurrency(diff.Processors) > a.processorShards {
+		return ErrRestartRequired
+	}
+
+	if diff.Empty() {
+		log.Printf("I! [agent] Configuration unchanged")
+		return nil
+	}
+
+	// Initialize the new plugins before touching the running ones, so that
+	// a configuration error leaves the agent as it was.
+	err := initPlugins(c, diff.AddedInputs, diff.AddedProcessors,
+		diff.AddedAggregators, nil)
+	if err != nil {
+		return err
+	}
+
+	// The outputs are initialized once the outputs they replace are closed.
+	for _, output := range diff.AddedOutputs {
+		err := c.ResolveSecrets(output)
+		if err != nil {
+			return fmt.Errorf("could not initialize output %s: %v",
+				output.LogName(), err)
+		}
+	}
+
+	err = models.LinkDeadLetterOutputs(diff.Outputs)
+	if err != nil {
+		return err
+	}
+
+	err = checkRoutes(a.Config.Agent.Routing, diff.Outputs)
+	if err != nil {
+		return err
+	}
+
+	for _, input := range diff.RemovedInputs {
+		a.inputs.Remove(input)
+		if si, ok := input.Input.(telegraf.ServiceInput); ok {
+			si.Stop()
+		}
+	}
+
+	for _, agg := range diff.AddedAggregators {
+		since, until := updateWindow(time.Now(), a.Config.Agent.RoundInterval, agg.Period())
+		agg.UpdateWindow(since, until)
+		a.startAggregator(agg)
+	}
+
+	a.pluginsMu.Lock()
+	a.Config.Apply(diff, c)
+	// New outputs only receive metrics once started, which happens after
+	// the outputs they replace are closed as they may share resources such
+	// as the disk buffer.
+	a.setOutputs(withoutOutputs(diff.Outputs, diff.AddedOutputs))
+	a.pluginsMu.Unlock()
+
+	for _, agg := range diff.RemovedAggregators {
+		a.aggregators.Remove(agg)
+	}
+
+	for _, output := range diff.RemovedOutputs {
+		a.outputs.Remove(output)
+		output.Close()
+	}
+
+	var errs []string
+	var failed []*models.RunningOutput
+	for _, output := range diff.AddedOutputs {
+		err := output.Init()
+		if err == nil {
+			err = connectOutput(ctx, output)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("could not start output %s: %v",
+				output.LogName(), err))
+			failed = append(failed, output)
+			continue
+		}
+
+		a.startOutput(output, a.startTime)
+	}
+
+	a.pluginsMu.Lock()
+	a.setOutputs(withoutOutputs(diff.Outputs, failed))
+	a.pluginsMu.Unlock()
+
+	if len(failed) > 0 {
+		err := models.LinkDeadLetterOutputs(a.Config.Outputs)
+		if err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+
+	for _, input := range diff.AddedInputs {
+		if si, ok := input.Input.(telegraf.ServiceInput); ok {
+			acc := NewAccumulator(input, a.inputC)
+			acc.SetPrecision(time.Nanosecond)
+
+			err := si.Start(acc)
+			if err != nil {
+				errs = append(errs, fmt.Sprintf("service for [%s] failed to start: %v",
+					input.LogName(), err))
+				continue
+			}
+		}
+		a.startInput(input, a.startTime, a.inputC)
+	}
+
+	log.Printf("I! [agent] Reloaded configuration: "+
+		"inputs +%d -%d, processors +%d -%d, aggregators +%d -%d, outputs +%d -%d",
+		len(diff.AddedInputs), len(diff.RemovedInputs),
+		len(diff.AddedProcessors), len(diff.RemovedProcessors),
+		len(diff.AddedAggregators), len(diff.RemovedAggregators),
+		len(diff.AddedOutputs)-len(failed), len(diff.RemovedOutputs))
+
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// setOutputs replaces the running outputs and rebuilds the router for them.
+// Must be called with pluginsMu held.
+func (a *Agent) setOutputs(outputs []*models.RunningOutput) {
+	a.Config.Outputs = outputs
+
+	router, err := newRouter(a.Config.Agent.Routing, outputs)
+	if err != nil {
+		// The routing table was validated when the agent started.
+		log.Printf("E! [agent] Error updating routes: %v", err)
+		return
+	}
+	a.router = router
+}
+
+// withoutOutputs returns the outputs not in the exclude list.
+func withoutOutputs(outputs, exclude []*models.RunningOutput) []*models.RunningOutput {
+	result := make([]*models.RunningOutput, 0, len(outputs))
+next:
+	for _, output := range outputs {
+		for _, e := range exclude {
+			if output == e {
+				continue next
+			}
+		}
+		result = append(result, output)
+	}
+	return result
+}
+
+// pluginGroup runs a goroutine for each plugin of a pipeline stage, allowing
+// plugins to be stopped and started individually.
+type pluginGroup struct {
+	sync.Mutex
+	ctx     context.Context
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+	plugins map[interface{}]*pluginRunner
+	closed  bool
+}
+
+type pluginRunner struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func newPluginGroup(ctx context.Context) *pluginGroup {
+	ctx, cancel := context.WithCancel(ctx)
+	return &pluginGroup{
+		ctx:     ctx,
+		cancel:  cancel,
+		plugins: make(map[interface{}]*pluginRunner),
+	}
+}
+
+// Start runs fn in a new goroutine for the plugin.  The context passed to fn
+// is done when the plugin is removed or the group is closed.  Returns false
+// if the plugin is already running or the group is closed.
+func (g *pluginGroup) Start(plugin interface{}, fn func(ctx context.Context)) bool {
+	g.Lock()
+	defer g.Unlock()
+
+	if _, ok := g.plugins[plugin]; ok || g.closed {
+		return false
+	}
+
+	ctx, cancel := context.WithCancel(g.ctx)
+	runner := &pluginRunner{cancel: cancel, done: make(chan struct{})}
+	g.plugins[plugin] = runner
+
+	g.wg.Add(1)
+	go func() {
+		defer g.wg.Done()
+		defer close(runner.done)
+		fn(ctx)
+	}()
+	return true
+}
+
+// Remove stops the plugin and waits for its goroutine to return.
+func (g *pluginGroup) Remove(plugin interface{}) {
+	g.Lock()
+	runner, ok := g.plugins[plugin]
+	delete(g.plugins, plugin)
+	g.Unlock()
+
+	if !ok {
+		return
+	}
+	runner.cancel()
+	<-runner.done
+}
+
+// Close stops all plugins and waits for their goroutines to return.
+func (g *pluginGroup) Close() {
+	g.Lock()
+	g.closed = true
+	g.Unlock()
+
+	g.cancel()
+	g.wg.Wait()
+}
@@ -0,0 +1,193 @@
+package agent
+
+import (
+	"fmt"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/filter"
+	"github.com/influxdata/telegraf/internal/config"
+	"github.com/influxdata/telegraf/internal/models"
+	"github.com/influxdata/telegraf/selfstat"
+)
+
+var agentMetricsUnrouted = selfstat.Register("agent", "metrics_unrouted", map[string]string{})
+
+// router selects the outputs that receive a metric according to the routing
+// table, so that only those outputs receive a copy.
+type router struct {
+	outputs []*models.RunningOutput
+	routes  []*route
+
+	// Indexes of the outputs for metrics matching no route; these are the
+	// default outputs and the outputs not named by any route.
+	defaults []int
+	// Outputs not named by any route or default receive every metric.
+	always []int
+
+	selected []bool
+	result   []*models.RunningOutput
+}
+
+type route struct {
+	measurement filter.Filter
+	tag         string
+	value       filter.Filter
+	outputs     []int
+}
+
+// checkRoutes returns an error if the routing table refers to outputs that
+// do not exist.
+func checkRoutes(c config.RoutingConfig, outputs []*models.RunningOutput) error {
+	aliases := make(map[string]bool, len(outputs))
+	for _, output := range outputs {
+		if output.Config.Alias != "" {
+			aliases[output.Config.Alias] = true
+		}
+	}
+
+	for _, r := range c.Routes {
+		if len(r.Outputs) == 0 {
+			return fmt.Errorf("routing: route has no outputs")
+		}
+		if r.Measurement == "" && r.Tag == "" {
+			return fmt.Errorf("routing: route to %v requires a measurement or tag", r.Outputs)
+		}
+		if r.Value != "" && r.Tag == "" {
+			return fmt.Errorf("routing: route to %v has a value but no tag", r.Outputs)
+		}
+		for _, alias := range r.Outputs {
+			if !aliases[alias] {
+				return fmt.Errorf("routing: unknown output alias %q", alias)
+			}
+		}
+	}
+	for _, alias := range c.DefaultOutputs {
+		if !aliases[alias] {
+			return fmt.Errorf("routing: unknown output alias %q", alias)
+		}
+	}
+
+	_, err := newRouter(c, outputs)
+	return err
+}
+
+// newRouter returns a router for the outputs, or nil if there are no routes
+// and every metric is sent to all outputs.  Routes to unknown outputs are
+// ignored, use checkRoutes to validate the routing table.
+func newRouter(c config.RoutingConfig, outputs []*models.RunningOutput) (*router, error) {
+	if len(c.Routes) == 0 {
+		return nil, nil
+	}
+
+	indexes := make(map[string][]int, len(outputs))
+	for i, output := range outputs {
+		if output.Config.Alias != "" {
+			indexes[output.Config.Alias] = append(indexes[output.Config.Alias], i)
+		}
+	}
+
+	r := &router{
+		outputs:  outputs,
+		selected: make([]bool, len(outputs)),
+	}
+
+	named := make([]bool, len(outputs))
+	for _, rc := range c.Routes {
+		rt := &route{tag: rc.Tag}
+
+		var err error
+		if rc.Measurement != "" {
+			rt.measurement, err = filter.Compile([]string{rc.Measurement})
+			if err != nil {
+				return nil, fmt.Errorf("routing: invalid measurement %q: %v", rc.Measurement, err)
+			}
+		}
+		if rc.Value != "" {
+			rt.value, err = filter.Compile([]string{rc.Value})
+			if err != nil {
+				return nil, fmt.Errorf("routing: invalid value %q: %v", rc.Value, err)
+			}
+		}
+
+		for _, alias := range rc.Outputs {
+			for _, i := range indexes[alias] {
+				rt.outputs = append(rt.outputs, i)
+				named[i] = true
+			}
+		}
+		r.routes = append(r.routes, rt)
+	}
+
+	for _, alias := range c.DefaultOutputs {
+		for _, i := range indexes[alias] {
+			if !named[i] {
+				r.defaults = append(r.defaults, i)
+				named[i] = true
+			}
+		}
+	}
+
+	for i := range outputs {
+		if !named[i] {
+			r.always = append(r.always, i)
+			r.defaults = append(r.defaults, i)
+		}
+	}
+
+	return r, nil
+}
+
+// match returns true if the route applies to the metric.
+func (rt *route) match(m telegraf.Metric) bool {
+	if rt.measurement != nil && !rt.measurement.Match(m.Name()) {
+		return false
+	}
+	if rt.tag != "" {
+		value, ok := m.GetTag(rt.tag)
+		if !ok {
+			return false
+		}
+		if rt.value != nil && !rt.value.Match(value) {
+			return false
+		}
+	}
+	return true
+}
+
+// Route returns the outputs receiving the metric, in configuration order.
+// The returned slice is only valid until the next call.
+func (r *router) Route(m telegraf.Metric) []*models.RunningOutput {
+	for i := range r.selected {
+		r.selected[i] = false
+	}
+
+	routed := false
+	for _, rt := range r.routes {
+		if !rt.match(m) {
+			continue
+		}
+		routed = true
+		for _, i := range rt.outputs {
+			r.selected[i] = true
+		}
+	}
+
+	if routed {
+		for _, i := range r.always {
+			r.selected[i] = true
+		}
+	} else {
+		agentMetricsUnrouted.Incr(1)
+		for _, i := range r.defaults {
+			r.selected[i] = true
+		}
+	}
+
+	r.result = r.result[:0]
+	for i, ok := range r.selected {
+		if ok {
+			r.result = append(r.result, r.outputs[i])
+		}
+	}
+	return r.result
+}
@@ -0,0 +1,91 @@
+package agent
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal/config"
+	"github.com/influxdata/telegraf/internal/models"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+type nopOutput struct{}
+
+func (o *nopOutput) Connect() error                        { return nil }
+func (o *nopOutput) Close() error                          { return nil }
+func (o *nopOutput) Description() string                   { return "" }
+func (o *nopOutput) SampleConfig() string                  { return "" }
+func (o *nopOutput) Write(metrics []telegraf.Metric) error { return nil }
+
+func newRoutedOutputs(aliases ...string) []*models.RunningOutput {
+	outputs := make([]*models.RunningOutput, 0, len(aliases))
+	for _, alias := range aliases {
+		outputs = append(outputs, models.NewRunningOutput("nop", &nopOutput{},
+			&models.OutputConfig{Name: "nop", Alias: alias}, 0, 0))
+	}
+	return outputs
+}
+
+func routeAliases(r *router, m telegraf.Metric) []string {
+	aliases := []string{}
+	for _, output := range r.Route(m) {
+		aliases = append(aliases, output.Config.Alias)
+	}
+	return aliases
+}
+
+func TestRouter(t *testing.T) {
+	outputs := newRoutedOutputs("prod", "dev", "system", "fallback", "debug")
+	c := config.RoutingConfig{
+		Routes: []config.RouteConfig{
+			{Tag: "env", Value: "prod*", Outputs: []string{"prod"}},
+			{Tag: "env", Value: "dev", Outputs: []string{"dev"}},
+			{Measurement: "cpu*", Outputs: []string{"system"}},
+		},
+		DefaultOutputs: []string{"fallback"},
+	}
+	require.NoError(t, checkRoutes(c, outputs))
+
+	r, err := newRouter(c, outputs)
+	require.NoError(t, err)
+
+	newMetric := func(name string, tags map[string]string) telegraf.Metric {
+		return testutil.MustMetric(name, tags,
+			map[string]interface{}{"value": 42.0}, time.Unix(0, 0))
+	}
+
+	require.Equal(t, []string{"prod", "debug"},
+		routeAliases(r, newMetric("mem", map[string]string{"env": "production"})))
+	require.Equal(t, []string{"prod", "system", "debug"},
+		routeAliases(r, newMetric("cpu", map[string]string{"env": "prod"})))
+	require.Equal(t, []string{"dev", "debug"},
+		routeAliases(r, newMetric("disk", map[string]string{"env": "dev"})))
+
+	before := agentMetricsUnrouted.Get()
+	require.Equal(t, []string{"fallback", "debug"},
+		routeAliases(r, newMetric("disk", map[string]string{"env": "test"})))
+	require.Equal(t, before+1, agentMetricsUnrouted.Get())
+}
+
+func TestRouter_NoRoutes(t *testing.T) {
+	r, err := newRouter(config.RoutingConfig{}, newRoutedOutputs("a"))
+	require.NoError(t, err)
+	require.Nil(t, r)
+}
+
+func TestCheckRoutes(t *testing.T) {
+	outputs := newRoutedOutputs("a")
+
+	require.Error(t, checkRoutes(config.RoutingConfig{
+		Routes: []config.RouteConfig{{Tag: "env", Outputs: []string{"missing"}}},
+	}, outputs))
+	require.Error(t, checkRoutes(config.RoutingConfig{
+		Routes: []config.RouteConfig{{Value: "prod", Outputs: []string{"a"}}},
+	}, outputs))
+	require.Error(t, checkRoutes(config.RoutingConfig{
+		Routes:         []config.RouteConfig{{Tag: "env", Outputs: []string{"a"}}},
+		DefaultOutputs: []string{"missing"},
+	}, outputs))
+}
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/internal/cron"
 )
 
 type Ticker struct {
@@ -55,3 +56,72 @@ func (t *Ticker) relayTime(ctx context.Context) {
 		}
 	}
 }
+
+// ScheduleTicker delivers the times matching a cron schedule on C.
+type ScheduleTicker struct {
+	C          chan time.Time
+	schedule   *cron.Schedule
+	notify     func(next time.Time)
+	wg         sync.WaitGroup
+	cancelFunc context.CancelFunc
+}
+
+// NewScheduleTicker returns a ticker for the schedule.  If notify is not nil
+// it is called with the time of each tick before waiting for it.
+func NewScheduleTicker(
+	schedule *cron.Schedule,
+	notify func(next time.Time),
+) *ScheduleTicker {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	t := &ScheduleTicker{
+		C:          make(chan time.Time, 1),
+		schedule:   schedule,
+		notify:     notify,
+		cancelFunc: cancel,
+	}
+
+	t.wg.Add(1)
+	go t.relayTime(ctx)
+
+	return t
+}
+
+func (t *ScheduleTicker) Stop() {
+	t.cancelFunc()
+	t.wg.Wait()
+}
+
+func (t *ScheduleTicker) relayTime(ctx context.Context) {
+	defer t.wg.Done()
+
+	var last time.Time
+	for {
+		// Never repeat a tick if the clock was set back.
+		now := time.Now()
+		if now.Before(last) {
+			now = last
+		}
+
+		next := t.schedule.Next(now)
+		if next.IsZero() {
+			return
+		}
+		if t.notify != nil {
+			t.notify(next)
+		}
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case tm := <-timer.C:
+			select {
+			case t.C <- tm:
+			default:
+			}
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		}
+		last = next
+	}
+}
@@ -1,10 +1,12 @@
 package main
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"flag"
 	"fmt"
+	"io/ioutil"
 	"log"
 	"net/http"
 	_ "net/http/pprof" // Comment this line to disable pprof endpoint.
@@ -26,6 +28,7 @@ import (
 	"github.com/influxdata/telegraf/plugins/outputs"
 	_ "github.com/influxdata/telegraf/plugins/outputs/all"
 	_ "github.com/influxdata/telegraf/plugins/processors/all"
+	_ "github.com/influxdata/telegraf/plugins/secretstores/all"
 	"github.com/kardianos/service"
 )
 
@@ -40,6 +43,8 @@ var fTestWait = flag.Int("test-wait", 0, "wait up to this many seconds for servi
 var fConfig = flag.String("config", "", "configuration file to load")
 var fConfigDirectory = flag.String("config-directory", "",
 	"directory containing additional *.conf files")
+var fWatchConfig = flag.Bool("watch-config", false,
+	"reload the configuration when the config files change")
 var fVersion = flag.Bool("version", false, "display the version and exit")
 var fSampleConfig = flag.Bool("sample-config", false,
 	"print out full sample configuration")
@@ -90,67 +95,239 @@ func reloadLoop(
 
 		ctx, cancel := context.WithCancel(context.Background())
 
-		signals := make(chan os.Signal)
+		// restart stops the agent and starts a new one with a freshly
+		// loaded configuration.
+		restart := func() {
+			<-reload
+			reload <- true
+			cancel()
+		}
+
+		hangup := make(chan *config.Config, 1)
+		signals := make(chan os.Signal, 1)
 		signal.Notify(signals, os.Interrupt, syscall.SIGHUP,
 			syscall.SIGTERM, syscall.SIGINT)
 		go func() {
-			select {
-			case sig := <-signals:
-				if sig == syscall.SIGHUP {
-					log.Printf("I! Reloading Telegraf config")
-					<-reload
-					reload <- true
+			for {
+				select {
+				case sig := <-signals:
+					if sig == syscall.SIGHUP {
+						log.Printf("I! Reloading Telegraf config")
+						requestReload(hangup, nil)
+						continue
+					}
+					cancel()
+				case <-stop:
+					cancel()
+				case <-ctx.Done():
 				}
-				cancel()
-			case <-stop:
-				cancel()
+				signal.Stop(signals)
+				return
 			}
 		}()
 
-		err := runAgent(ctx, inputFilters, outputFilters)
+		err := runAgent(ctx, inputFilters, outputFilters, hangup, restart)
 		if err != nil && err != context.Canceled {
 			log.Fatalf("E! [telegraf] Error running agent: %v", err)
 		}
 	}
 }
 
-func runAgent(ctx context.Context,
+// loadConfig loads and validates the configuration files.
+func loadConfig(
 	inputFilters []string,
 	outputFilters []string,
-) error {
-	log.Printf("I! Starting Telegraf %s", version)
-
-	// If no other options are specified, load the config file and run.
+) (*config.Config, error) {
 	c := config.NewConfig()
 	c.OutputFilters = outputFilters
 	c.InputFilters = inputFilters
 	err := c.LoadConfig(*fConfig)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	if *fConfigDirectory != "" {
 		err = c.LoadDirectory(*fConfigDirectory)
 		if err != nil {
-			return err
+			return nil, err
 		}
 	}
 	if !*fTest && len(c.Outputs) == 0 {
-		return errors.New("Error: no outputs found, did you provide a valid config file?")
+		return nil, errors.New("Error: no outputs found, did you provide a valid config file?")
 	}
 	if *fPlugins == "" && len(c.Inputs) == 0 {
-		return errors.New("Error: no inputs found, did you provide a valid config file?")
+		return nil, errors.New("Error: no inputs found, did you provide a valid config file?")
 	}
 
 	if int64(c.Agent.Interval.Duration) <= 0 {
-		return fmt.Errorf("Agent interval must be positive, found %s",
+		return nil, fmt.Errorf("Agent interval must be positive, found %s",
 			c.Agent.Interval.Duration)
 	}
 
 	if int64(c.Agent.FlushInterval.Duration) <= 0 {
-		return fmt.Errorf("Agent flush_interval must be positive; found %s",
+		return nil, fmt.Errorf("Agent flush_interval must be positive; found %s",
 			c.Agent.Interval.Duration)
 	}
+	return c, nil
+}
+
+// checkConfig validates the configuration files without running any plugin
+// and prints the problems found.  It returns the exit code.
+func checkConfig() int {
+	diagnostics := config.Check(*fConfig, *fConfigDirectory)
+	for _, d := range diagnostics {
+		fmt.Println(d)
+	}
+	if len(diagnostics) > 0 {
+		return 1
+	}
+	return 0
+}
+
+// secretsCommand manages the secrets of a secret store.  "list <id>" prints
+// the keys of the secrets and "set <id> <key>" sets the secret to the value
+// read from stdin.
+func secretsCommand(args []string) error {
+	usage := errors.New("usage: telegraf secrets list <id> | set <id> <key>")
+	if len(args) < 2 {
+		return usage
+	}
+
+	c := config.NewConfig()
+	err := c.LoadConfig(*fConfig)
+	if err != nil {
+		return err
+	}
+	if *fConfigDirectory != "" {
+		err = c.LoadDirectory(*fConfigDirectory)
+		if err != nil {
+			return err
+		}
+	}
+
+	store, err := c.SecretStore(args[1])
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case args[0] == "list" && len(args) == 2:
+		keys, err := store.List()
+		if err != nil {
+			return err
+		}
+		for _, key := range keys {
+			fmt.Println(key)
+		}
+		return nil
+	case args[0] == "set" && len(args) == 3:
+		value, err := ioutil.ReadAll(os.Stdin)
+		if err != nil {
+			return err
+		}
+		value = bytes.TrimSuffix(value, []byte("\n"))
+		return store.Set(args[2], value)
+	}
+	return usage
+}
+
+// requestReload queues a reload of the configuration.  A nil config is
+// loaded from the files when the reload is applied.  At most one reload is
+// pending, a newer request replaces the pending one so no change is lost
+// while a reload is in progress.
+func requestReload(hangup chan *config.Config, c *config.Config) {
+	for {
+		select {
+		case hangup <- c:
+			return
+		default:
+		}
+
+		select {
+		case <-hangup:
+		default:
+		}
+	}
+}
+
+// reloadAgent applies the configuration to the running agent each time a
+// hangup is received, restarting the agent when the changes cannot be
+// applied in place.
+func reloadAgent(
+	ctx context.Context,
+	ag *agent.Agent,
+	inputFilters []string,
+	outputFilters []string,
+	hangup <-chan *config.Config,
+	restart func(),
+) {
+	for {
+		var c *config.Config
+		select {
+		case <-ctx.Done():
+			return
+		case c = <-hangup:
+		}
+
+		if c == nil {
+			var err error
+			c, err = loadConfig(inputFilters, outputFilters)
+			if err != nil {
+				log.Printf("E! [telegraf] Error loading config, keeping the running config: %v", err)
+				continue
+			}
+		}
+
+		err := ag.Reload(ctx, c)
+		if err == agent.ErrRestartRequired {
+			if ctx.Err() != nil {
+				return
+			}
+			log.Printf("I! Restarting Telegraf to apply config changes")
+			restart()
+			return
+		}
+		if err != nil {
+			log.Printf("E! [telegraf] Error reloading config: %v", err)
+		}
+	}
+}
+
+// watchConfig triggers a reload when the configuration files change and the
+// new configuration is valid.  The validated configuration is the one
+// applied, the files are not loaded again.
+func watchConfig(
+	ctx context.Context,
+	w *config.Watcher,
+	inputFilters []string,
+	outputFilters []string,
+	hangup chan *config.Config,
+) {
+	w.Run(ctx, func() {
+		c, err := loadConfig(inputFilters, outputFilters)
+		if err != nil {
+			log.Printf("E! [telegraf] Config changed but is invalid, not reloading: %v", err)
+			return
+		}
+
+		log.Printf("I! Config changed, reloading Telegraf config")
+		requestReload(hangup, c)
+	})
+}
+
+func runAgent(ctx context.Context,
+	inputFilters []string,
+	outputFilters []string,
+	hangup chan *config.Config,
+	restart func(),
+) error {
+	log.Printf("I! Starting Telegraf %s", version)
+
+	// If no other options are specified, load the config file and run.
+	c, err := loadConfig(inputFilters, outputFilters)
+	if err != nil {
+		return err
+	}
 
 	ag, err := agent.NewAgent(c)
 	if err != nil {
@@ -159,13 +336,18 @@ func runAgent(ctx context.Context,
 
 	// Setup logging as configured.
 	logConfig := logger.LogConfig{
-		Debug:               ag.Config.Agent.Debug || *fDebug,
-		Quiet:               ag.Config.Agent.Quiet || *fQuiet,
-		LogTarget:           ag.Config.Agent.LogTarget,
-		Logfile:             ag.Config.Agent.Logfile,
-		RotationInterval:    ag.Config.Agent.LogfileRotationInterval,
-		RotationMaxSize:     ag.Config.Agent.LogfileRotationMaxSize,
-		RotationMaxArchives: ag.Config.Agent.LogfileRotationMaxArchives,
+		Debug:                ag.Config.Agent.Debug || *fDebug,
+		Quiet:                ag.Config.Agent.Quiet || *fQuiet,
+		LogTarget:            ag.Config.Agent.LogTarget,
+		LogFormat:            ag.Config.Agent.LogFormat,
+		Logfile:              ag.Config.Agent.Logfile,
+		RotationInterval:     ag.Config.Agent.LogfileRotationInterval,
+		RotationMaxSize:      ag.Config.Agent.LogfileRotationMaxSize,
+		RotationMaxArchives:  ag.Config.Agent.LogfileRotationMaxArchives,
+		RotationMaxTotalSize: ag.Config.Agent.LogfileRotationMaxTotalSize,
+		RotationCompression:  ag.Config.Agent.LogfileRotationCompression,
+		SyslogAddress:        ag.Config.Agent.LogSyslogAddress,
+		SyslogFraming:        ag.Config.Agent.LogSyslogFraming,
 	}
 
 	logger.SetupLogging(logConfig)
@@ -199,6 +381,23 @@ func runAgent(ctx context.Context,
 		}
 	}
 
+	if *fWatchConfig {
+		w, err := config.NewWatcher(*fConfig, *fConfigDirectory)
+		if err != nil {
+			return err
+		}
+		defer w.Close()
+
+		go watchConfig(ctx, w, inputFilters, outputFilters, hangup)
+	}
+
+	ag.RequestReload = func() {
+		log.Printf("I! Reloading Telegraf config")
+		requestReload(hangup, nil)
+	}
+
+	go reloadAgent(ctx, ag, inputFilters, outputFilters, hangup, restart)
+
 	return ag.Run(ctx)
 }
 
@@ -313,6 +512,9 @@ func main() {
 			fmt.Println(formatFullVersion())
 			return
 		case "config":
+			if len(args) > 1 && args[1] == "check" {
+				os.Exit(checkConfig())
+			}
 			config.PrintSampleConfig(
 				sectionFilters,
 				inputFilters,
@@ -321,6 +523,12 @@ func main() {
 				processorFilters,
 			)
 			return
+		case "secrets":
+			err := secretsCommand(args[1:])
+			if err != nil {
+				log.Fatal("E! " + err.Error())
+			}
+			return
 		}
 	}
 
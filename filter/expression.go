@@ -0,0 +1,628 @@
+package filter
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/influxdata/telegraf"
+)
+
+// Expression is a boolean expression over the name, tags, fields and time
+// of a metric, such as `fields.usage_idle < 10 && tags.host =~ "^db"`.  The
+// syntax is described with the metricpass option in docs/CONFIGURATION.md.
+//
+// A missing tag or field, or operands of mismatched types, make comparisons
+// false, except for != which is true.  The right operand of =~ and !~ must
+// be a string with a regular expression, it is compiled with the
+// expression.
+type Expression struct {
+	text string
+	root node
+}
+
+// CompileExpression parses the expression.
+func CompileExpression(text string) (*Expression, error) {
+	tokens, err := lex(text)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokenEOF {
+		return nil, fmt.Errorf("unexpected %s at position %d", tok, tok.pos)
+	}
+	return &Expression{text: text, root: root}, nil
+}
+
+// Match returns true if the expression is true for the metric.  Expressions
+// that do not evaluate to a boolean never match.
+func (e *Expression) Match(m telegraf.Metric) bool {
+	b, ok := e.root.eval(m).(bool)
+	return ok && b
+}
+
+func (e *Expression) String() string {
+	return e.text
+}
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenNumber
+	tokenString
+	tokenOp
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+func (t token) String() string {
+	if t.kind == tokenEOF {
+		return "end of expression"
+	}
+	return strconv.Quote(t.text)
+}
+
+// Operators, longest first so that they are matched greedily.
+var operators = []string{
+	"||", "&&", "==", "!=", "<=", ">=", "=~", "!~",
+	"<", ">", "+", "-", "*", "/", "%", "!", "(", ")", "[", "]", ".", ",",
+}
+
+func lex(text string) ([]token, error) {
+	var tokens []token
+	i := 0
+	for i < len(text) {
+		c := rune(text[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case c == '_' || unicode.IsLetter(c):
+			start := i
+			for i < len(text) && (text[i] == '_' || unicode.IsLetter(rune(text[i])) || unicode.IsDigit(rune(text[i]))) {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenIdent, text: text[start:i], pos: start})
+		case unicode.IsDigit(c):
+			start := i
+			for i < len(text) && (unicode.IsDigit(rune(text[i])) || strings.ContainsRune(".eE", rune(text[i])) ||
+				((text[i] == '+' || text[i] == '-') && (text[i-1] == 'e' || text[i-1] == 'E'))) {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenNumber, text: text[start:i], pos: start})
+		case c == '"' || c == '\'':
+			start := i
+			var b strings.Builder
+			i++
+			for i < len(text) && rune(text[i]) != c {
+				// Only quotes and backslashes are unescaped, other escapes
+				// are kept for regular expressions such as "\d+".
+				if text[i] == '\\' && i+1 < len(text) && strings.ContainsRune(`"'\\`, rune(text[i+1])) {
+					i++
+				}
+				b.WriteByte(text[i])
+				i++
+			}
+			if i >= len(text) {
+				return nil, fmt.Errorf("unterminated string at position %d", start)
+			}
+			i++
+			tokens = append(tokens, token{kind: tokenString, text: b.String(), pos: start})
+		default:
+			op := ""
+			for _, o := range operators {
+				if strings.HasPrefix(text[i:], o) {
+					op = o
+					break
+				}
+			}
+			if op == "" {
+				return nil, fmt.Errorf("unexpected character %q at position %d", c, i)
+			}
+			tokens = append(tokens, token{kind: tokenOp, text: op, pos: i})
+			i += len(op)
+		}
+	}
+	return append(tokens, token{kind: tokenEOF, pos: len(text)}), nil
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokenEOF {
+		p.pos++
+	}
+	return tok
+}
+
+// accept consumes the next token if it is one of the operators.
+func (p *parser) accept(ops ...string) (string, bool) {
+	tok := p.peek()
+	if tok.kind != tokenOp {
+		return "", false
+	}
+	for _, op := range ops {
+		if tok.text == op {
+			p.pos++
+			return op, true
+		}
+	}
+	return "", false
+}
+
+func (p *parser) expect(op string) error {
+	if _, ok := p.accept(op); !ok {
+		tok := p.peek()
+		return fmt.Errorf("expected %q at position %d, found %s", op, tok.pos, tok)
+	}
+	return nil
+}
+
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if _, ok := p.accept("||"); !ok {
+			return left, nil
+		}
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &logicalNode{or: true, left: left, right: right}
+	}
+}
+
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseComparison()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if _, ok := p.accept("&&"); !ok {
+			return left, nil
+		}
+		right, err := p.parseComparison()
+		if err != nil {
+			return nil, err
+		}
+		left = &logicalNode{left: left, right: right}
+	}
+}
+
+func (p *parser) parseComparison() (node, error) {
+	left, err := p.parseAdditive()
+	if err != nil {
+		return nil, err
+	}
+
+	op, ok := p.accept("==", "!=", "<=", ">=", "<", ">", "=~", "!~")
+	if !ok {
+		return left, nil
+	}
+
+	if op == "=~" || op == "!~" {
+		tok := p.next()
+		if tok.kind != tokenString {
+			return nil, fmt.Errorf("expected a regular expression string at position %d, found %s", tok.pos, tok)
+		}
+		re, err := regexp.Compile(tok.text)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regular expression at position %d: %v", tok.pos, err)
+		}
+		return &regexNode{operand: left, re: re, negate: op == "!~"}, nil
+	}
+
+	right, err := p.parseAdditive()
+	if err != nil {
+		return nil, err
+	}
+	return &compareNode{op: op, left: left, right: right}, nil
+}
+
+func (p *parser) parseAdditive() (node, error) {
+	left, err := p.parseMultiplicative()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.accept("+", "-")
+		if !ok {
+			return left, nil
+		}
+		right, err := p.parseMultiplicative()
+		if err != nil {
+			return nil, err
+		}
+		left = &arithmeticNode{op: op, left: left, right: right}
+	}
+}
+
+func (p *parser) parseMultiplicative() (node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.accept("*", "/", "%")
+		if !ok {
+			return left, nil
+		}
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &arithmeticNode{op: op, left: left, right: right}
+	}
+}
+
+func (p *parser) parseUnary() (node, error) {
+	if op, ok := p.accept("!", "-"); ok {
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		if op == "!" {
+			return &notNode{operand: operand}, nil
+		}
+		return &arithmeticNode{op: "-", left: &literalNode{value: int64(0)}, right: operand}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *parser) parsePrimary() (node, error) {
+	tok := p.next()
+	switch tok.kind {
+	case tokenNumber:
+		if i, err := strconv.ParseInt(tok.text, 10, 64); err == nil {
+			return &literalNode{value: i}, nil
+		}
+		f, err := strconv.ParseFloat(tok.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %s at position %d", tok, tok.pos)
+		}
+		return &literalNode{value: f}, nil
+	case tokenString:
+		return &literalNode{value: tok.text}, nil
+	case tokenOp:
+		if tok.text == "(" {
+			n, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			return n, p.expect(")")
+		}
+	case tokenIdent:
+		switch tok.text {
+		case "true":
+			return &literalNode{value: true}, nil
+		case "false":
+			return &literalNode{value: false}, nil
+		case "name":
+			return &nameNode{}, nil
+		case "time":
+			return &timeNode{}, nil
+		case "tags", "fields":
+			return p.parseLookup(tok.text == "tags")
+		case "now":
+			if err := p.expect("("); err != nil {
+				return nil, err
+			}
+			return &nowNode{}, p.expect(")")
+		case "has":
+			if err := p.expect("("); err != nil {
+				return nil, err
+			}
+			arg := p.next()
+			if arg.kind != tokenIdent || (arg.text != "tags" && arg.text != "fields") {
+				return nil, fmt.Errorf("has() requires a tag or field at position %d", arg.pos)
+			}
+			n, err := p.parseLookup(arg.text == "tags")
+			if err != nil {
+				return nil, err
+			}
+			return &hasNode{lookup: n.(*lookupNode)}, p.expect(")")
+		}
+		return nil, fmt.Errorf("unknown identifier %s at position %d", tok, tok.pos)
+	}
+	return nil, fmt.Errorf("unexpected %s at position %d", tok, tok.pos)
+}
+
+// parseLookup parses the key of a tag or field, either as ".key" or
+// "[\"key\"]".
+func (p *parser) parseLookup(tag bool) (node, error) {
+	if _, ok := p.accept("."); ok {
+		tok := p.next()
+		if tok.kind != tokenIdent {
+			return nil, fmt.Errorf("expected a key at position %d, found %s", tok.pos, tok)
+		}
+		return &lookupNode{tag: tag, key: tok.text}, nil
+	}
+
+	if err := p.expect("["); err != nil {
+		return nil, err
+	}
+	tok := p.next()
+	if tok.kind != tokenString {
+		return nil, fmt.Errorf("expected a key string at position %d, found %s", tok.pos, tok)
+	}
+	return &lookupNode{tag: tag, key: tok.text}, p.expect("]")
+}
+
+// node evaluates to nil, a bool, string, int64 or float64.
+type node interface {
+	eval(m telegraf.Metric) interface{}
+}
+
+type literalNode struct {
+	value interface{}
+}
+
+func (n *literalNode) eval(telegraf.Metric) interface{} {
+	return n.value
+}
+
+type nameNode struct{}
+
+func (n *nameNode) eval(m telegraf.Metric) interface{} {
+	return m.Name()
+}
+
+type timeNode struct{}
+
+func (n *timeNode) eval(m telegraf.Metric) interface{} {
+	return unixSeconds(m.Time())
+}
+
+type nowNode struct{}
+
+func (n *nowNode) eval(telegraf.Metric) interface{} {
+	return unixSeconds(time.Now())
+}
+
+func unixSeconds(t time.Time) float64 {
+	return float64(t.UnixNano()) / float64(time.Second)
+}
+
+type lookupNode struct {
+	tag bool
+	key string
+}
+
+func (n *lookupNode) eval(m telegraf.Metric) interface{} {
+	if n.tag {
+		if v, ok := m.GetTag(n.key); ok {
+			return v
+		}
+		return nil
+	}
+
+	v, ok := m.GetField(n.key)
+	if !ok {
+		return nil
+	}
+	switch v := v.(type) {
+	case uint64:
+		if v <= math.MaxInt64 {
+			return int64(v)
+		}
+		return float64(v)
+	case int64, float64, string, bool:
+		return v
+	}
+	return nil
+}
+
+type hasNode struct {
+	lookup *lookupNode
+}
+
+func (n *hasNode) eval(m telegraf.Metric) interface{} {
+	if n.lookup.tag {
+		return m.HasTag(n.lookup.key)
+	}
+	return m.HasField(n.lookup.key)
+}
+
+type notNode struct {
+	operand node
+}
+
+func (n *notNode) eval(m telegraf.Metric) interface{} {
+	b, ok := n.operand.eval(m).(bool)
+	return ok && !b
+}
+
+type logicalNode struct {
+	or          bool
+	left, right node
+}
+
+func (n *logicalNode) eval(m telegraf.Metric) interface{} {
+	left, _ := n.left.eval(m).(bool)
+	if left == n.or {
+		return left
+	}
+	right, _ := n.right.eval(m).(bool)
+	return right
+}
+
+type regexNode struct {
+	operand node
+	re      *regexp.Regexp
+	negate  bool
+}
+
+func (n *regexNode) eval(m telegraf.Metric) interface{} {
+	s, ok := n.operand.eval(m).(string)
+	if !ok {
+		return n.negate
+	}
+	return n.re.MatchString(s) != n.negate
+}
+
+type compareNode struct {
+	op          string
+	left, right node
+}
+
+func (n *compareNode) eval(m telegraf.Metric) interface{} {
+	c, ok := compare(n.left.eval(m), n.right.eval(m))
+	if !ok {
+		return n.op == "!="
+	}
+
+	switch n.op {
+	case "==":
+		return c == 0
+	case "!=":
+		return c != 0
+	case "<":
+		return c < 0
+	case "<=":
+		return c <= 0
+	case ">":
+		return c > 0
+	case ">=":
+		return c >= 0
+	}
+	return false
+}
+
+// compare returns the ordering of the values, and false if they cannot be
+// compared.  Booleans only compare as equal or not equal.
+func compare(left, right interface{}) (int, bool) {
+	switch l := left.(type) {
+	case string:
+		r, ok := right.(string)
+		if !ok {
+			return 0, false
+		}
+		return strings.Compare(l, r), true
+	case bool:
+		r, ok := right.(bool)
+		if !ok {
+			return 0, false
+		}
+		if l == r {
+			return 0, true
+		}
+		return 1, true
+	}
+
+	if l, ok := left.(int64); ok {
+		if r, ok := right.(int64); ok {
+			switch {
+			case l < r:
+				return -1, true
+			case l > r:
+				return 1, true
+			}
+			return 0, true
+		}
+	}
+
+	l, ok := toFloat(left)
+	if !ok {
+		return 0, false
+	}
+	r, ok := toFloat(right)
+	if !ok {
+		return 0, false
+	}
+	switch {
+	case l < r:
+		return -1, true
+	case l > r:
+		return 1, true
+	case l == r:
+		return 0, true
+	}
+	// NaN
+	return 0, false
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch v := v.(type) {
+	case int64:
+		return float64(v), true
+	case float64:
+		return v, true
+	}
+	return 0, false
+}
+
+type arithmeticNode struct {
+	op          string
+	left, right node
+}
+
+// eval computes the result with integers if both operands are integers,
+// except for divisions, and returns nil if an operand is not a number.
+func (n *arithmeticNode) eval(m telegraf.Metric) interface{} {
+	left, right := n.left.eval(m), n.right.eval(m)
+
+	if l, ok := left.(int64); ok {
+		if r, ok := right.(int64); ok {
+			switch n.op {
+			case "+":
+				return l + r
+			case "-":
+				return l - r
+			case "*":
+				return l * r
+			case "%":
+				if r == 0 {
+					return nil
+				}
+				return l % r
+			}
+		}
+	}
+
+	l, ok := toFloat(left)
+	if !ok {
+		return nil
+	}
+	r, ok := toFloat(right)
+	if !ok {
+		return nil
+	}
+	switch n.op {
+	case "+":
+		return l + r
+	case "-":
+		return l - r
+	case "*":
+		return l * r
+	case "/":
+		return l / r
+	case "%":
+		return math.Mod(l, r)
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package filter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExpression(t *testing.T) {
+	m := testutil.MustMetric("cpu",
+		map[string]string{
+			"host":     "db01",
+			"cpu-name": "cpu0",
+		},
+		map[string]interface{}{
+			"usage_idle": 5.5,
+			"count":      int64(7),
+			"total":      uint64(10),
+			"active":     true,
+			"state":      "running",
+		},
+		time.Unix(1500000000, 0),
+	)
+
+	tests := []struct {
+		expression string
+		expected   bool
+	}{
+		{`name == "cpu"`, true},
+		{`name != "cpu"`, false},
+		{`fields.usage_idle < 10 && tags.host =~ "^db"`, true},
+		{`fields.usage_idle < 5 || tags.host =~ "^web"`, false},
+		{`fields.usage_idle < 5 || tags.host !~ "^web"`, true},
+		{`tags["cpu-name"] == 'cpu0'`, true},
+		{`fields["count"] >= 7`, true},
+		{`fields.count * 2 + 1 == 15`, true},
+		{`fields.count % 4 == 3`, true},
+		{`fields.total / 4 == 2.5`, true},
+		{`-fields.count < 0`, true},
+		{`fields.total > fields.count`, true},
+		{`fields.active`, true},
+		{`!fields.active`, false},
+		{`fields.active == true`, true},
+		{`fields.state == "running"`, true},
+		{`time == 1500000000`, true},
+		{`now() - time > 3600`, true},
+		{`has(tags.host) && !has(fields.missing)`, true},
+		{`(fields.count > 10 || fields.count < 8) && name == "cpu"`, true},
+		{`fields.count > 10 || fields.count < 8 && name == "mem"`, false},
+		{`tags.host =~ "^db\d+$"`, true},
+		{`tags.host =~ "^db\\d+$"`, true},
+		{`tags.host =~ '^db\.'`, false},
+		{`fields.state == "run\"ning"`, false},
+		{`'it\'s' == "it's"`, true},
+
+		// Missing values and mismatched types never match, except with !=.
+		{`fields.missing < 10`, false},
+		{`fields.missing >= 10`, false},
+		{`fields.missing != 10`, true},
+		{`tags.host > 10`, false},
+		{`tags.missing =~ ".*"`, false},
+		{`tags.missing !~ ".*"`, true},
+		{`fields.count`, false},
+		{`fields.count / 0 > 0`, true},
+		{`fields.count % 0 == 0`, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expression, func(t *testing.T) {
+			e, err := CompileExpression(tt.expression)
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, e.Match(m))
+		})
+	}
+}
+
+func TestCompileExpressionErrors(t *testing.T) {
+	tests := []string{
+		``,
+		`name ==`,
+		`name == "cpu`,
+		`fields.`,
+		`tags[host]`,
+		`tags.host =~ host`,
+		`tags.host =~ "("`,
+		`(name == "cpu"`,
+		`name == "cpu")`,
+		`unknown == 1`,
+		`has(name)`,
+		`name # "cpu"`,
+	}
+
+	for _, expression := range tests {
+		t.Run(expression, func(t *testing.T) {
+			_, err := CompileExpression(expression)
+			require.Error(t, err)
+		})
+	}
+}
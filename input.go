@@ -1,5 +1,7 @@
 package telegraf
 
+import "context"
+
 type Input interface {
 	// SampleConfig returns the default configuration of the Input
 	SampleConfig() string
@@ -12,6 +14,18 @@ type Input interface {
 	Gather(Accumulator) error
 }
 
+// ContextGatherer is an Input that can stop gathering early.  When
+// implemented GatherContext is called instead of Gather, and the context is
+// cancelled when the gather exceeds its gather_timeout or the input is stopped
+// by a shutdown or reload.
+type ContextGatherer interface {
+	Input
+
+	// GatherContext adds the metrics that the Input gathers to the
+	// accumulator, returning early when the context is done.
+	GatherContext(ctx context.Context, acc Accumulator) error
+}
+
 type ServiceInput interface {
 	Input
 
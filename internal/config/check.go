@@ -0,0 +1,258 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/toml"
+	"github.com/influxdata/toml/ast"
+)
+
+// Diagnostic is a problem found in the configuration.
+type Diagnostic struct {
+	File    string
+	Line    int
+	Plugin  string
+	Message string
+}
+
+func (d Diagnostic) String() string {
+	var b strings.Builder
+	if d.File != "" {
+		b.WriteString(d.File)
+		if d.Line > 0 {
+			fmt.Fprintf(&b, ":%d", d.Line)
+		}
+		b.WriteString(": ")
+	}
+	if d.Plugin != "" {
+		b.WriteString(d.Plugin)
+		b.WriteString(": ")
+	}
+	b.WriteString(d.Message)
+	return b.String()
+}
+
+// checkState collects the diagnostics while checking the configuration.
+type checkState struct {
+	diagnostics []Diagnostic
+
+	// file and line of the table each plugin was created from.
+	sources map[interface{}]source
+	file    string
+}
+
+type source struct {
+	file string
+	line int
+}
+
+// unknownKeysError lists the keys of a table that do not correspond to any
+// option of the plugin.
+type unknownKeysError []unknownKey
+
+type unknownKey struct {
+	name string
+	line int
+}
+
+func (e unknownKeysError) Error() string {
+	names := make([]string, 0, len(e))
+	for _, key := range e {
+		names = append(names, key.name)
+	}
+	return fmt.Sprintf("unknown keys: %s", strings.Join(names, ", "))
+}
+
+// Check loads the configuration file at path and the files in directory,
+// reporting every problem found instead of stopping at the first one.  The
+// secrets are resolved and the plugins initialized but not started, so no
+// service is contacted.  No diagnostics are returned if the configuration is
+// valid.
+func Check(path string, directory string) []Diagnostic {
+	c := NewConfig()
+	c.check = &checkState{sources: make(map[interface{}]source)}
+
+	err := c.LoadConfig(path)
+	if err != nil {
+		c.check.report("", "", 0, err)
+	}
+	if directory != "" {
+		err = c.LoadDirectory(directory)
+		if err != nil {
+			c.check.report("", "", 0, err)
+		}
+	}
+
+	for _, input := range c.Inputs {
+		c.checkInit(input, input.LogName(), input.Input)
+	}
+	for _, processor := range c.Processors {
+		c.checkInit(processor, processor.LogName(), processor.Processor)
+	}
+	for _, aggregator := range c.Aggregators {
+		c.checkInit(aggregator, aggregator.LogName(), aggregator.Aggregator)
+	}
+	for _, output := range c.Outputs {
+		c.checkInit(output, output.LogName(), output.Output)
+	}
+
+	if len(c.Outputs) == 0 {
+		c.check.report("", "", 0, fmt.Errorf("no outputs found"))
+	}
+	if len(c.Inputs) == 0 {
+		c.check.report("", "", 0, fmt.Errorf("no inputs found"))
+	}
+	if c.Agent.Interval.Duration <= 0 {
+		c.check.report("", "agent", 0, fmt.Errorf("interval must be positive, found %s",
+			c.Agent.Interval.Duration))
+	}
+	if c.Agent.FlushInterval.Duration <= 0 {
+		c.check.report("", "agent", 0, fmt.Errorf("flush_interval must be positive, found %s",
+			c.Agent.FlushInterval.Duration))
+	}
+
+	diagnostics := c.check.diagnostics
+	sort.SliceStable(diagnostics, func(i, j int) bool {
+		// Diagnostics of the whole configuration come last.
+		if diagnostics[i].File == "" || diagnostics[j].File == "" {
+			return diagnostics[j].File == "" && diagnostics[i].File != ""
+		}
+		if diagnostics[i].File != diagnostics[j].File {
+			return diagnostics[i].File < diagnostics[j].File
+		}
+		return diagnostics[i].Line < diagnostics[j].Line
+	})
+	return diagnostics
+}
+
+// checkInit resolves the secrets of the plugin and calls its Init function.
+// The running plugin is not initialized, as that would also open resources
+// such as disk buffers.
+func (c *Config) checkInit(running interface{}, name string, plugin interface{}) {
+	err := c.ResolveSecrets(running)
+	if err == nil {
+		if p, ok := plugin.(telegraf.Initializer); ok {
+			err = p.Init()
+		}
+	}
+	if err != nil {
+		source := c.check.sources[running]
+		c.check.report(source.file, name, source.line, err)
+	}
+}
+
+// setSource records the table the plugin was created from when checking
+// the configuration.
+func (c *Config) setSource(plugin interface{}, tbl *ast.Table) {
+	if c.check == nil {
+		return
+	}
+	c.check.sources[plugin] = source{file: c.check.file, line: tbl.Line}
+}
+
+// tableError returns the error for a table of the file at path that could
+// not be loaded.  When checking the configuration the error is recorded
+// instead, and loading continues with the next table.
+func (c *Config) tableError(path string, name string, line int, err error) error {
+	if c.check == nil {
+		return fmt.Errorf("Error parsing %s, %s", path, err)
+	}
+	c.check.report(path, name, line, err)
+	return nil
+}
+
+// unmarshalTable sets the options of v from the table.  When checking the
+// configuration, unknown keys are collected so that all of them are
+// reported at once.
+func (c *Config) unmarshalTable(tbl *ast.Table, v interface{}) error {
+	if c.check == nil {
+		return toml.UnmarshalTable(tbl, v)
+	}
+
+	var unknown unknownKeysError
+	cfg := toml.DefaultConfig
+	cfg.MissingField = func(typ reflect.Type, key string) error {
+		unknown = append(unknown, unknownKey{name: key, line: keyLine(tbl, key)})
+		return nil
+	}
+
+	err := cfg.UnmarshalTable(tbl, v)
+	if err != nil {
+		return err
+	}
+	if len(unknown) > 0 {
+		return unknown
+	}
+	return nil
+}
+
+// report records a diagnostic for the error.  The line is taken from the
+// error when available.
+func (s *checkState) report(file string, plugin string, line int, err error) {
+	switch err := err.(type) {
+	case unknownKeysError:
+		for _, key := range err {
+			s.diagnostics = append(s.diagnostics, Diagnostic{
+				File:    file,
+				Line:    key.line,
+				Plugin:  plugin,
+				Message: fmt.Sprintf("unknown key %q", key.name),
+			})
+		}
+		return
+	case *toml.LineError:
+		message := err.Err.Error()
+		if err.StructField != "" {
+			message = fmt.Sprintf("(%s) %s", err.StructField, message)
+		}
+		s.diagnostics = append(s.diagnostics, Diagnostic{
+			File:    file,
+			Line:    err.Line,
+			Plugin:  plugin,
+			Message: message,
+		})
+		return
+	}
+
+	s.diagnostics = append(s.diagnostics, Diagnostic{
+		File:    file,
+		Line:    line,
+		Plugin:  plugin,
+		Message: err.Error(),
+	})
+}
+
+// keyLine returns the line of the first key with the name in the table or
+// its subtables.
+func keyLine(tbl *ast.Table, name string) int {
+	if field, ok := tbl.Fields[name]; ok {
+		switch field := field.(type) {
+		case *ast.KeyValue:
+			return field.Line
+		case *ast.Table:
+			return field.Line
+		case []*ast.Table:
+			return field[0].Line
+		}
+	}
+
+	for _, field := range tbl.Fields {
+		switch field := field.(type) {
+		case *ast.Table:
+			if line := keyLine(field, name); line > 0 {
+				return line
+			}
+		case []*ast.Table:
+			for _, t := range field {
+				if line := keyLine(t, name); line > 0 {
+					return line
+				}
+			}
+		}
+	}
+	return 0
+}
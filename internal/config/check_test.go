@@ -0,0 +1,49 @@
+package config
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/inputs"
+	"github.com/stretchr/testify/require"
+)
+
+type initErrorInput struct{}
+
+func (*initErrorInput) SampleConfig() string              { return "" }
+func (*initErrorInput) Description() string               { return "" }
+func (*initErrorInput) Gather(telegraf.Accumulator) error { return nil }
+func (*initErrorInput) Init() error                       { return errors.New("invalid settings") }
+
+func TestCheck(t *testing.T) {
+	inputs.Add("check_init", func() telegraf.Input { return &initErrorInput{} })
+
+	file := "./testdata/check.toml"
+	diagnostics := Check(file, "")
+
+	var lines []string
+	for _, d := range diagnostics {
+		lines = append(lines, d.String())
+	}
+	require.Equal(t, []string{
+		file + ":3: agent: unknown key \"flush_intervall\"",
+		file + ":7: inputs.memcached: unknown key \"unknown_a\"",
+		file + ":8: inputs.memcached: unknown key \"unknown_b\"",
+		file + ":11: inputs.memcached: (memcached.Memcached.Servers) cannot unmarshal TOML string into []string",
+		file + ":13: inputs.check_init: invalid settings",
+		file + ":18: outputs.not_a_plugin: Undefined but requested output: not_a_plugin",
+	}, lines)
+}
+
+func TestCheck_Valid(t *testing.T) {
+	diagnostics := Check("./testdata/single_plugin.toml", "./testdata/subconfig")
+	require.Len(t, diagnostics, 1)
+	require.Equal(t, "no outputs found", diagnostics[0].Message)
+}
+
+func TestCheck_MissingFile(t *testing.T) {
+	diagnostics := Check("./testdata/does_not_exist.toml", "")
+	require.NotEmpty(t, diagnostics)
+	require.Equal(t, "./testdata/does_not_exist.toml", diagnostics[0].File)
+}
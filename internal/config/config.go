@@ -20,11 +20,16 @@ import (
 
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/internal/cron"
 	"github.com/influxdata/telegraf/internal/models"
+	"github.com/influxdata/telegraf/internal/syslog"
+	"github.com/influxdata/telegraf/logger"
 	"github.com/influxdata/telegraf/plugins/aggregators"
 	"github.com/influxdata/telegraf/plugins/inputs"
 	"github.com/influxdata/telegraf/plugins/outputs"
 	"github.com/influxdata/telegraf/plugins/parsers"
+	"github.com/influxdata/telegraf/plugins/parsers/json_v2"
+	"github.com/influxdata/telegraf/plugins/parsers/xml"
 	"github.com/influxdata/telegraf/plugins/processors"
 	"github.com/influxdata/telegraf/plugins/serializers"
 	"github.com/influxdata/toml"
@@ -66,6 +71,22 @@ type Config struct {
 	Aggregators []*models.RunningAggregator
 	// Processors have a slice wrapper type because they need to be sorted
 	Processors models.RunningProcessors
+
+	// SecretStores by their id.
+	SecretStores map[string]telegraf.SecretStore
+	// ids of the secret stores that have been initialized.
+	initializedStores map[string]bool
+
+	// fingerprints of the tables each plugin was created from, used to find
+	// the plugins changed between two configurations.
+	fingerprints map[interface{}]string
+
+	// options of each plugin which held a secret reference, see
+	// ResolveSecrets.
+	secrets map[interface{}]*SecretOptions
+
+	// Set when checking the configuration, see Check.
+	check *checkState
 }
 
 func NewConfig() *Config {
@@ -77,14 +98,20 @@ func NewConfig() *Config {
 			FlushInterval:              internal.Duration{Duration: 10 * time.Second},
 			LogTarget:                  "file",
 			LogfileRotationMaxArchives: 5,
+			Cardinality: CardinalityConfig{
+				SeriesTTL: internal.Duration{Duration: time.Hour},
+			},
 		},
 
 		Tags:          make(map[string]string),
 		Inputs:        make([]*models.RunningInput, 0),
 		Outputs:       make([]*models.RunningOutput, 0),
 		Processors:    make([]*models.RunningProcessor, 0),
+		SecretStores:  make(map[string]telegraf.SecretStore),
 		InputFilters:  make([]string, 0),
 		OutputFilters: make([]string, 0),
+		fingerprints:  make(map[interface{}]string),
+		secrets:       make(map[interface{}]*SecretOptions),
 	}
 	return c
 }
@@ -148,10 +175,14 @@ type AgentConfig struct {
 	Quiet bool `toml:"quiet"`
 
 	// Log target controls the destination for logs and can be one of "file",
-	// "stderr" or, on Windows, "eventlog".  When set to "file", the output file
-	// is determined by the "logfile" setting.
+	// "stderr", "syslog", "journald" or, on Windows, "eventlog".  When set to
+	// "file", the output file is determined by the "logfile" setting.
 	LogTarget string `toml:"logtarget"`
 
+	// Log format controls the format of the messages written to stderr or
+	// the logfile and can be one of "text" or "json".
+	LogFormat string `toml:"logformat"`
+
 	// Name of the file to be logged to when using the "file" logtarget.  If set to
 	// the empty string then logs are written to stderr.
 	Logfile string `toml:"logfile"`
@@ -168,8 +199,74 @@ type AgentConfig struct {
 	// If set to -1, no archives are removed.
 	LogfileRotationMaxArchives int `toml:"logfile_rotation_max_archives"`
 
+	// The oldest rotated archives are deleted when their total size becomes
+	// larger than the specified size.  When set to 0 the total size is not
+	// limited.
+	LogfileRotationMaxTotalSize internal.Size `toml:"logfile_rotation_max_total_size"`
+
+	// Compression of the rotated archives, either "gzip" or "zstd".  When
+	// empty the archives are not compressed.
+	LogfileRotationCompression string `toml:"logfile_rotation_compression"`
+
+	// Address of the syslog server when using the "syslog" logtarget, such
+	// as "udp://127.0.0.1:514".  If empty the local syslog socket is used.
+	LogSyslogAddress string `toml:"log_syslog_address"`
+
+	// Framing of the syslog messages on tcp and unix stream sockets, either
+	// "octet-counting" or "non-transparent".
+	LogSyslogFraming syslog.Framing `toml:"log_syslog_framing"`
+
 	Hostname     string
 	OmitHostname bool
+
+	// Address of the HTTP admin API, the API is disabled when empty.  An
+	// address without a host listens on localhost.
+	APIAddress string `toml:"api_address"`
+
+	// Address of the Prometheus metrics endpoint, it is disabled when empty.
+	MetricsAddress string `toml:"metrics_address"`
+
+	// Routing selects the outputs that receive each metric.  Without routes
+	// every metric is sent to all outputs.
+	Routing RoutingConfig `toml:"routing"`
+
+	// Cardinality limits the number of series of each measurement sent to
+	// the outputs.
+	Cardinality CardinalityConfig `toml:"cardinality"`
+}
+
+// CardinalityConfig limits the number of distinct series, the combinations of
+// tags, of each measurement.
+type CardinalityConfig struct {
+	// Maximum number of series per measurement, no limit when 0.
+	MaxSeriesPerMeasurement int `toml:"max_series_per_measurement"`
+
+	// Action on the metrics of new series once a measurement reached its
+	// limit, one of "drop", "strip_tag" or "alert".
+	Action string `toml:"action"`
+
+	// Series not seen for this long no longer count against the limit.  When
+	// set to 0 series never expire.
+	SeriesTTL internal.Duration `toml:"series_ttl"`
+}
+
+// RoutingConfig maps metrics to the outputs, identified by their alias, that
+// receive them.
+type RoutingConfig struct {
+	Routes []RouteConfig `toml:"route"`
+
+	// Outputs receiving the metrics not matched by any route.
+	DefaultOutputs []string `toml:"default_outputs"`
+}
+
+// RouteConfig sends the metrics matching the measurement pattern and tag to
+// the outputs.  The measurement and tag value are glob patterns, a route
+// with a tag but no value matches all metrics with the tag.
+type RouteConfig struct {
+	Measurement string   `toml:"measurement"`
+	Tag         string   `toml:"tag"`
+	Value       string   `toml:"value"`
+	Outputs     []string `toml:"outputs"`
 }
 
 // Inputs returns a list of strings of the configured inputs.
@@ -295,10 +392,16 @@ var agentConfig = `
   # quiet = false
 
   ## Log target controls the destination for logs and can be one of "file",
-  ## "stderr" or, on Windows, "eventlog".  When set to "file", the output file
-  ## is determined by the "logfile" setting.
+  ## "stderr", "syslog", "journald" or, on Windows, "eventlog".  When set to
+  ## "file", the output file is determined by the "logfile" setting.
   # logtarget = "file"
 
+  ## Log format controls the format of the messages written to stderr or the
+  ## logfile and can be one of "text" or "json".  The json format writes one
+  ## object per line with the time, level, plugin type, name and alias, and
+  ## the message.
+  # logformat = "text"
+
   ## Name of the file to be logged to when using the "file" logtarget.  If set to
   ## the empty string then logs are written to stderr.
   # logfile = ""
@@ -316,11 +419,61 @@ var agentConfig = `
   ## If set to -1, no archives are removed.
   # logfile_rotation_max_archives = 5
 
+  ## The oldest rotated archives are deleted when their total size becomes
+  ## larger than the specified size.  When set to 0 the total size is not
+  ## limited.
+  # logfile_rotation_max_total_size = "0MB"
+
+  ## Compression of the rotated archives, either "gzip" or "zstd".  Archives
+  ## are compressed in the background after rotation.
+  # logfile_rotation_compression = ""
+
+  ## Address of the syslog server when using the "syslog" logtarget, for
+  ## example "udp://127.0.0.1:514", "tcp://127.0.0.1:601" or
+  ## "unixgram:///dev/log".  If empty the local syslog socket is used.
+  # log_syslog_address = ""
+
+  ## Framing of the syslog messages on tcp and unix stream sockets, either
+  ## "octet-counting" or "non-transparent".
+  # log_syslog_framing = "octet-counting"
+
   ## Override default hostname, if empty use os.Hostname()
   hostname = ""
   ## If set to true, do no set the "host" tag in the telegraf agent.
   omit_hostname = false
 
+  ## Address of the HTTP admin API, used to inspect and control the running
+  ## agent.  An address without a host listens on localhost.  The API has no
+  ## authentication, only listen on other addresses if the network is trusted.
+  # api_address = "localhost:8090"
+
+  ## Address serving the internal statistics in the Prometheus text format on
+  ## /metrics, separately from the admin API.
+  # metrics_address = ":9273"
+
+  ## Routes select the outputs, by alias, that receive a metric based on its
+  ## measurement name or the value of a tag.  Outputs not named by any route
+  ## receive every metric.
+  # [agent.routing]
+  #   ## Outputs receiving the metrics that match no route.
+  #   default_outputs = []
+  #   [[agent.routing.route]]
+  #     tag = "env"
+  #     value = "prod*"
+  #     outputs = ["production"]
+
+  ## Limits the number of series, distinct sets of tags, of each measurement
+  ## sent to the outputs.  Once a measurement has max_series_per_measurement
+  ## series the action applies to the metrics of new series:
+  ##   drop      - drop the metrics
+  ##   strip_tag - remove the tag with the most distinct values
+  ##   alert     - only log a warning and count the metrics
+  ## Series not seen within series_ttl no longer count against the limit.
+  # [agent.cardinality]
+  #   max_series_per_measurement = 0
+  #   action = "drop"
+  #   series_ttl = "1h"
+
 `
 
 var outputHeader = `
@@ -682,14 +835,22 @@ func (c *Config) LoadConfig(path string) error {
 			return err
 		}
 	}
+	if c.check != nil {
+		c.check.file = path
+	}
+
 	data, err := loadConfig(path)
 	if err != nil {
+		if c.check != nil {
+			c.check.report(path, "", 0, err)
+			return nil
+		}
 		return fmt.Errorf("Error loading %s, %s", path, err)
 	}
 
 	tbl, err := parseConfig(data)
 	if err != nil {
-		return fmt.Errorf("Error parsing %s, %s", path, err)
+		return c.tableError(path, "", 0, err)
 	}
 
 	// Parse tags tables first:
@@ -712,9 +873,13 @@ func (c *Config) LoadConfig(path string) error {
 		if !ok {
 			return fmt.Errorf("%s: invalid configuration", path)
 		}
-		if err = toml.UnmarshalTable(subTable, c.Agent); err != nil {
-			log.Printf("E! Could not parse [agent] config\n")
-			return fmt.Errorf("Error parsing %s, %s", path, err)
+		if err = c.unmarshalTable(subTable, c.Agent); err != nil {
+			if c.check == nil {
+				log.Printf("E! Could not parse [agent] config\n")
+			}
+			if err = c.tableError(path, "agent", subTable.Line, err); err != nil {
+				return err
+			}
 		}
 	}
 
@@ -746,12 +911,18 @@ func (c *Config) LoadConfig(path string) error {
 				// legacy [outputs.influxdb] support
 				case *ast.Table:
 					if err = c.addOutput(pluginName, pluginSubTable); err != nil {
-						return fmt.Errorf("Error parsing %s, %s", path, err)
+						err = c.tableError(path, "outputs."+pluginName, pluginSubTable.Line, err)
+						if err != nil {
+							return err
+						}
 					}
 				case []*ast.Table:
 					for _, t := range pluginSubTable {
 						if err = c.addOutput(pluginName, t); err != nil {
-							return fmt.Errorf("Error parsing %s, %s", path, err)
+							err = c.tableError(path, "outputs."+pluginName, t.Line, err)
+							if err != nil {
+								return err
+							}
 						}
 					}
 				default:
@@ -765,12 +936,18 @@ func (c *Config) LoadConfig(path string) error {
 				// legacy [inputs.cpu] support
 				case *ast.Table:
 					if err = c.addInput(pluginName, pluginSubTable); err != nil {
-						return fmt.Errorf("Error parsing %s, %s", path, err)
+						err = c.tableError(path, "inputs."+pluginName, pluginSubTable.Line, err)
+						if err != nil {
+							return err
+						}
 					}
 				case []*ast.Table:
 					for _, t := range pluginSubTable {
 						if err = c.addInput(pluginName, t); err != nil {
-							return fmt.Errorf("Error parsing %s, %s", path, err)
+							err = c.tableError(path, "inputs."+pluginName, t.Line, err)
+							if err != nil {
+								return err
+							}
 						}
 					}
 				default:
@@ -784,7 +961,27 @@ func (c *Config) LoadConfig(path string) error {
 				case []*ast.Table:
 					for _, t := range pluginSubTable {
 						if err = c.addProcessor(pluginName, t); err != nil {
-							return fmt.Errorf("Error parsing %s, %s", path, err)
+							err = c.tableError(path, "processors."+pluginName, t.Line, err)
+							if err != nil {
+								return err
+							}
+						}
+					}
+				default:
+					return fmt.Errorf("Unsupported config format: %s, file %s",
+						pluginName, path)
+				}
+			}
+		case "secretstores":
+			for pluginName, pluginVal := range subTable.Fields {
+				switch pluginSubTable := pluginVal.(type) {
+				case []*ast.Table:
+					for _, t := range pluginSubTable {
+						if err = c.addSecretStore(pluginName, t); err != nil {
+							err = c.tableError(path, "secretstores."+pluginName, t.Line, err)
+							if err != nil {
+								return err
+							}
 						}
 					}
 				default:
@@ -798,7 +995,10 @@ func (c *Config) LoadConfig(path string) error {
 				case []*ast.Table:
 					for _, t := range pluginSubTable {
 						if err = c.addAggregator(pluginName, t); err != nil {
-							return fmt.Errorf("Error parsing %s, %s", path, err)
+							err = c.tableError(path, "aggregators."+pluginName, t.Line, err)
+							if err != nil {
+								return err
+							}
 						}
 					}
 				default:
@@ -810,7 +1010,10 @@ func (c *Config) LoadConfig(path string) error {
 		// identifiers are present
 		default:
 			if err = c.addInput(name, subTable); err != nil {
-				return fmt.Errorf("Error parsing %s, %s", path, err)
+				err = c.tableError(path, "inputs."+name, subTable.Line, err)
+				if err != nil {
+					return err
+				}
 			}
 		}
 	}
@@ -910,17 +1113,21 @@ func (c *Config) addAggregator(name string, table *ast.Table) error {
 		return fmt.Errorf("Undefined but requested aggregator: %s", name)
 	}
 	aggregator := creator()
+	fingerprint := tableFingerprint("aggregators."+name, table)
 
 	conf, err := buildAggregator(name, table)
 	if err != nil {
 		return err
 	}
 
-	if err := toml.UnmarshalTable(table, aggregator); err != nil {
+	if err := c.unmarshalTable(table, aggregator); err != nil {
 		return err
 	}
 
-	c.Aggregators = append(c.Aggregators, models.NewRunningAggregator(aggregator, conf))
+	ra := models.NewRunningAggregator(aggregator, conf)
+	c.setFingerprint(ra, fingerprint)
+	c.setSource(ra, table)
+	c.Aggregators = append(c.Aggregators, ra)
 	return nil
 }
 
@@ -930,17 +1137,27 @@ func (c *Config) addProcessor(name string, table *ast.Table) error {
 		return fmt.Errorf("Undefined but requested processor: %s", name)
 	}
 	processor := creator()
+	fingerprint := tableFingerprint("processors."+name, table)
 
 	processorConfig, err := buildProcessor(name, table)
 	if err != nil {
 		return err
 	}
 
-	if err := toml.UnmarshalTable(table, processor); err != nil {
+	if err := c.unmarshalTable(table, processor); err != nil {
 		return err
 	}
 
 	rf := models.NewRunningProcessor(processor, processorConfig)
+	for i := 1; i < processorConfig.Concurrency; i++ {
+		worker := creator()
+		if err := c.unmarshalTable(table, worker); err != nil {
+			return err
+		}
+		rf.AddWorker(worker)
+	}
+	c.setFingerprint(rf, fingerprint)
+	c.setSource(rf, table)
 
 	c.Processors = append(c.Processors, rf)
 	return nil
@@ -955,6 +1172,7 @@ func (c *Config) addOutput(name string, table *ast.Table) error {
 		return fmt.Errorf("Undefined but requested output: %s", name)
 	}
 	output := creator()
+	fingerprint := tableFingerprint("outputs."+name, table)
 
 	// If the output has a SetSerializer function, then this means it can write
 	// arbitrary types of output, so build the serializer and set it.
@@ -972,12 +1190,14 @@ func (c *Config) addOutput(name string, table *ast.Table) error {
 		return err
 	}
 
-	if err := toml.UnmarshalTable(table, output); err != nil {
+	if err := c.unmarshalTable(table, output); err != nil {
 		return err
 	}
 
 	ro := models.NewRunningOutput(name, output, outputConfig,
 		c.Agent.MetricBatchSize, c.Agent.MetricBufferLimit)
+	c.setFingerprint(ro, fingerprint)
+	c.setSource(ro, table)
 	c.Outputs = append(c.Outputs, ro)
 	return nil
 }
@@ -996,6 +1216,7 @@ func (c *Config) addInput(name string, table *ast.Table) error {
 		return fmt.Errorf("Undefined but requested input: %s", name)
 	}
 	input := creator()
+	fingerprint := tableFingerprint("inputs."+name, table)
 
 	// If the input has a SetParser function, then this means it can accept
 	// arbitrary types of input, so build the parser and set it.
@@ -1024,12 +1245,14 @@ func (c *Config) addInput(name string, table *ast.Table) error {
 		return err
 	}
 
-	if err := toml.UnmarshalTable(table, input); err != nil {
+	if err := c.unmarshalTable(table, input); err != nil {
 		return err
 	}
 
 	rp := models.NewRunningInput(input, pluginConfig)
 	rp.SetDefaultTags(c.Tags)
+	c.setFingerprint(rp, fingerprint)
+	c.setSource(rp, table)
 	c.Inputs = append(c.Inputs, rp)
 	return nil
 }
@@ -1179,8 +1402,24 @@ func buildProcessor(name string, tbl *ast.Table) (*models.ProcessorConfig, error
 		}
 	}
 
+	if node, ok := tbl.Fields["concurrency"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if integer, ok := kv.Value.(*ast.Integer); ok {
+				v, err := integer.Int()
+				if err != nil {
+					return nil, err
+				}
+				if v < 1 {
+					return nil, fmt.Errorf("concurrency must be at least 1, found %d", v)
+				}
+				conf.Concurrency = int(v)
+			}
+		}
+	}
+
 	delete(tbl.Fields, "alias")
 	delete(tbl.Fields, "order")
+	delete(tbl.Fields, "concurrency")
 	var err error
 	conf.Filter, err = buildFilter(tbl)
 	if err != nil {
@@ -1309,6 +1548,14 @@ func buildFilter(tbl *ast.Table) (models.Filter, error) {
 			}
 		}
 	}
+
+	if node, ok := tbl.Fields["metricpass"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				f.MetricPass = str.Value
+			}
+		}
+	}
 	if err := f.Compile(); err != nil {
 		return f, err
 	}
@@ -1323,6 +1570,7 @@ func buildFilter(tbl *ast.Table) (models.Filter, error) {
 	delete(tbl.Fields, "tagpass")
 	delete(tbl.Fields, "tagexclude")
 	delete(tbl.Fields, "taginclude")
+	delete(tbl.Fields, "metricpass")
 	return f, nil
 }
 
@@ -1344,6 +1592,105 @@ func buildInput(name string, tbl *ast.Table) (*models.InputConfig, error) {
 		}
 	}
 
+	var schedule, scheduleTimezone string
+	if node, ok := tbl.Fields["schedule"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				schedule = str.Value
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["schedule_timezone"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				scheduleTimezone = str.Value
+			}
+		}
+	}
+
+	if schedule != "" {
+		var err error
+		loc := time.Local
+		if scheduleTimezone != "" {
+			loc, err = time.LoadLocation(scheduleTimezone)
+			if err != nil {
+				return nil, fmt.Errorf("invalid schedule_timezone, %s", err)
+			}
+		}
+		cp.Schedule, err = cron.Parse(schedule, loc)
+		if err != nil {
+			return nil, fmt.Errorf("invalid schedule, %s", err)
+		}
+	} else if scheduleTimezone != "" {
+		return nil, fmt.Errorf("schedule_timezone requires a schedule")
+	}
+
+	if node, ok := tbl.Fields["gather_timeout"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				dur, err := time.ParseDuration(str.Value)
+				if err != nil {
+					return nil, err
+				}
+
+				cp.GatherTimeout = dur
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["overlap_policy"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				cp.OverlapPolicy = str.Value
+			}
+		}
+	}
+	switch cp.OverlapPolicy {
+	case "", models.OverlapQueue, models.OverlapSkip, models.OverlapCancel:
+	default:
+		return nil, fmt.Errorf("invalid overlap_policy %q, must be %q, %q or %q",
+			cp.OverlapPolicy, models.OverlapQueue, models.OverlapSkip, models.OverlapCancel)
+	}
+
+	if node, ok := tbl.Fields["max_metrics_per_gather"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if integer, ok := kv.Value.(*ast.Integer); ok {
+				v, err := integer.Int()
+				if err != nil {
+					return nil, err
+				}
+				cp.MaxMetricsPerGather = int(v)
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["max_metrics_per_second"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if integer, ok := kv.Value.(*ast.Integer); ok {
+				v, err := integer.Int()
+				if err != nil {
+					return nil, err
+				}
+				cp.MaxMetricsPerSecond = int(v)
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["max_metrics_policy"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				cp.MaxMetricsPolicy = str.Value
+			}
+		}
+	}
+	switch cp.MaxMetricsPolicy {
+	case "", models.LimitDrop, models.LimitSample:
+	default:
+		return nil, fmt.Errorf("invalid max_metrics_policy %q, must be %q or %q",
+			cp.MaxMetricsPolicy, models.LimitDrop, models.LimitSample)
+	}
+
 	if node, ok := tbl.Fields["name_prefix"]; ok {
 		if kv, ok := node.(*ast.KeyValue); ok {
 			if str, ok := kv.Value.(*ast.String); ok {
@@ -1376,6 +1723,19 @@ func buildInput(name string, tbl *ast.Table) (*models.InputConfig, error) {
 		}
 	}
 
+	if node, ok := tbl.Fields["log_level"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				cp.LogLevel = str.Value
+			}
+		}
+	}
+	if cp.LogLevel != "" {
+		if _, err := logger.ParseLevel(cp.LogLevel); err != nil {
+			return nil, fmt.Errorf("invalid log_level, %s", err)
+		}
+	}
+
 	cp.Tags = make(map[string]string)
 	if node, ok := tbl.Fields["tags"]; ok {
 		if subtbl, ok := node.(*ast.Table); ok {
@@ -1389,7 +1749,15 @@ func buildInput(name string, tbl *ast.Table) (*models.InputConfig, error) {
 	delete(tbl.Fields, "name_suffix")
 	delete(tbl.Fields, "name_override")
 	delete(tbl.Fields, "alias")
+	delete(tbl.Fields, "log_level")
 	delete(tbl.Fields, "interval")
+	delete(tbl.Fields, "schedule")
+	delete(tbl.Fields, "schedule_timezone")
+	delete(tbl.Fields, "gather_timeout")
+	delete(tbl.Fields, "overlap_policy")
+	delete(tbl.Fields, "max_metrics_per_gather")
+	delete(tbl.Fields, "max_metrics_per_second")
+	delete(tbl.Fields, "max_metrics_policy")
 	delete(tbl.Fields, "tags")
 	var err error
 	cp.Filter, err = buildFilter(tbl)
@@ -1796,6 +2164,188 @@ func getParserConfig(name string, tbl *ast.Table) (*parsers.Config, error) {
 		}
 	}
 
+	if node, ok := tbl.Fields["prometheus_metric_version"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if integer, ok := kv.Value.(*ast.Integer); ok {
+				v, err := integer.Int()
+				if err != nil {
+					return nil, err
+				}
+				c.PrometheusMetricVersion = int(v)
+			}
+		}
+	}
+
+	//for protobuf data_format
+	if node, ok := tbl.Fields["protobuf_files"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if ary, ok := kv.Value.(*ast.Array); ok {
+				for _, elem := range ary.Value {
+					if str, ok := elem.(*ast.String); ok {
+						c.ProtobufFiles = append(c.ProtobufFiles, str.Value)
+					}
+				}
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["protobuf_import_paths"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if ary, ok := kv.Value.(*ast.Array); ok {
+				for _, elem := range ary.Value {
+					if str, ok := elem.(*ast.String); ok {
+						c.ProtobufImportPaths = append(c.ProtobufImportPaths, str.Value)
+					}
+				}
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["protobuf_message_type"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				c.ProtobufMessageType = str.Value
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["protobuf_tag_keys"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if ary, ok := kv.Value.(*ast.Array); ok {
+				for _, elem := range ary.Value {
+					if str, ok := elem.(*ast.String); ok {
+						c.ProtobufTagKeys = append(c.ProtobufTagKeys, str.Value)
+					}
+				}
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["protobuf_field_keys"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if ary, ok := kv.Value.(*ast.Array); ok {
+				for _, elem := range ary.Value {
+					if str, ok := elem.(*ast.String); ok {
+						c.ProtobufFieldKeys = append(c.ProtobufFieldKeys, str.Value)
+					}
+				}
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["protobuf_timestamp_key"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				c.ProtobufTimestampKey = str.Value
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["protobuf_timestamp_format"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				c.ProtobufTimestampFormat = str.Value
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["protobuf_timezone"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				c.ProtobufTimezone = str.Value
+			}
+		}
+	}
+
+	//for avro data_format
+	if node, ok := tbl.Fields["avro_schema_file"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				c.AvroSchemaFile = str.Value
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["avro_schema_registry"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				c.AvroSchemaRegistry = str.Value
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["avro_tag_keys"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if ary, ok := kv.Value.(*ast.Array); ok {
+				for _, elem := range ary.Value {
+					if str, ok := elem.(*ast.String); ok {
+						c.AvroTagKeys = append(c.AvroTagKeys, str.Value)
+					}
+				}
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["avro_field_keys"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if ary, ok := kv.Value.(*ast.Array); ok {
+				for _, elem := range ary.Value {
+					if str, ok := elem.(*ast.String); ok {
+						c.AvroFieldKeys = append(c.AvroFieldKeys, str.Value)
+					}
+				}
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["avro_timestamp_key"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				c.AvroTimestampKey = str.Value
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["avro_timestamp_format"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				c.AvroTimestampFormat = str.Value
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["avro_timezone"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				c.AvroTimezone = str.Value
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["json_v2"]; ok {
+		if subtbls, ok := node.([]*ast.Table); ok {
+			for _, subtbl := range subtbls {
+				var jc json_v2.Config
+				if err := toml.UnmarshalTable(subtbl, &jc); err != nil {
+					return nil, fmt.Errorf("Error parsing json_v2 config for %s: %s", name, err)
+				}
+				c.JSONV2Config = append(c.JSONV2Config, jc)
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["xml"]; ok {
+		if subtbls, ok := node.([]*ast.Table); ok {
+			for _, subtbl := range subtbls {
+				var xc xml.Config
+				if err := toml.UnmarshalTable(subtbl, &xc); err != nil {
+					return nil, fmt.Errorf("Error parsing xml config for %s: %s", name, err)
+				}
+				c.XMLConfig = append(c.XMLConfig, xc)
+			}
+		}
+	}
+
 	c.MetricName = name
 
 	delete(tbl.Fields, "data_format")
@@ -1808,6 +2358,7 @@ func getParserConfig(name string, tbl *ast.Table) (*parsers.Config, error) {
 	delete(tbl.Fields, "json_time_format")
 	delete(tbl.Fields, "json_time_key")
 	delete(tbl.Fields, "json_timezone")
+	delete(tbl.Fields, "json_v2")
 	delete(tbl.Fields, "data_type")
 	delete(tbl.Fields, "collectd_auth_file")
 	delete(tbl.Fields, "collectd_security_level")
@@ -1838,6 +2389,23 @@ func getParserConfig(name string, tbl *ast.Table) (*parsers.Config, error) {
 	delete(tbl.Fields, "csv_timestamp_format")
 	delete(tbl.Fields, "csv_trim_space")
 	delete(tbl.Fields, "form_urlencoded_tag_keys")
+	delete(tbl.Fields, "prometheus_metric_version")
+	delete(tbl.Fields, "protobuf_files")
+	delete(tbl.Fields, "protobuf_import_paths")
+	delete(tbl.Fields, "protobuf_message_type")
+	delete(tbl.Fields, "protobuf_tag_keys")
+	delete(tbl.Fields, "protobuf_field_keys")
+	delete(tbl.Fields, "protobuf_timestamp_key")
+	delete(tbl.Fields, "protobuf_timestamp_format")
+	delete(tbl.Fields, "protobuf_timezone")
+	delete(tbl.Fields, "avro_schema_file")
+	delete(tbl.Fields, "avro_schema_registry")
+	delete(tbl.Fields, "avro_tag_keys")
+	delete(tbl.Fields, "avro_field_keys")
+	delete(tbl.Fields, "avro_timestamp_key")
+	delete(tbl.Fields, "avro_timestamp_format")
+	delete(tbl.Fields, "avro_timezone")
+	delete(tbl.Fields, "xml")
 
 	return c, nil
 }
@@ -2084,11 +2652,142 @@ func buildOutput(name string, tbl *ast.Table) (*models.OutputConfig, error) {
 		}
 	}
 
+	if node, ok := tbl.Fields["log_level"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				oc.LogLevel = str.Value
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["buffer_strategy"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				oc.BufferStrategy = str.Value
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["buffer_directory"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				oc.BufferDirectory = str.Value
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["buffer_max_size"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			var size internal.Size
+			if err := size.UnmarshalTOML([]byte(kv.Value.Source())); err != nil {
+				return nil, fmt.Errorf("invalid buffer_max_size: %v", err)
+			}
+			oc.BufferMaxSize = size.Size
+		}
+	}
+
+	if node, ok := tbl.Fields["retry_initial_interval"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				dur, err := time.ParseDuration(str.Value)
+				if err != nil {
+					return nil, err
+				}
+				oc.RetryInitialInterval = dur
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["retry_max_interval"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				dur, err := time.ParseDuration(str.Value)
+				if err != nil {
+					return nil, err
+				}
+				oc.RetryMaxInterval = dur
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["retry_multiplier"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			switch v := kv.Value.(type) {
+			case *ast.Float:
+				f, err := v.Float()
+				if err != nil {
+					return nil, err
+				}
+				oc.RetryMultiplier = f
+			case *ast.Integer:
+				i, err := v.Int()
+				if err != nil {
+					return nil, err
+				}
+				oc.RetryMultiplier = float64(i)
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["retry_max_attempts"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if integer, ok := kv.Value.(*ast.Integer); ok {
+				v, err := integer.Int()
+				if err != nil {
+					return nil, err
+				}
+				oc.RetryMaxAttempts = int(v)
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["dead_letter_output"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				oc.DeadLetterOutput = str.Value
+			}
+		}
+	}
+
+	if oc.RetryMultiplier != 0 && oc.RetryMultiplier < 1 {
+		return nil, fmt.Errorf("retry_multiplier must be at least 1, found %v",
+			oc.RetryMultiplier)
+	}
+	if oc.LogLevel != "" {
+		if _, err := logger.ParseLevel(oc.LogLevel); err != nil {
+			return nil, fmt.Errorf("invalid log_level, %s", err)
+		}
+	}
+	if oc.DeadLetterOutput != "" && oc.DeadLetterOutput == oc.Alias {
+		return nil, fmt.Errorf("dead_letter_output %q cannot be the output itself",
+			oc.DeadLetterOutput)
+	}
+
+	switch oc.BufferStrategy {
+	case "", models.BUFFER_STRATEGY_MEMORY:
+	case models.BUFFER_STRATEGY_DISK:
+		if oc.BufferDirectory == "" {
+			return nil, fmt.Errorf("buffer_directory is required when using the %q buffer_strategy",
+				oc.BufferStrategy)
+		}
+	default:
+		return nil, fmt.Errorf("unknown buffer_strategy %q", oc.BufferStrategy)
+	}
+
 	delete(tbl.Fields, "flush_interval")
 	delete(tbl.Fields, "flush_jitter")
 	delete(tbl.Fields, "metric_buffer_limit")
 	delete(tbl.Fields, "metric_batch_size")
 	delete(tbl.Fields, "alias")
+	delete(tbl.Fields, "log_level")
+	delete(tbl.Fields, "buffer_strategy")
+	delete(tbl.Fields, "buffer_directory")
+	delete(tbl.Fields, "buffer_max_size")
+	delete(tbl.Fields, "retry_initial_interval")
+	delete(tbl.Fields, "retry_max_interval")
+	delete(tbl.Fields, "retry_multiplier")
+	delete(tbl.Fields, "retry_max_attempts")
+	delete(tbl.Fields, "dead_letter_output")
 
 	return oc, nil
 }
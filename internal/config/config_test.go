@@ -1,6 +1,7 @@
 package config
 
 import (
+	"io/ioutil"
 	"os"
 	"testing"
 	"time"
@@ -14,6 +15,10 @@ import (
 	"github.com/influxdata/telegraf/plugins/inputs/procstat"
 	httpOut "github.com/influxdata/telegraf/plugins/outputs/http"
 	"github.com/influxdata/telegraf/plugins/parsers"
+	"github.com/influxdata/telegraf/plugins/parsers/json_v2"
+	"github.com/influxdata/telegraf/plugins/parsers/xml"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/influxdata/toml/ast"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -256,3 +261,205 @@ func TestConfig_BadOrdering(t *testing.T) {
 	require.Error(t, err, "bad ordering")
 	assert.Equal(t, "Error parsing ./testdata/non_slice_slice.toml, line 4: cannot unmarshal TOML array into string (need slice)", err.Error())
 }
+
+func TestConfig_AgentRouting(t *testing.T) {
+	c := NewConfig()
+	require.NoError(t, c.LoadConfig("./testdata/routing.toml"))
+
+	require.Equal(t, RoutingConfig{
+		Routes: []RouteConfig{
+			{Tag: "env", Value: "prod*", Outputs: []string{"production"}},
+			{Measurement: "cpu*", Outputs: []string{"production", "system"}},
+		},
+		DefaultOutputs: []string{"fallback"},
+	}, c.Agent.Routing)
+}
+
+func TestConfig_AgentCardinality(t *testing.T) {
+	c := NewConfig()
+	require.NoError(t, c.LoadConfig("./testdata/cardinality.toml"))
+
+	require.Equal(t, CardinalityConfig{
+		MaxSeriesPerMeasurement: 1000,
+		Action:                  "strip_tag",
+		SeriesTTL:               internal.Duration{Duration: time.Hour},
+	}, c.Agent.Cardinality)
+}
+
+func TestConfig_JSONV2Parser(t *testing.T) {
+	contents, err := ioutil.ReadFile("./testdata/json_v2.toml")
+	require.NoError(t, err)
+	table, err := parseConfig(contents)
+	require.NoError(t, err)
+
+	tbl := table.Fields["inputs"].(*ast.Table).Fields["exec"].([]*ast.Table)[0]
+	c, err := getParserConfig("exec", tbl)
+	require.NoError(t, err)
+	require.Equal(t, "json_v2", c.DataFormat)
+	require.Equal(t, []json_v2.Config{
+		{
+			MeasurementName: "books",
+			TimestampPath:   "timestamp",
+			TimestampFormat: "unix",
+			Objects: []json_v2.Object{
+				{
+					Path:         "books",
+					Tags:         []string{"title"},
+					ExcludedKeys: []string{"isbn"},
+					Fields:       map[string]string{"pages": "int"},
+				},
+				{
+					Path:               "authors",
+					DisablePrependKeys: true,
+					Renames:            map[string]string{"name": "author"},
+				},
+			},
+		},
+	}, c.JSONV2Config)
+	require.NotContains(t, tbl.Fields, "json_v2")
+
+	cfg := NewConfig()
+	require.NoError(t, cfg.LoadConfig("./testdata/json_v2.toml"))
+	require.Len(t, cfg.Inputs, 1)
+}
+
+func TestConfig_BinaryParsers(t *testing.T) {
+	contents, err := ioutil.ReadFile("./testdata/binary_parsers.toml")
+	require.NoError(t, err)
+	table, err := parseConfig(contents)
+	require.NoError(t, err)
+
+	tbls := table.Fields["inputs"].(*ast.Table).Fields["exec"].([]*ast.Table)
+	require.Len(t, tbls, 2)
+
+	c, err := getParserConfig("exec", tbls[0])
+	require.NoError(t, err)
+	require.Equal(t, "protobuf", c.DataFormat)
+	require.Equal(t, []string{"sensor.proto"}, c.ProtobufFiles)
+	require.Equal(t, []string{"/etc/telegraf/proto"}, c.ProtobufImportPaths)
+	require.Equal(t, "sensors.Reading", c.ProtobufMessageType)
+	require.Equal(t, []string{"device"}, c.ProtobufTagKeys)
+	require.Equal(t, []string{"temperature"}, c.ProtobufFieldKeys)
+	require.Equal(t, "time", c.ProtobufTimestampKey)
+	require.Equal(t, "unix_ms", c.ProtobufTimestampFormat)
+	require.NotContains(t, tbls[0].Fields, "protobuf_files")
+
+	c, err = getParserConfig("exec", tbls[1])
+	require.NoError(t, err)
+	require.Equal(t, "avro", c.DataFormat)
+	require.Equal(t, "http://localhost:8081", c.AvroSchemaRegistry)
+	require.Equal(t, []string{"device"}, c.AvroTagKeys)
+	require.Equal(t, "time", c.AvroTimestampKey)
+	require.Equal(t, "Europe/Berlin", c.AvroTimezone)
+	require.NotContains(t, tbls[1].Fields, "avro_schema_registry")
+}
+
+func TestConfig_XMLParser(t *testing.T) {
+	contents, err := ioutil.ReadFile("./testdata/xml.toml")
+	require.NoError(t, err)
+	table, err := parseConfig(contents)
+	require.NoError(t, err)
+
+	tbl := table.Fields["inputs"].(*ast.Table).Fields["exec"].([]*ast.Table)[0]
+	c, err := getParserConfig("exec", tbl)
+	require.NoError(t, err)
+	require.Equal(t, "xml", c.DataFormat)
+	require.Equal(t, []xml.Config{
+		{
+			MetricSelection: "/Status/Battery",
+			MetricName:      "string('ups_battery')",
+			Timestamp:       "/Status/@time",
+			TimestampFormat: "unix",
+			Tags:            map[string]string{"id": "@id"},
+			Fields:          map[string]string{"charge": "number(Charge)"},
+		},
+		{
+			MetricSelection: "/Status/Input",
+			FieldsInt:       map[string]string{"voltage": "Voltage"},
+		},
+	}, c.XMLConfig)
+	require.NotContains(t, tbl.Fields, "xml")
+
+	cfg := NewConfig()
+	require.NoError(t, cfg.LoadConfig("./testdata/xml.toml"))
+	require.Len(t, cfg.Inputs, 1)
+}
+
+func TestConfig_MetricPass(t *testing.T) {
+	c := NewConfig()
+	require.NoError(t, c.LoadConfig("./testdata/metricpass.toml"))
+	require.Len(t, c.Inputs, 1)
+
+	f := c.Inputs[0].Config.Filter
+	require.Equal(t, `fields.evictions > 0 && tags.server =~ "^cache"`, f.MetricPass)
+	require.True(t, f.IsActive())
+
+	m := testutil.MustMetric("memcached",
+		map[string]string{"server": "cache01"},
+		map[string]interface{}{"evictions": int64(3)},
+		time.Unix(0, 0))
+	require.True(t, f.Select(m))
+
+	m = testutil.MustMetric("memcached",
+		map[string]string{"server": "cache01"},
+		map[string]interface{}{"evictions": int64(0)},
+		time.Unix(0, 0))
+	require.False(t, f.Select(m))
+}
+
+func TestConfig_LogLevel(t *testing.T) {
+	c := NewConfig()
+	require.NoError(t, c.LoadConfig("./testdata/log_level.toml"))
+	require.Len(t, c.Inputs, 1)
+	require.Len(t, c.Outputs, 1)
+	require.Equal(t, "debug", c.Inputs[0].Config.LogLevel)
+	require.Equal(t, "error", c.Outputs[0].Config.LogLevel)
+
+	c = NewConfig()
+	err := c.LoadConfig("./testdata/invalid_log_level.toml")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "invalid log_level")
+}
+
+func TestConfig_Schedule(t *testing.T) {
+	c := NewConfig()
+	require.NoError(t, c.LoadConfig("./testdata/schedule.toml"))
+	require.Len(t, c.Inputs, 1)
+
+	schedule := c.Inputs[0].Config.Schedule
+	require.NotNil(t, schedule)
+	require.Equal(t, "0 */6 * * *", schedule.String())
+	require.Equal(t, "Europe/Berlin", schedule.Location().String())
+
+	c = NewConfig()
+	err := c.LoadConfig("./testdata/invalid_schedule.toml")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "invalid schedule")
+}
+
+func TestConfig_GatherTimeout(t *testing.T) {
+	c := NewConfig()
+	require.NoError(t, c.LoadConfig("./testdata/gather_timeout.toml"))
+	require.Len(t, c.Inputs, 1)
+	require.Equal(t, 30*time.Second, c.Inputs[0].Config.GatherTimeout)
+	require.Equal(t, models.OverlapSkip, c.Inputs[0].Config.OverlapPolicy)
+
+	c = NewConfig()
+	err := c.LoadConfig("./testdata/invalid_overlap_policy.toml")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "invalid overlap_policy")
+}
+
+func TestConfig_MaxMetrics(t *testing.T) {
+	c := NewConfig()
+	require.NoError(t, c.LoadConfig("./testdata/max_metrics.toml"))
+	require.Len(t, c.Inputs, 1)
+	require.Equal(t, 1000, c.Inputs[0].Config.MaxMetricsPerGather)
+	require.Equal(t, 500, c.Inputs[0].Config.MaxMetricsPerSecond)
+	require.Equal(t, models.LimitSample, c.Inputs[0].Config.MaxMetricsPolicy)
+
+	c = NewConfig()
+	err := c.LoadConfig("./testdata/invalid_max_metrics_policy.toml")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "invalid max_metrics_policy")
+}
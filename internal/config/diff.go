@@ -0,0 +1,290 @@
+package config
+
+import (
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/influxdata/telegraf/internal/models"
+	"github.com/influxdata/toml/ast"
+)
+
+// Diff describes the changes needed to go from a running configuration to a
+// newly loaded one.
+//
+// The plugin lists hold the complete set of plugins of the new
+// configuration, in order, where plugins with an unchanged table are the
+// instances from the running configuration so their state is kept.  The
+// Added lists contain the new instances that need to be started and the
+// Removed lists the running instances that need to be stopped.
+type Diff struct {
+	// Restart is true when the agent, global tags or secret stores changed,
+	// these affect every plugin so the agent must be restarted.
+	Restart bool
+
+	Inputs        []*models.RunningInput
+	AddedInputs   []*models.RunningInput
+	RemovedInputs []*models.RunningInput
+
+	Processors        models.RunningProcessors
+	AddedProcessors   []*models.RunningProcessor
+	RemovedProcessors []*models.RunningProcessor
+
+	Aggregators        []*models.RunningAggregator
+	AddedAggregators   []*models.RunningAggregator
+	RemovedAggregators []*models.RunningAggregator
+
+	Outputs        []*models.RunningOutput
+	AddedOutputs   []*models.RunningOutput
+	RemovedOutputs []*models.RunningOutput
+}
+
+// Empty returns true if there are no changes.
+func (d *Diff) Empty() bool {
+	return !d.Restart &&
+		len(d.AddedInputs) == 0 && len(d.RemovedInputs) == 0 &&
+		len(d.AddedProcessors) == 0 && len(d.RemovedProcessors) == 0 &&
+		len(d.AddedAggregators) == 0 && len(d.RemovedAggregators) == 0 &&
+		len(d.AddedOutputs) == 0 && len(d.RemovedOutputs) == 0
+}
+
+// Diff compares c against the running configuration.  Plugins are matched
+// by their type, name and the contents of their table; formatting, comments
+// and the order of keys are not significant.
+func (c *Config) Diff(running *Config) *Diff {
+	d := &Diff{}
+
+	if !reflect.DeepEqual(c.Agent, running.Agent) ||
+		!reflect.DeepEqual(c.Tags, running.Tags) ||
+		!reflect.DeepEqual(c.secretStoreFingerprints(), running.secretStoreFingerprints()) {
+		d.Restart = true
+	}
+
+	m := newPluginMatcher(running)
+	for _, input := range running.Inputs {
+		m.add(input)
+	}
+	for _, input := range c.Inputs {
+		if old, ok := m.take(c.fingerprints[input]); ok {
+			d.Inputs = append(d.Inputs, old.(*models.RunningInput))
+			continue
+		}
+		d.Inputs = append(d.Inputs, input)
+		d.AddedInputs = append(d.AddedInputs, input)
+	}
+	for _, input := range running.Inputs {
+		if !m.matched(input) {
+			d.RemovedInputs = append(d.RemovedInputs, input)
+		}
+	}
+
+	m = newPluginMatcher(running)
+	for _, processor := range running.Processors {
+		m.add(processor)
+	}
+	for _, processor := range c.Processors {
+		if old, ok := m.take(c.fingerprints[processor]); ok {
+			d.Processors = append(d.Processors, old.(*models.RunningProcessor))
+			continue
+		}
+		d.Processors = append(d.Processors, processor)
+		d.AddedProcessors = append(d.AddedProcessors, processor)
+	}
+	for _, processor := range running.Processors {
+		if !m.matched(processor) {
+			d.RemovedProcessors = append(d.RemovedProcessors, processor)
+		}
+	}
+
+	m = newPluginMatcher(running)
+	for _, aggregator := range running.Aggregators {
+		m.add(aggregator)
+	}
+	for _, aggregator := range c.Aggregators {
+		if old, ok := m.take(c.fingerprints[aggregator]); ok {
+			d.Aggregators = append(d.Aggregators, old.(*models.RunningAggregator))
+			continue
+		}
+		d.Aggregators = append(d.Aggregators, aggregator)
+		d.AddedAggregators = append(d.AddedAggregators, aggregator)
+	}
+	for _, aggregator := range running.Aggregators {
+		if !m.matched(aggregator) {
+			d.RemovedAggregators = append(d.RemovedAggregators, aggregator)
+		}
+	}
+
+	m = newPluginMatcher(running)
+	for _, output := range running.Outputs {
+		m.add(output)
+	}
+	for _, output := range c.Outputs {
+		if old, ok := m.take(c.fingerprints[output]); ok {
+			d.Outputs = append(d.Outputs, old.(*models.RunningOutput))
+			continue
+		}
+		d.Outputs = append(d.Outputs, output)
+		d.AddedOutputs = append(d.AddedOutputs, output)
+	}
+	for _, output := range running.Outputs {
+		if !m.matched(output) {
+			d.RemovedOutputs = append(d.RemovedOutputs, output)
+		}
+	}
+
+	return d
+}
+
+// Apply replaces the plugins of c with the plugins from the diff, so that c
+// describes the running plugins once the diff has been applied.  The diff
+// must have been created by calling Diff on from.
+func (c *Config) Apply(d *Diff, from *Config) {
+	fingerprints := make(map[interface{}]string)
+	secrets := make(map[interface{}]*SecretOptions)
+	lookup := func(plugin interface{}) {
+		if fingerprint, ok := c.fingerprints[plugin]; ok {
+			fingerprints[plugin] = fingerprint
+		} else if fingerprint, ok := from.fingerprints[plugin]; ok {
+			fingerprints[plugin] = fingerprint
+		}
+		if options, ok := c.secrets[plugin]; ok {
+			secrets[plugin] = options
+		} else if options, ok := from.secrets[plugin]; ok {
+			secrets[plugin] = options
+		}
+	}
+	for _, input := range d.Inputs {
+		lookup(input)
+	}
+	for _, processor := range d.Processors {
+		lookup(processor)
+	}
+	for _, aggregator := range d.Aggregators {
+		lookup(aggregator)
+	}
+	for _, output := range d.Outputs {
+		lookup(output)
+	}
+	for _, store := range c.SecretStores {
+		lookup(store)
+	}
+
+	c.Inputs = d.Inputs
+	c.Processors = d.Processors
+	c.Aggregators = d.Aggregators
+	c.Outputs = d.Outputs
+	c.fingerprints = fingerprints
+	c.secrets = secrets
+}
+
+// secretStoreFingerprints returns the fingerprint of each secret store by
+// its id.
+func (c *Config) secretStoreFingerprints() map[string]string {
+	fingerprints := make(map[string]string, len(c.SecretStores))
+	for id, store := range c.SecretStores {
+		fingerprints[id] = c.fingerprints[store]
+	}
+	return fingerprints
+}
+
+// pluginMatcher pairs plugins with identical fingerprints, each plugin can
+// only be matched once.
+type pluginMatcher struct {
+	config  *Config
+	plugins map[string][]interface{}
+	used    map[interface{}]bool
+}
+
+func newPluginMatcher(c *Config) *pluginMatcher {
+	return &pluginMatcher{
+		config:  c,
+		plugins: make(map[string][]interface{}),
+		used:    make(map[interface{}]bool),
+	}
+}
+
+func (m *pluginMatcher) add(plugin interface{}) {
+	fingerprint, ok := m.config.fingerprints[plugin]
+	if !ok {
+		return
+	}
+	m.plugins[fingerprint] = append(m.plugins[fingerprint], plugin)
+}
+
+func (m *pluginMatcher) take(fingerprint string) (interface{}, bool) {
+	plugins := m.plugins[fingerprint]
+	if fingerprint == "" || len(plugins) == 0 {
+		return nil, false
+	}
+	m.plugins[fingerprint] = plugins[1:]
+	m.used[plugins[0]] = true
+	return plugins[0], true
+}
+
+func (m *pluginMatcher) matched(plugin interface{}) bool {
+	return m.used[plugin]
+}
+
+func (c *Config) setFingerprint(plugin interface{}, fingerprint string) {
+	if c.fingerprints == nil {
+		c.fingerprints = make(map[interface{}]string)
+	}
+	c.fingerprints[plugin] = fingerprint
+}
+
+// tableFingerprint returns a canonical representation of a plugin table.
+// It must be called before the table is modified by the build functions.
+func tableFingerprint(name string, tbl *ast.Table) string {
+	var b strings.Builder
+	b.WriteString(name)
+	writeTable(&b, tbl)
+	return b.String()
+}
+
+func writeTable(b *strings.Builder, tbl *ast.Table) {
+	keys := make([]string, 0, len(tbl.Fields))
+	for key := range tbl.Fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	b.WriteByte('{')
+	for _, key := range keys {
+		b.WriteString(strconv.Quote(key))
+		b.WriteByte('=')
+		switch v := tbl.Fields[key].(type) {
+		case *ast.KeyValue:
+			writeValue(b, v.Value)
+		case *ast.Table:
+			writeTable(b, v)
+		case []*ast.Table:
+			b.WriteByte('[')
+			for _, t := range v {
+				writeTable(b, t)
+				b.WriteByte(',')
+			}
+			b.WriteByte(']')
+		}
+		b.WriteByte(';')
+	}
+	b.WriteByte('}')
+}
+
+func writeValue(b *strings.Builder, value ast.Value) {
+	switch v := value.(type) {
+	case *ast.Array:
+		b.WriteByte('[')
+		for _, elem := range v.Value {
+			writeValue(b, elem)
+			b.WriteByte(',')
+		}
+		b.WriteByte(']')
+	case *ast.Table:
+		writeTable(b, v)
+	case *ast.String:
+		b.WriteString(strconv.Quote(v.Value))
+	default:
+		b.WriteString(value.Source())
+	}
+}
@@ -0,0 +1,59 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfig_DiffUnchanged(t *testing.T) {
+	running := NewConfig()
+	require.NoError(t, running.LoadConfig("./testdata/diff_before.toml"))
+
+	c := NewConfig()
+	require.NoError(t, c.LoadConfig("./testdata/diff_before.toml"))
+
+	diff := c.Diff(running)
+	require.True(t, diff.Empty())
+	require.Equal(t, running.Inputs, diff.Inputs)
+	require.Equal(t, running.Outputs, diff.Outputs)
+}
+
+func TestConfig_DiffChangedInput(t *testing.T) {
+	running := NewConfig()
+	require.NoError(t, running.LoadConfig("./testdata/diff_before.toml"))
+
+	c := NewConfig()
+	require.NoError(t, c.LoadConfig("./testdata/diff_after.toml"))
+
+	diff := c.Diff(running)
+	require.False(t, diff.Empty())
+	require.False(t, diff.Restart)
+
+	require.Len(t, diff.Inputs, 2)
+	require.True(t, diff.Inputs[0] == running.Inputs[0])
+	require.True(t, diff.Inputs[1] == c.Inputs[1])
+	require.Len(t, diff.AddedInputs, 1)
+	require.True(t, diff.AddedInputs[0] == c.Inputs[1])
+	require.Len(t, diff.RemovedInputs, 1)
+	require.True(t, diff.RemovedInputs[0] == running.Inputs[1])
+
+	require.Len(t, diff.Outputs, 1)
+	require.True(t, diff.Outputs[0] == running.Outputs[0])
+	require.Empty(t, diff.AddedOutputs)
+	require.Empty(t, diff.RemovedOutputs)
+
+	running.Apply(diff, c)
+	require.True(t, c.Diff(running).Empty())
+}
+
+func TestConfig_DiffAgentChanged(t *testing.T) {
+	running := NewConfig()
+	require.NoError(t, running.LoadConfig("./testdata/diff_before.toml"))
+
+	c := NewConfig()
+	require.NoError(t, c.LoadConfig("./testdata/diff_before.toml"))
+	c.Agent.Interval.Duration *= 2
+
+	require.True(t, c.Diff(running).Restart)
+}
@@ -0,0 +1,246 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal/models"
+	"github.com/influxdata/telegraf/plugins/secretstores"
+	"github.com/influxdata/toml/ast"
+)
+
+var (
+	// secretRe matches a secret reference "@{<id>:<key>}".
+	secretRe = regexp.MustCompile(`@\{([\w-]+):([^{}]+)\}`)
+
+	secretStoreIDRe = regexp.MustCompile(`^[\w-]+$`)
+)
+
+func (c *Config) addSecretStore(name string, table *ast.Table) error {
+	creator, ok := secretstores.SecretStores[name]
+	if !ok {
+		return fmt.Errorf("Undefined but requested secretstore: %s", name)
+	}
+	store := creator()
+	fingerprint := tableFingerprint("secretstores."+name, table)
+
+	var id string
+	if node, ok := table.Fields["id"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				id = str.Value
+			}
+		}
+	}
+	delete(table.Fields, "id")
+
+	if !secretStoreIDRe.MatchString(id) {
+		return fmt.Errorf("secretstore %s requires an id of letters, digits, '_' or '-'", name)
+	}
+	if _, ok := c.SecretStores[id]; ok {
+		return fmt.Errorf("duplicate secretstore id %q", id)
+	}
+
+	if err := c.unmarshalTable(table, store); err != nil {
+		return err
+	}
+
+	c.setFingerprint(store, fingerprint)
+	c.SecretStores[id] = store
+	return nil
+}
+
+// SecretStore returns the secret store with the id, initialized on first
+// use.
+func (c *Config) SecretStore(id string) (telegraf.SecretStore, error) {
+	store, ok := c.SecretStores[id]
+	if !ok {
+		return nil, fmt.Errorf("unknown secretstore %q", id)
+	}
+
+	if c.initializedStores == nil {
+		c.initializedStores = make(map[string]bool)
+	}
+	if !c.initializedStores[id] {
+		if p, ok := store.(telegraf.Initializer); ok {
+			err := p.Init()
+			if err != nil {
+				return nil, fmt.Errorf("could not initialize secretstore %q: %v", id, err)
+			}
+		}
+		c.initializedStores[id] = true
+	}
+	return store, nil
+}
+
+// ResolveSecrets replaces the secret references in the options of the
+// plugin with the values from the secret stores.  It must be called before
+// the plugin is initialized.  The options which held a reference are
+// returned by SecretOptions.
+func (c *Config) ResolveSecrets(plugin interface{}) error {
+	r := &secretResolver{
+		config:  c,
+		visited: make(map[uintptr]bool),
+		options: &SecretOptions{
+			strings: make(map[uintptr]bool),
+			mapKeys: make(map[uintptr]map[string]bool),
+		},
+	}
+
+	var err error
+	switch p := plugin.(type) {
+	case *models.RunningInput:
+		err = r.resolve(reflect.ValueOf(p.Input))
+	case *models.RunningProcessor:
+		for _, processor := range p.Instances() {
+			if err = r.resolve(reflect.ValueOf(processor)); err != nil {
+				break
+			}
+		}
+	case *models.RunningAggregator:
+		err = r.resolve(reflect.ValueOf(p.Aggregator))
+	case *models.RunningOutput:
+		err = r.resolve(reflect.ValueOf(p.Output))
+	default:
+		err = r.resolve(reflect.ValueOf(plugin))
+	}
+	if err != nil {
+		return err
+	}
+
+	if c.secrets == nil {
+		c.secrets = make(map[interface{}]*SecretOptions)
+	}
+	c.secrets[plugin] = r.options
+	return nil
+}
+
+// SecretOptions returns the options of the plugin which held a secret
+// reference when its secrets were resolved, or nil if they were not.
+func (c *Config) SecretOptions(plugin interface{}) *SecretOptions {
+	return c.secrets[plugin]
+}
+
+// SecretOptions are the options of a plugin which held a secret reference.
+// Options are identified by their address, so they must be looked up on
+// the plugin the secrets were resolved on.
+type SecretOptions struct {
+	strings map[uintptr]bool
+	mapKeys map[uintptr]map[string]bool
+}
+
+// String returns true if the string option v held a secret reference, v
+// must be addressable.
+func (s *SecretOptions) String(v reflect.Value) bool {
+	if s == nil || !v.CanAddr() {
+		return false
+	}
+	return s.strings[v.UnsafeAddr()]
+}
+
+// MapValue returns true if the value of the map option v with the key held
+// a secret reference.
+func (s *SecretOptions) MapValue(v reflect.Value, key string) bool {
+	if s == nil || v.Kind() != reflect.Map || v.IsNil() {
+		return false
+	}
+	return s.mapKeys[v.Pointer()][key]
+}
+
+// secretResolver walks the exported options of a plugin and replaces the
+// secret references in strings.  Interfaces and unexported fields are not
+// walked as they are not set from the configuration.
+type secretResolver struct {
+	config  *Config
+	visited map[uintptr]bool
+	options *SecretOptions
+}
+
+func (r *secretResolver) resolve(v reflect.Value) error {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() || r.visited[v.Pointer()] {
+			return nil
+		}
+		r.visited[v.Pointer()] = true
+		return r.resolve(v.Elem())
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if v.Type().Field(i).PkgPath != "" {
+				continue
+			}
+			if err := r.resolve(v.Field(i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if err := r.resolve(v.Index(i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		if v.Type().Elem().Kind() != reflect.String {
+			return nil
+		}
+		for _, key := range v.MapKeys() {
+			value := v.MapIndex(key)
+			resolved, err := r.replace(value.String())
+			if err != nil {
+				return err
+			}
+			if resolved != value.String() {
+				v.SetMapIndex(key, reflect.ValueOf(resolved).Convert(value.Type()))
+				keys, ok := r.options.mapKeys[v.Pointer()]
+				if !ok {
+					keys = make(map[string]bool)
+					r.options.mapKeys[v.Pointer()] = keys
+				}
+				keys[key.String()] = true
+			}
+		}
+	case reflect.String:
+		if !v.CanSet() {
+			return nil
+		}
+		resolved, err := r.replace(v.String())
+		if err != nil {
+			return err
+		}
+		if resolved != v.String() {
+			v.SetString(resolved)
+			r.options.strings[v.UnsafeAddr()] = true
+		}
+	}
+	return nil
+}
+
+// replace returns s with the secret references replaced by their values.
+func (r *secretResolver) replace(s string) (string, error) {
+	var err error
+	resolved := secretRe.ReplaceAllStringFunc(s, func(ref string) string {
+		if err != nil {
+			return ref
+		}
+
+		match := secretRe.FindStringSubmatch(ref)
+		id, key := match[1], match[2]
+
+		var store telegraf.SecretStore
+		store, err = r.config.SecretStore(id)
+		if err != nil {
+			return ref
+		}
+
+		var value []byte
+		value, err = store.Get(key)
+		if err != nil {
+			err = fmt.Errorf("could not get secret %q from secretstore %q: %v", key, id, err)
+			return ref
+		}
+		return string(value)
+	})
+	return resolved, err
+}
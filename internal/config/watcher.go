@@ -0,0 +1,171 @@
+package config
+
+import (
+	"context"
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/fsnotify.v1"
+)
+
+// Time to wait for further changes before reporting a change, editors and
+// Kubernetes ConfigMap updates often touch several files in quick succession.
+const DEFAULT_WATCH_DELAY = time.Second
+
+// Watcher reports changes to the configuration file and the files in the
+// configuration directory.
+type Watcher struct {
+	file      string
+	directory string
+	delay     time.Duration
+
+	watcher *fsnotify.Watcher
+}
+
+// NewWatcher returns a Watcher for the configuration file at path and the
+// *.conf files under directory.  If path is empty the default configuration
+// file is watched, configuration loaded from a URL is not watched.
+func NewWatcher(path, directory string) (*Watcher, error) {
+	var err error
+	if path == "" {
+		if path, err = getDefaultConfigPath(); err != nil {
+			return nil, err
+		}
+	}
+
+	if u, err := url.Parse(path); err == nil && (u.Scheme == "http" || u.Scheme == "https") {
+		log.Printf("W! Not watching %s, only local configuration files can be watched", path)
+		path = ""
+	}
+
+	w := &Watcher{
+		delay: DEFAULT_WATCH_DELAY,
+	}
+	w.watcher, err = fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+
+	if path != "" {
+		w.file, err = filepath.Abs(path)
+		if err != nil {
+			w.watcher.Close()
+			return nil, err
+		}
+
+		// Watch the parent directory rather than the file itself, as the
+		// file is usually replaced rather than written to.
+		err = w.watcher.Add(filepath.Dir(w.file))
+		if err != nil {
+			w.watcher.Close()
+			return nil, err
+		}
+	}
+
+	if directory != "" {
+		w.directory, err = filepath.Abs(directory)
+		if err != nil {
+			w.watcher.Close()
+			return nil, err
+		}
+
+		err = w.addDirectory(w.directory)
+		if err != nil {
+			w.watcher.Close()
+			return nil, err
+		}
+	}
+
+	return w, nil
+}
+
+// addDirectory watches the directory and all of its subdirectories, using
+// the same rules as LoadDirectory.
+func (w *Watcher) addDirectory(path string) error {
+	return filepath.Walk(path, func(thispath string, info os.FileInfo, _ error) error {
+		if info == nil || !info.IsDir() {
+			return nil
+		}
+		if thispath != w.directory && strings.HasPrefix(info.Name(), "..") {
+			return filepath.SkipDir
+		}
+		return w.watcher.Add(thispath)
+	})
+}
+
+// Run calls changed each time the configuration changes, until the context
+// is done.
+func (w *Watcher) Run(ctx context.Context, changed func()) {
+	timer := time.NewTimer(w.delay)
+	if !timer.Stop() {
+		<-timer.C
+	}
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-w.watcher.Events:
+			if !ok {
+				return
+			}
+			if w.isConfigEvent(event) {
+				timer.Reset(w.delay)
+			}
+		case err, ok := <-w.watcher.Errors:
+			if !ok {
+				return
+			}
+			log.Printf("E! Error watching configuration: %v", err)
+		case <-timer.C:
+			changed()
+		}
+	}
+}
+
+// isConfigEvent returns true if the event may change the configuration.
+func (w *Watcher) isConfigEvent(event fsnotify.Event) bool {
+	if event.Op == fsnotify.Chmod {
+		return false
+	}
+
+	name := filepath.Clean(event.Name)
+	dir, base := filepath.Split(name)
+	dir = filepath.Clean(dir)
+
+	// Kubernetes updates mounted ConfigMaps by swapping a "..data" symlink
+	// next to the files.
+	isMount := strings.HasPrefix(base, "..")
+
+	if w.file != "" && dir == filepath.Dir(w.file) {
+		if name == w.file || isMount {
+			return true
+		}
+	}
+
+	if w.directory != "" && (dir == w.directory || strings.HasPrefix(dir, w.directory+string(filepath.Separator))) {
+		if isMount || strings.HasSuffix(base, ".conf") {
+			return true
+		}
+		if event.Op&fsnotify.Create != 0 {
+			if info, err := os.Stat(name); err == nil && info.IsDir() {
+				if err := w.addDirectory(name); err != nil {
+					log.Printf("E! Error watching configuration: %v", err)
+				}
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// Close stops watching the configuration.
+func (w *Watcher) Close() error {
+	return w.watcher.Close()
+}
@@ -0,0 +1,87 @@
+package config
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func setupWatcher(t *testing.T) (string, *Watcher, chan struct{}, func()) {
+	dir, err := ioutil.TempDir("", "telegraf-watch")
+	require.NoError(t, err)
+
+	file := filepath.Join(dir, "telegraf.conf")
+	require.NoError(t, ioutil.WriteFile(file, []byte("[agent]\n"), 0644))
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "telegraf.d"), 0755))
+
+	w, err := NewWatcher(file, filepath.Join(dir, "telegraf.d"))
+	require.NoError(t, err)
+	w.delay = 10 * time.Millisecond
+
+	changed := make(chan struct{}, 10)
+	ctx, cancel := context.WithCancel(context.Background())
+	go w.Run(ctx, func() {
+		changed <- struct{}{}
+	})
+
+	return dir, w, changed, func() {
+		cancel()
+		w.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func requireChanged(t *testing.T, changed chan struct{}) {
+	select {
+	case <-changed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("no change reported")
+	}
+}
+
+func requireUnchanged(t *testing.T, changed chan struct{}) {
+	select {
+	case <-changed:
+		t.Fatal("unexpected change reported")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestWatcher_ConfigFile(t *testing.T) {
+	dir, _, changed, cleanup := setupWatcher(t)
+	defer cleanup()
+
+	err := ioutil.WriteFile(filepath.Join(dir, "telegraf.conf"), []byte("[agent]\n  debug = true\n"), 0644)
+	require.NoError(t, err)
+	requireChanged(t, changed)
+
+	err = ioutil.WriteFile(filepath.Join(dir, "other.conf"), []byte("[agent]\n"), 0644)
+	require.NoError(t, err)
+	requireUnchanged(t, changed)
+}
+
+func TestWatcher_ConfigDirectory(t *testing.T) {
+	dir, _, changed, cleanup := setupWatcher(t)
+	defer cleanup()
+
+	err := ioutil.WriteFile(filepath.Join(dir, "telegraf.d", "inputs.conf"), []byte("[[inputs.memcached]]\n"), 0644)
+	require.NoError(t, err)
+	requireChanged(t, changed)
+
+	err = ioutil.WriteFile(filepath.Join(dir, "telegraf.d", "README"), []byte("notes\n"), 0644)
+	require.NoError(t, err)
+	requireUnchanged(t, changed)
+
+	sub := filepath.Join(dir, "telegraf.d", "sub")
+	require.NoError(t, os.Mkdir(sub, 0755))
+	requireChanged(t, changed)
+
+	err = ioutil.WriteFile(filepath.Join(sub, "outputs.conf"), []byte("[[outputs.http]]\n"), 0644)
+	require.NoError(t, err)
+	requireChanged(t, changed)
+}
@@ -0,0 +1,149 @@
+package cron
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNext(t *testing.T) {
+	tests := []struct {
+		name     string
+		spec     string
+		now      string
+		expected string
+	}{
+		{
+			name:     "every six hours",
+			spec:     "0 */6 * * *",
+			now:      "2020-01-01T01:02:03Z",
+			expected: "2020-01-01T06:00:00Z",
+		},
+		{
+			name:     "exact match is skipped",
+			spec:     "0 */6 * * *",
+			now:      "2020-01-01T06:00:00Z",
+			expected: "2020-01-01T12:00:00Z",
+		},
+		{
+			name:     "next day",
+			spec:     "30 2 * * *",
+			now:      "2020-01-01T02:30:01Z",
+			expected: "2020-01-02T02:30:00Z",
+		},
+		{
+			name:     "list and range",
+			spec:     "15,45 9-17 * * mon-fri",
+			now:      "2020-01-03T17:50:00Z",
+			expected: "2020-01-06T09:15:00Z",
+		},
+		{
+			name:     "range with step",
+			spec:     "10-40/15 * * * *",
+			now:      "2020-01-01T00:26:00Z",
+			expected: "2020-01-01T00:40:00Z",
+		},
+		{
+			name:     "sunday as seven",
+			spec:     "0 0 * * 7",
+			now:      "2020-01-01T00:00:00Z",
+			expected: "2020-01-05T00:00:00Z",
+		},
+		{
+			name:     "day of month or day of week",
+			spec:     "0 0 15 * fri",
+			now:      "2020-01-04T00:00:00Z",
+			expected: "2020-01-10T00:00:00Z",
+		},
+		{
+			name:     "month names",
+			spec:     "0 0 1 mar,sep *",
+			now:      "2020-04-01T00:00:00Z",
+			expected: "2020-09-01T00:00:00Z",
+		},
+		{
+			name:     "leap day",
+			spec:     "0 0 29 2 *",
+			now:      "2020-03-01T00:00:00Z",
+			expected: "2024-02-29T00:00:00Z",
+		},
+		{
+			name:     "descriptor",
+			spec:     "@monthly",
+			now:      "2020-01-31T23:59:00Z",
+			expected: "2020-02-01T00:00:00Z",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := Parse(tt.spec, time.UTC)
+			require.NoError(t, err)
+
+			now, err := time.Parse(time.RFC3339, tt.now)
+			require.NoError(t, err)
+			expected, err := time.Parse(time.RFC3339, tt.expected)
+			require.NoError(t, err)
+
+			require.True(t, expected.Equal(s.Next(now)),
+				"expected %s, got %s", expected, s.Next(now))
+		})
+	}
+}
+
+func TestNext_Location(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+
+	s, err := Parse("0 9 * * *", loc)
+	require.NoError(t, err)
+
+	next := s.Next(time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC))
+	require.Equal(t, time.Date(2020, 1, 1, 14, 0, 0, 0, time.UTC), next.UTC())
+	require.Equal(t, loc, next.Location())
+}
+
+func TestNext_DaylightSavingTime(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+
+	// 02:30 does not exist when the clocks go forward.
+	s, err := Parse("30 2 * * *", loc)
+	require.NoError(t, err)
+	next := s.Next(time.Date(2020, 3, 8, 0, 0, 0, 0, loc))
+	require.Equal(t, time.Date(2020, 3, 9, 2, 30, 0, 0, loc), next)
+
+	// 01:30 occurs twice when the clocks go back, it matches once.
+	s, err = Parse("30 1 * * *", loc)
+	require.NoError(t, err)
+	first := s.Next(time.Date(2020, 11, 1, 0, 0, 0, 0, loc))
+	require.Equal(t, 1, first.Hour())
+	require.Equal(t, 30, first.Minute())
+	second := s.Next(first)
+	require.Equal(t, 2, second.Day())
+}
+
+func TestParse_Invalid(t *testing.T) {
+	tests := []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"a * * * *",
+		"* * * foo *",
+		"@sometimes",
+		"0 0 30 2 *",
+	}
+	for _, spec := range tests {
+		t.Run(spec, func(t *testing.T) {
+			_, err := Parse(spec, time.UTC)
+			require.Error(t, err)
+		})
+	}
+}
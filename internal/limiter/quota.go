@@ -0,0 +1,85 @@
+package limiter
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// Quota limits the number of events allowed in a period.
+//
+// Periods either have a fixed length, or are started explicitly by calling
+// Reset.  Once the limit is reached all further events of the period are
+// rejected.  When sampling, events are also rejected at random if the
+// previous period exceeded the limit, so that the allowed events are spread
+// over the period instead of being the first ones.
+type Quota struct {
+	mu sync.Mutex
+
+	limit  int
+	period time.Duration
+	sample bool
+	rand   func() float64
+
+	start   time.Time
+	count   int
+	allowed int
+	ratio   float64
+}
+
+// NewQuota returns a quota of limit events per period.  If period is zero a
+// new period is only started by Reset.
+func NewQuota(limit int, period time.Duration, sample bool) *Quota {
+	return &Quota{
+		limit:  limit,
+		period: period,
+		sample: sample,
+		rand:   rand.Float64,
+		ratio:  1,
+	}
+}
+
+// Allow records an event at the time now and returns true if it is within
+// the quota.
+func (q *Quota) Allow(now time.Time) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.period > 0 {
+		elapsed := now.Sub(q.start)
+		if elapsed >= 2*q.period {
+			// The previous period had no events at all.
+			q.count = 0
+		}
+		if elapsed >= q.period {
+			q.reset()
+			q.start = now.Truncate(q.period)
+		}
+	}
+
+	q.count++
+	if q.allowed >= q.limit {
+		return false
+	}
+	if q.ratio < 1 && q.rand() >= q.ratio {
+		return false
+	}
+	q.allowed++
+	return true
+}
+
+// Reset starts a new period.
+func (q *Quota) Reset() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.reset()
+}
+
+func (q *Quota) reset() {
+	q.ratio = 1
+	if q.sample && q.count > q.limit {
+		q.ratio = float64(q.limit) / float64(q.count)
+	}
+	q.count = 0
+	q.allowed = 0
+}
@@ -0,0 +1,65 @@
+package limiter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func allowed(q *Quota, now time.Time, n int) int {
+	count := 0
+	for i := 0; i < n; i++ {
+		if q.Allow(now) {
+			count++
+		}
+	}
+	return count
+}
+
+func TestQuota_Period(t *testing.T) {
+	q := NewQuota(5, time.Second, false)
+	now := time.Unix(100, 0)
+
+	require.Equal(t, 5, allowed(q, now, 10))
+	require.Equal(t, 0, allowed(q, now.Add(500*time.Millisecond), 10))
+	require.Equal(t, 5, allowed(q, now.Add(time.Second), 10))
+}
+
+func TestQuota_Reset(t *testing.T) {
+	q := NewQuota(3, 0, false)
+	now := time.Unix(100, 0)
+
+	require.Equal(t, 3, allowed(q, now, 10))
+	require.Equal(t, 0, allowed(q, now.Add(time.Hour), 10))
+	q.Reset()
+	require.Equal(t, 3, allowed(q, now, 10))
+}
+
+func TestQuota_Sample(t *testing.T) {
+	q := NewQuota(5, 0, true)
+	values := []float64{0.1, 0.9, 0.3, 0.7, 0.2, 0.8, 0.4, 0.6, 0.0, 0.5}
+	q.rand = func() float64 {
+		v := values[0]
+		values = append(values[1:], v)
+		return v
+	}
+	now := time.Unix(100, 0)
+
+	// Nothing is sampled until the limit was exceeded.
+	require.Equal(t, 5, allowed(q, now, 10))
+
+	// The previous period had twice the limit, half of the events are kept.
+	q.Reset()
+	var kept []int
+	for i := 0; i < 10; i++ {
+		if q.Allow(now) {
+			kept = append(kept, i)
+		}
+	}
+	require.Equal(t, []int{0, 2, 4, 6, 8}, kept)
+
+	q.Reset()
+	q.Reset()
+	require.Equal(t, 5, allowed(q, now, 5))
+}
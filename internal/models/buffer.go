@@ -1,6 +1,7 @@
 package models
 
 import (
+	"path/filepath"
 	"sync"
 
 	"github.com/influxdata/telegraf"
@@ -24,6 +25,9 @@ type Buffer struct {
 	batchFirst int // index of the first metric in the batch
 	batchSize  int // number of metrics currently in the batch
 
+	wal *diskLog // write-ahead log when using the disk strategy
+	log telegraf.Logger
+
 	MetricsAdded   selfstat.Stat
 	MetricsWritten selfstat.Stat
 	MetricsDropped selfstat.Stat
@@ -90,12 +94,14 @@ func (b *Buffer) metricAdded() {
 func (b *Buffer) metricWritten(metric telegraf.Metric) {
 	AgentMetricsWritten.Incr(1)
 	b.MetricsWritten.Incr(1)
+	b.unlog(metric)
 	metric.Accept()
 }
 
 func (b *Buffer) metricDropped(metric telegraf.Metric) {
 	AgentMetricsDropped.Incr(1)
 	b.MetricsDropped.Incr(1)
+	b.unlog(metric)
 	metric.Reject()
 }
 
@@ -122,9 +128,30 @@ func (b *Buffer) add(m telegraf.Metric) int {
 	}
 
 	b.size = min(b.size+1, b.cap)
+
+	if b.wal != nil {
+		if err := b.wal.append(m); err != nil {
+			b.log.Errorf("Error writing metric to disk buffer: %v", err)
+		}
+
+		// Like the ring buffer, discard the oldest metrics when the
+		// persisted metrics exceed the size limit.
+		for b.wal.full() && b.size > 1 {
+			b.dropOldest()
+			dropped++
+		}
+	}
 	return dropped
 }
 
+// dropOldest drops the oldest metric that is not part of the current batch.
+func (b *Buffer) dropOldest() {
+	b.metricDropped(b.buf[b.first])
+	b.buf[b.first] = nil
+	b.first = b.next(b.first)
+	b.size--
+}
+
 // Add adds metrics to the buffer and returns number of dropped metrics.
 func (b *Buffer) Add(metrics ...telegraf.Metric) int {
 	b.Lock()
@@ -138,6 +165,13 @@ func (b *Buffer) Add(metrics ...telegraf.Metric) int {
 	}
 
 	b.BufferSize.Set(int64(b.length()))
+
+	if b.wal != nil && b.wal.needsCompaction() {
+		if err := b.wal.compact(); err != nil {
+			b.log.Errorf("Error compacting disk buffer: %v", err)
+		}
+	}
+	b.syncLog()
 	return dropped
 }
 
@@ -167,6 +201,7 @@ func (b *Buffer) Batch(batchSize int) []telegraf.Metric {
 
 	b.last = b.batchFirst
 	b.size -= outLen
+
 	return out
 }
 
@@ -181,6 +216,7 @@ func (b *Buffer) Accept(batch []telegraf.Metric) {
 
 	b.resetBatch()
 	b.BufferSize.Set(int64(b.length()))
+	b.syncLog()
 }
 
 // Reject returns the batch, acquired from Batch(), to the buffer and marks it
@@ -230,6 +266,114 @@ func (b *Buffer) Reject(batch []telegraf.Metric) {
 
 	b.resetBatch()
 	b.BufferSize.Set(int64(b.length()))
+	b.syncLog()
+}
+
+// Drop removes the batch, acquired from Batch(), from the buffer and marks it
+// as dropped.
+func (b *Buffer) Drop(batch []telegraf.Metric) {
+	b.Lock()
+	defer b.Unlock()
+
+	for _, m := range batch {
+		b.metricDropped(m)
+	}
+
+	b.resetBatch()
+	b.BufferSize.Set(int64(b.length()))
+	b.syncLog()
+}
+
+// Release removes the batch, acquired from Batch(), from the buffer without
+// marking it as written or dropped.  The caller takes ownership of the
+// metrics.
+func (b *Buffer) Release(batch []telegraf.Metric) {
+	b.Lock()
+	defer b.Unlock()
+
+	for _, m := range batch {
+		b.unlog(m)
+	}
+
+	b.resetBatch()
+	b.BufferSize.Set(int64(b.length()))
+	b.syncLog()
+}
+
+// OpenLog switches the buffer to the disk strategy, persisting metrics in a
+// write-ahead log stored in dir.  Metrics left in the log by a previous run
+// are replayed into the buffer.
+func (b *Buffer) OpenLog(dir string, name string, alias string, maxSize int64, log telegraf.Logger) error {
+	b.Lock()
+	defer b.Unlock()
+
+	wal := newDiskLog(filepath.Join(dir, walFilename(name, alias)), maxSize)
+	metrics, discarded, err := wal.load()
+	if err != nil {
+		return err
+	}
+	if discarded > 0 {
+		log.Warnf("Discarded %d bytes of corrupt records at the end of the disk buffer", discarded)
+	}
+
+	err = wal.create()
+	if err != nil {
+		return err
+	}
+
+	b.wal = wal
+	b.log = log
+	for _, m := range metrics {
+		b.add(m)
+	}
+
+	err = wal.commit()
+	if err != nil {
+		b.wal = nil
+		wal.close()
+		return err
+	}
+
+	b.BufferSize.Set(int64(b.length()))
+	if len(metrics) > 0 {
+		log.Infof("Replayed %d metrics from disk buffer", len(metrics))
+	}
+	return nil
+}
+
+// Close closes the write-ahead log, if any.  Metrics remaining in the buffer
+// are kept on disk and replayed on the next start.
+func (b *Buffer) Close() error {
+	b.Lock()
+	defer b.Unlock()
+
+	if b.wal == nil {
+		return nil
+	}
+	err := b.wal.close()
+	b.wal = nil
+	return err
+}
+
+// unlog removes the metric from the write-ahead log.
+func (b *Buffer) unlog(metric telegraf.Metric) {
+	if b.wal == nil {
+		return
+	}
+	if err := b.wal.remove(metric); err != nil {
+		b.log.Errorf("Error writing to disk buffer: %v", err)
+	}
+}
+
+// syncLog flushes the records written to the write-ahead log, if any, to
+// disk.
+func (b *Buffer) syncLog() {
+	if b.wal == nil {
+		return
+	}
+	if err := b.wal.sync(); err != nil {
+		b.log.Errorf("Error syncing disk buffer: %v", err)
+	}
 }
 
 // dist returns the distance between two indexes.  Because this data structure
@@ -0,0 +1,394 @@
+package models
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/plugins/parsers/influx"
+	serializer "github.com/influxdata/telegraf/plugins/serializers/influx"
+)
+
+const (
+	// Default maximum size of the metrics persisted by a disk buffer.
+	DEFAULT_BUFFER_MAX_SIZE = 128 * 1024 * 1024
+
+	walOpAdd    = byte('a')
+	walOpRemove = byte('r')
+
+	// op + seq
+	walHeaderSize = 1 + 8
+	// type + payload length
+	walAddHeaderSize = 1 + 4
+	walChecksumSize  = 4
+)
+
+var errWALCorrupt = errors.New("corrupt record")
+
+// walEntry is a metric currently persisted in the log.
+type walEntry struct {
+	seq  uint64
+	size int64
+}
+
+// diskLog is an append only write-ahead log of the metrics held by a Buffer.
+//
+// Every metric added to the buffer is appended to the log, and a remove
+// record is appended once the metric is written or dropped.  Metrics without
+// a remove record are replayed into the buffer on startup.  The log is
+// compacted once it grows to twice the size of the live metrics limit.
+type diskLog struct {
+	path    string
+	maxSize int64
+
+	file  *os.File
+	size  int64 // current size of the log file
+	live  int64 // size of the add records not yet removed
+	seq   uint64
+	dirty bool // records were written since the last sync
+
+	// A metric may be added more than once, so each metric maps to its
+	// entries ordered from oldest to newest.
+	entries    map[telegraf.Metric][]walEntry
+	serializer *serializer.Serializer
+	buf        bytes.Buffer
+}
+
+// walRecord is an add record read back from the log.
+type walRecord struct {
+	seq     uint64
+	vtype   telegraf.ValueType
+	payload []byte
+}
+
+// walFilename returns the name of the log file for an output.
+func walFilename(name, alias string) string {
+	if alias != "" {
+		name = name + "-" + alias
+	}
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '/', '\\', ':', '*', '?', '"', '<', '>', '|':
+			return '_'
+		}
+		return r
+	}, name) + ".wal"
+}
+
+func newDiskLog(path string, maxSize int64) *diskLog {
+	if maxSize <= 0 {
+		maxSize = DEFAULT_BUFFER_MAX_SIZE
+	}
+
+	s := serializer.NewSerializer()
+	s.SetFieldTypeSupport(serializer.UintSupport)
+
+	return &diskLog{
+		path:       path,
+		maxSize:    maxSize,
+		entries:    make(map[telegraf.Metric][]walEntry),
+		serializer: s,
+	}
+}
+
+// load reads the existing log, if any, and returns the metrics that have not
+// been removed ordered from oldest to newest, and the number of bytes
+// discarded after the first corrupt record.
+func (l *diskLog) load() ([]telegraf.Metric, int64, error) {
+	f, err := os.Open(l.path)
+	if os.IsNotExist(err) {
+		return nil, 0, nil
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var read int64
+	pending := make(map[uint64]walRecord)
+	r := bufio.NewReader(f)
+	for {
+		rec, op, err := readRecord(r)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			// A partially written record at the end of the log is
+			// expected after a crash; everything before it is usable.
+			break
+		}
+
+		switch op {
+		case walOpAdd:
+			pending[rec.seq] = rec
+			read += walHeaderSize + walAddHeaderSize + int64(len(rec.payload)) + walChecksumSize
+		case walOpRemove:
+			delete(pending, rec.seq)
+			read += walHeaderSize + walChecksumSize
+		}
+	}
+
+	records := make([]walRecord, 0, len(pending))
+	for _, rec := range pending {
+		records = append(records, rec)
+	}
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].seq < records[j].seq
+	})
+
+	parser := influx.NewParser(influx.NewMetricHandler())
+	metrics := make([]telegraf.Metric, 0, len(records))
+	for _, rec := range records {
+		m, err := parser.ParseLine(string(bytes.TrimSuffix(rec.payload, []byte("\n"))))
+		if err != nil {
+			return nil, 0, fmt.Errorf("could not parse record %d: %v", rec.seq, err)
+		}
+		m, err = metric.New(m.Name(), m.Tags(), m.Fields(), m.Time(), rec.vtype)
+		if err != nil {
+			return nil, 0, err
+		}
+		metrics = append(metrics, m)
+	}
+
+	return metrics, info.Size() - read, nil
+}
+
+// create starts a new, empty log file in place of the current one.  The
+// existing file is only replaced once the new file is committed.
+func (l *diskLog) create() error {
+	err := os.MkdirAll(filepath.Dir(l.path), 0755)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(l.path+".tmp", os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0640)
+	if err != nil {
+		return err
+	}
+
+	if l.file != nil {
+		l.file.Close()
+	}
+	l.file = f
+	l.size = 0
+	return nil
+}
+
+// commit atomically replaces the log with the file started by create.
+func (l *diskLog) commit() error {
+	if err := l.file.Sync(); err != nil {
+		return err
+	}
+	l.dirty = false
+	return os.Rename(l.path+".tmp", l.path)
+}
+
+// append adds a record for the metric to the log.
+func (l *diskLog) append(m telegraf.Metric) error {
+	octets, err := l.serializer.Serialize(m)
+	if err != nil {
+		return err
+	}
+
+	l.seq++
+	l.buf.Reset()
+	l.buf.WriteByte(walOpAdd)
+	binary.Write(&l.buf, binary.LittleEndian, l.seq)
+	l.buf.WriteByte(byte(m.Type()))
+	binary.Write(&l.buf, binary.LittleEndian, uint32(len(octets)))
+	l.buf.Write(octets)
+
+	n, err := l.write()
+	if err != nil {
+		return err
+	}
+
+	l.entries[m] = append(l.entries[m], walEntry{seq: l.seq, size: n})
+	l.live += n
+	return nil
+}
+
+// remove marks the metric as no longer buffered.
+func (l *diskLog) remove(m telegraf.Metric) error {
+	entries, ok := l.entries[m]
+	if !ok {
+		return nil
+	}
+	entry := entries[0]
+	if len(entries) == 1 {
+		delete(l.entries, m)
+	} else {
+		l.entries[m] = entries[1:]
+	}
+	l.live -= entry.size
+
+	l.buf.Reset()
+	l.buf.WriteByte(walOpRemove)
+	binary.Write(&l.buf, binary.LittleEndian, entry.seq)
+
+	_, err := l.write()
+	return err
+}
+
+// write appends the record in buf, with its checksum, to the log file.
+func (l *diskLog) write() (int64, error) {
+	binary.Write(&l.buf, binary.LittleEndian, crc32.ChecksumIEEE(l.buf.Bytes()))
+
+	n, err := l.file.Write(l.buf.Bytes())
+	l.size += int64(n)
+	l.dirty = true
+	return int64(n), err
+}
+
+// full returns true if the live metrics exceed the size limit.
+func (l *diskLog) full() bool {
+	return l.live > l.maxSize
+}
+
+// needsCompaction returns true when the log has grown enough that it should
+// be rewritten with only the live metrics.
+func (l *diskLog) needsCompaction() bool {
+	return l.size > 2*l.maxSize
+}
+
+// compact rewrites the log so it only contains the live metrics.
+func (l *diskLog) compact() error {
+	type liveMetric struct {
+		metric telegraf.Metric
+		seq    uint64
+	}
+
+	metrics := make([]liveMetric, 0, len(l.entries))
+	for m, entries := range l.entries {
+		for _, entry := range entries {
+			metrics = append(metrics, liveMetric{metric: m, seq: entry.seq})
+		}
+	}
+	sort.Slice(metrics, func(i, j int) bool {
+		return metrics[i].seq < metrics[j].seq
+	})
+
+	// The log is rebuilt separately so that on failure the current file
+	// and entries are kept.
+	next := &diskLog{
+		path:       l.path,
+		maxSize:    l.maxSize,
+		seq:        l.seq,
+		entries:    make(map[telegraf.Metric][]walEntry, len(metrics)),
+		serializer: l.serializer,
+	}
+	err := next.create()
+	if err != nil {
+		return err
+	}
+	for _, lm := range metrics {
+		if err = next.append(lm.metric); err != nil {
+			break
+		}
+	}
+	if err == nil {
+		err = next.commit()
+	}
+	if err != nil {
+		next.file.Close()
+		os.Remove(l.path + ".tmp")
+		return err
+	}
+
+	l.file.Close()
+	l.file = next.file
+	l.size = next.size
+	l.live = next.live
+	l.seq = next.seq
+	l.entries = next.entries
+	l.dirty = false
+	return nil
+}
+
+// sync flushes the records written since the last sync to stable storage.
+func (l *diskLog) sync() error {
+	if l.file == nil || !l.dirty {
+		return nil
+	}
+	l.dirty = false
+	return l.file.Sync()
+}
+
+func (l *diskLog) close() error {
+	if l.file == nil {
+		return nil
+	}
+	err := l.file.Sync()
+	if cerr := l.file.Close(); err == nil {
+		err = cerr
+	}
+	l.file = nil
+	return err
+}
+
+// readRecord reads the next record from the log, returning the record and the
+// operation.
+func readRecord(r *bufio.Reader) (walRecord, byte, error) {
+	var rec walRecord
+	var buf bytes.Buffer
+
+	header := make([]byte, walHeaderSize)
+	if _, err := io.ReadFull(r, header); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return rec, 0, errWALCorrupt
+		}
+		return rec, 0, err
+	}
+	buf.Write(header)
+
+	op := header[0]
+	rec.seq = binary.LittleEndian.Uint64(header[1:])
+
+	switch op {
+	case walOpAdd:
+		addHeader := make([]byte, walAddHeaderSize)
+		if _, err := io.ReadFull(r, addHeader); err != nil {
+			return rec, 0, errWALCorrupt
+		}
+		buf.Write(addHeader)
+
+		rec.vtype = telegraf.ValueType(addHeader[0])
+		length := binary.LittleEndian.Uint32(addHeader[1:])
+		if length > uint32(DEFAULT_BUFFER_MAX_SIZE) {
+			return rec, 0, errWALCorrupt
+		}
+		rec.payload = make([]byte, length)
+		if _, err := io.ReadFull(r, rec.payload); err != nil {
+			return rec, 0, errWALCorrupt
+		}
+		buf.Write(rec.payload)
+	case walOpRemove:
+	default:
+		return rec, 0, errWALCorrupt
+	}
+
+	checksum := make([]byte, walChecksumSize)
+	if _, err := io.ReadFull(r, checksum); err != nil {
+		return rec, 0, errWALCorrupt
+	}
+	if binary.LittleEndian.Uint32(checksum) != crc32.ChecksumIEEE(buf.Bytes()) {
+		return rec, 0, errWALCorrupt
+	}
+
+	return rec, op, nil
+}
@@ -1,6 +1,10 @@
 package models
 
 import (
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -726,3 +730,177 @@ func TestBuffer_RejectEmptyBatch(t *testing.T) {
 		require.NotNil(t, m)
 	}
 }
+
+func setupDisk(t *testing.T, dir string, capacity int, maxSize int64) *Buffer {
+	b := setup(NewBuffer("test", "", capacity))
+	err := b.OpenLog(dir, "test", "", maxSize, &testutil.Logger{})
+	require.NoError(t, err)
+	return b
+}
+
+func TestBuffer_DiskReplayUnsent(t *testing.T) {
+	dir, err := ioutil.TempDir("", "buffer")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	b := setupDisk(t, dir, 5, 0)
+	b.Add(MetricTime(1), MetricTime(2), MetricTime(3))
+	batch := b.Batch(1)
+	b.Accept(batch)
+	require.NoError(t, b.Close())
+
+	b = setupDisk(t, dir, 5, 0)
+	defer b.Close()
+	require.Equal(t, 2, b.Len())
+	testutil.RequireMetricsEqual(t,
+		[]telegraf.Metric{
+			MetricTime(2),
+			MetricTime(1),
+		}, b.Batch(5))
+}
+
+func TestBuffer_DiskReplayRejectedBatch(t *testing.T) {
+	dir, err := ioutil.TempDir("", "buffer")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	b := setupDisk(t, dir, 5, 0)
+	b.Add(MetricTime(1), MetricTime(2))
+	batch := b.Batch(2)
+	b.Reject(batch)
+	batch = b.Batch(1)
+	testutil.RequireMetricsEqual(t,
+		[]telegraf.Metric{
+			MetricTime(2),
+		}, batch)
+	require.NoError(t, b.Close())
+
+	b = setupDisk(t, dir, 5, 0)
+	defer b.Close()
+	require.Equal(t, 2, b.Len())
+	testutil.RequireMetricsEqual(t,
+		[]telegraf.Metric{
+			MetricTime(2),
+			MetricTime(1),
+		}, b.Batch(5))
+}
+
+func TestBuffer_DiskReplayOverCapacity(t *testing.T) {
+	dir, err := ioutil.TempDir("", "buffer")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	b := setupDisk(t, dir, 5, 0)
+	b.Add(MetricTime(1), MetricTime(2), MetricTime(3), MetricTime(4))
+	require.NoError(t, b.Close())
+
+	b = setupDisk(t, dir, 2, 0)
+	defer b.Close()
+	require.Equal(t, int64(2), b.MetricsDropped.Get())
+	testutil.RequireMetricsEqual(t,
+		[]telegraf.Metric{
+			MetricTime(4),
+			MetricTime(3),
+		}, b.Batch(5))
+}
+
+func TestBuffer_DiskMaxSizeDropsOldest(t *testing.T) {
+	dir, err := ioutil.TempDir("", "buffer")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	b := setupDisk(t, dir, 100, 100)
+	defer b.Close()
+	for i := int64(0); i < 20; i++ {
+		b.Add(MetricTime(i))
+	}
+
+	require.True(t, b.Len() < 20)
+	require.Equal(t, int64(20-b.Len()), b.MetricsDropped.Get())
+	batch := b.Batch(1)
+	testutil.RequireMetricsEqual(t,
+		[]telegraf.Metric{
+			MetricTime(19),
+		}, batch)
+}
+
+func TestBuffer_DiskCompaction(t *testing.T) {
+	dir, err := ioutil.TempDir("", "buffer")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	b := setupDisk(t, dir, 100, 200)
+	for i := int64(0); i < 50; i++ {
+		b.Add(MetricTime(i))
+		b.Accept(b.Batch(1))
+	}
+	b.Add(MetricTime(100))
+	require.NoError(t, b.Close())
+
+	info, err := os.Stat(dir + "/" + walFilename("test", ""))
+	require.NoError(t, err)
+	require.True(t, info.Size() <= 400)
+
+	b = setupDisk(t, dir, 100, 200)
+	defer b.Close()
+	testutil.RequireMetricsEqual(t,
+		[]telegraf.Metric{
+			MetricTime(100),
+		}, b.Batch(5))
+}
+
+func TestBuffer_DiskDiscardsCorruptTail(t *testing.T) {
+	dir, err := ioutil.TempDir("", "buffer")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	b := setupDisk(t, dir, 5, 0)
+	b.Add(MetricTime(1), MetricTime(2))
+	require.NoError(t, b.Close())
+
+	path := filepath.Join(dir, walFilename("test", ""))
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0640)
+	require.NoError(t, err)
+	_, err = f.Write([]byte("garbage"))
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	metrics, discarded, err := newDiskLog(path, 0).load()
+	require.NoError(t, err)
+	require.Len(t, metrics, 2)
+	require.Equal(t, int64(len("garbage")), discarded)
+}
+
+func TestBuffer_DiskCompactionFailureKeepsLog(t *testing.T) {
+	dir, err := ioutil.TempDir("", "buffer")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, walFilename("test", ""))
+	l := newDiskLog(path, 0)
+	require.NoError(t, l.create())
+	require.NoError(t, l.commit())
+	require.NoError(t, l.append(MetricTime(1)))
+
+	// A metric which cannot be serialized makes the rewrite fail.
+	invalid, err := metric.New("cpu", map[string]string{},
+		map[string]interface{}{"value": math.NaN()}, time.Unix(0, 0))
+	require.NoError(t, err)
+	l.entries[invalid] = []walEntry{{seq: 0}}
+	require.Error(t, l.compact())
+	delete(l.entries, invalid)
+
+	// The log is still written to the committed file.
+	require.NoError(t, l.append(MetricTime(2)))
+	require.NoError(t, l.close())
+	_, err = os.Stat(path + ".tmp")
+	require.True(t, os.IsNotExist(err))
+
+	metrics, _, err := newDiskLog(path, 0).load()
+	require.NoError(t, err)
+	testutil.RequireMetricsEqual(t,
+		[]telegraf.Metric{
+			MetricTime(1),
+			MetricTime(2),
+		}, metrics)
+}
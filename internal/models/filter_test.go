@@ -482,6 +482,49 @@ func TestFilter_FilterTagsPassAndDrop(t *testing.T) {
 
 }
 
+func TestFilter_MetricPass(t *testing.T) {
+	f := Filter{
+		MetricPass: `fields.usage_idle < 10 && tags.host =~ "^db"`,
+	}
+	require.NoError(t, f.Compile())
+	require.True(t, f.IsActive())
+
+	passes := []telegraf.Metric{
+		testutil.MustMetric("cpu",
+			map[string]string{"host": "db01"},
+			map[string]interface{}{"usage_idle": 5.0},
+			time.Unix(0, 0)),
+	}
+	drops := []telegraf.Metric{
+		testutil.MustMetric("cpu",
+			map[string]string{"host": "db01"},
+			map[string]interface{}{"usage_idle": 50.0},
+			time.Unix(0, 0)),
+		testutil.MustMetric("cpu",
+			map[string]string{"host": "web01"},
+			map[string]interface{}{"usage_idle": 5.0},
+			time.Unix(0, 0)),
+		testutil.MustMetric("cpu",
+			map[string]string{},
+			map[string]interface{}{"usage_idle": 5.0},
+			time.Unix(0, 0)),
+	}
+
+	for _, m := range passes {
+		require.True(t, f.Select(m), "should pass: %v", m)
+	}
+	for _, m := range drops {
+		require.False(t, f.Select(m), "should drop: %v", m)
+	}
+}
+
+func TestFilter_MetricPassInvalid(t *testing.T) {
+	f := Filter{
+		MetricPass: `fields.usage_idle <`,
+	}
+	require.Error(t, f.Compile())
+}
+
 func BenchmarkFilter(b *testing.B) {
 	tests := []struct {
 		name   string
@@ -1,59 +1,115 @@
 package models
 
 import (
+	"fmt"
 	"log"
 	"reflect"
 
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/logger"
 	"github.com/influxdata/telegraf/selfstat"
+	"github.com/influxdata/wlog"
 )
 
 // Logger defines a logging structure for plugins.
 type Logger struct {
 	Errs selfstat.Stat
 	Name string // Name is the plugin name, will be printed in the `[]`.
+
+	// level overrides the global log level when set.
+	level    wlog.Level
+	hasLevel bool
+}
+
+var levelPrefixes = map[wlog.Level]string{
+	wlog.DEBUG: "D! ",
+	wlog.INFO:  "I! ",
+	wlog.WARN:  "W! ",
+	wlog.ERROR: "E! ",
+}
+
+// SetLevel overrides the global log level for the messages of the plugin,
+// the global level is used if level is empty.
+func (l *Logger) SetLevel(level string) error {
+	if level == "" {
+		l.hasLevel = false
+		return nil
+	}
+
+	lvl, err := logger.ParseLevel(level)
+	if err != nil {
+		return err
+	}
+	l.level = lvl
+	l.hasLevel = true
+	return nil
 }
 
 // Errorf logs an error message, patterned after log.Printf.
 func (l *Logger) Errorf(format string, args ...interface{}) {
 	l.Errs.Incr(1)
-	log.Printf("E! ["+l.Name+"] "+format, args...)
+	l.printf(wlog.ERROR, format, args...)
 }
 
 // Error logs an error message, patterned after log.Print.
 func (l *Logger) Error(args ...interface{}) {
 	l.Errs.Incr(1)
-	log.Print(append([]interface{}{"E! [" + l.Name + "] "}, args...)...)
+	l.print(wlog.ERROR, args...)
 }
 
 // Debugf logs a debug message, patterned after log.Printf.
 func (l *Logger) Debugf(format string, args ...interface{}) {
-	log.Printf("D! ["+l.Name+"] "+format, args...)
+	l.printf(wlog.DEBUG, format, args...)
 }
 
 // Debug logs a debug message, patterned after log.Print.
 func (l *Logger) Debug(args ...interface{}) {
-	log.Print(append([]interface{}{"D! [" + l.Name + "] "}, args...)...)
+	l.print(wlog.DEBUG, args...)
 }
 
 // Warnf logs a warning message, patterned after log.Printf.
 func (l *Logger) Warnf(format string, args ...interface{}) {
-	log.Printf("W! ["+l.Name+"] "+format, args...)
+	l.printf(wlog.WARN, format, args...)
 }
 
 // Warn logs a warning message, patterned after log.Print.
 func (l *Logger) Warn(args ...interface{}) {
-	log.Print(append([]interface{}{"W! [" + l.Name + "] "}, args...)...)
+	l.print(wlog.WARN, args...)
 }
 
 // Infof logs an information message, patterned after log.Printf.
 func (l *Logger) Infof(format string, args ...interface{}) {
-	log.Printf("I! ["+l.Name+"] "+format, args...)
+	l.printf(wlog.INFO, format, args...)
 }
 
 // Info logs an information message, patterned after log.Print.
 func (l *Logger) Info(args ...interface{}) {
-	log.Print(append([]interface{}{"I! [" + l.Name + "] "}, args...)...)
+	l.print(wlog.INFO, args...)
+}
+
+// printf logs the message if it is at or above the level of the plugin.
+// Without a level of its own the message is filtered by the global level.
+func (l *Logger) printf(level wlog.Level, format string, args ...interface{}) {
+	prefix := levelPrefixes[level] + "[" + l.Name + "] "
+	if !l.hasLevel {
+		log.Printf(prefix+format, args...)
+		return
+	}
+	if level >= l.level {
+		logger.Print(fmt.Sprintf(prefix+format, args...))
+	}
+}
+
+// print logs the message like printf, patterned after log.Print.
+func (l *Logger) print(level wlog.Level, args ...interface{}) {
+	args = append([]interface{}{levelPrefixes[level] + "[" + l.Name + "] "}, args...)
+	if !l.hasLevel {
+		log.Print(args...)
+		return
+	}
+	if level >= l.level {
+		logger.Print(fmt.Sprint(args...))
+	}
 }
 
 // logName returns the log-friendly name/type.
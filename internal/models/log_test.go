@@ -1,8 +1,12 @@
 package models
 
 import (
+	"bytes"
+	"io/ioutil"
+	"os"
 	"testing"
 
+	"github.com/influxdata/telegraf/logger"
 	"github.com/influxdata/telegraf/selfstat"
 	"github.com/stretchr/testify/require"
 )
@@ -68,3 +72,41 @@ func TestLogging(t *testing.T) {
 	log.Error("something happened")
 	require.Equal(t, int64(2), log.Errs.Get())
 }
+
+func TestLoggerLevel(t *testing.T) {
+	tmpfile, err := ioutil.TempFile("", "")
+	require.NoError(t, err)
+	defer func() { os.Remove(tmpfile.Name()) }()
+
+	logger.SetupLogging(logger.LogConfig{
+		Logfile:             tmpfile.Name(),
+		LogTarget:           logger.LogTargetFile,
+		RotationMaxArchives: -1,
+	})
+	defer logger.SetupLogging(logger.LogConfig{})
+
+	errs := selfstat.Register("gather", "errors", map[string]string{"input": "test"})
+
+	// Instances with the same name keep their own level.
+	debug := Logger{Name: "inputs.test", Errs: errs}
+	require.NoError(t, debug.SetLevel("debug"))
+	quiet := Logger{Name: "inputs.test", Errs: errs}
+	require.NoError(t, quiet.SetLevel("error"))
+	global := Logger{Name: "inputs.test", Errs: errs}
+	require.Error(t, global.SetLevel("verbose"))
+
+	debug.Debugf("debug %d", 1)
+	quiet.Warn("ignored")
+	quiet.Errorf("error %d", 2)
+	global.Debug("ignored")
+	global.Info("info")
+
+	f, err := ioutil.ReadFile(tmpfile.Name())
+	require.NoError(t, err)
+
+	lines := bytes.Split(bytes.TrimSpace(f), []byte("\n"))
+	require.Len(t, lines, 3)
+	require.Equal(t, []byte("Z D! [inputs.test] debug 1"), lines[0][19:])
+	require.Equal(t, []byte("Z E! [inputs.test] error 2"), lines[1][19:])
+	require.Equal(t, []byte("Z I! [inputs.test] info"), lines[2][19:])
+}
@@ -17,6 +17,9 @@ type RunningAggregator struct {
 	periodEnd   time.Time
 	log         telegraf.Logger
 
+	// Deliveries of the tracking metrics aggregated since the last push.
+	deliveries metric.DeliveryGroup
+
 	MetricsPushed   selfstat.Stat
 	MetricsFiltered selfstat.Stat
 	MetricsDropped  selfstat.Stat
@@ -132,10 +135,10 @@ func (r *RunningAggregator) Add(m telegraf.Metric) bool {
 		return false
 	}
 
-	// Make a copy of the metric but don't retain tracking.  We do not fail a
-	// delivery due to the aggregation not being sent because we can't create
-	// aggregations of historical data.  Additionally, waiting for the
-	// aggregation to be pushed would introduce a hefty latency to delivery.
+	// Make a copy of the metric but don't retain tracking, the aggregator
+	// may keep it for the whole period.  The delivery of the original is
+	// instead added to the deliveries of the next push.
+	original := m
 	m = metric.FromMetric(m)
 
 	r.Config.Filter.Modify(m)
@@ -155,6 +158,7 @@ func (r *RunningAggregator) Add(m telegraf.Metric) bool {
 	}
 
 	r.Aggregator.Add(m)
+	r.deliveries.Add(original)
 	return r.Config.DropOriginal
 }
 
@@ -170,9 +174,63 @@ func (r *RunningAggregator) Push(acc telegraf.Accumulator) {
 	r.Aggregator.Reset()
 }
 
+// push sends the aggregated metrics to acc.  If tracking metrics were
+// aggregated, the pushed metrics are tracked as a group and the tracking
+// metrics are delivered once the group is.
 func (r *RunningAggregator) push(acc telegraf.Accumulator) {
 	start := time.Now()
-	r.Aggregator.Push(acc)
+	if r.deliveries.Len() == 0 {
+		r.Aggregator.Push(acc)
+	} else {
+		pushed := &pushAccumulator{Accumulator: acc}
+		r.Aggregator.Push(pushed)
+		for _, m := range r.deliveries.Track(pushed.metrics) {
+			acc.AddMetric(m)
+		}
+	}
 	elapsed := time.Since(start)
 	r.PushTime.Incr(elapsed.Nanoseconds())
 }
+
+// pushAccumulator collects the metrics pushed by an aggregator.
+type pushAccumulator struct {
+	telegraf.Accumulator
+	metrics []telegraf.Metric
+}
+
+func (a *pushAccumulator) AddFields(measurement string, fields map[string]interface{}, tags map[string]string, t ...time.Time) {
+	a.add(measurement, fields, tags, telegraf.Untyped, t...)
+}
+
+func (a *pushAccumulator) AddGauge(measurement string, fields map[string]interface{}, tags map[string]string, t ...time.Time) {
+	a.add(measurement, fields, tags, telegraf.Gauge, t...)
+}
+
+func (a *pushAccumulator) AddCounter(measurement string, fields map[string]interface{}, tags map[string]string, t ...time.Time) {
+	a.add(measurement, fields, tags, telegraf.Counter, t...)
+}
+
+func (a *pushAccumulator) AddSummary(measurement string, fields map[string]interface{}, tags map[string]string, t ...time.Time) {
+	a.add(measurement, fields, tags, telegraf.Summary, t...)
+}
+
+func (a *pushAccumulator) AddHistogram(measurement string, fields map[string]interface{}, tags map[string]string, t ...time.Time) {
+	a.add(measurement, fields, tags, telegraf.Histogram, t...)
+}
+
+func (a *pushAccumulator) AddMetric(m telegraf.Metric) {
+	a.metrics = append(a.metrics, m)
+}
+
+func (a *pushAccumulator) add(measurement string, fields map[string]interface{}, tags map[string]string, tp telegraf.ValueType, t ...time.Time) {
+	tm := time.Now()
+	if len(t) > 0 {
+		tm = t[0]
+	}
+
+	m, err := metric.New(measurement, tags, fields, tm, tp)
+	if err != nil {
+		return
+	}
+	a.metrics = append(a.metrics, m)
+}
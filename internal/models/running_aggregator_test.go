@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
 	"github.com/influxdata/telegraf/testutil"
 	"github.com/stretchr/testify/require"
 )
@@ -239,6 +240,57 @@ func TestAddDoesNotModifyMetric(t *testing.T) {
 	testutil.RequireMetricEqual(t, expected, m)
 }
 
+func TestPushTracksAggregatedMetrics(t *testing.T) {
+	for _, delivered := range []bool{true, false} {
+		ra := NewRunningAggregator(&TestAggregator{}, &AggregatorConfig{
+			Name:         "TestRunningAggregator",
+			DropOriginal: true,
+			Period:       time.Minute,
+		})
+		require.NoError(t, ra.Config.Filter.Compile())
+
+		now := time.Now()
+		ra.UpdateWindow(now, now.Add(ra.Config.Period))
+
+		var info telegraf.DeliveryInfo
+		m, _ := metric.WithTracking(testutil.MustMetric("RITest",
+			map[string]string{},
+			map[string]interface{}{
+				"value": int64(101),
+			},
+			now,
+			telegraf.Untyped), func(di telegraf.DeliveryInfo) { info = di })
+
+		require.True(t, ra.Add(m))
+		m.Drop()
+		require.Nil(t, info)
+
+		acc := &metricAccumulator{}
+		ra.Push(acc)
+		require.Len(t, acc.metrics, 1)
+		require.Nil(t, info)
+
+		if delivered {
+			acc.metrics[0].Accept()
+		} else {
+			acc.metrics[0].Reject()
+		}
+		require.NotNil(t, info)
+		require.Equal(t, delivered, info.Delivered())
+	}
+}
+
+// metricAccumulator keeps the metrics added with AddMetric, so that they can
+// be accepted or rejected.
+type metricAccumulator struct {
+	testutil.Accumulator
+	metrics []telegraf.Metric
+}
+
+func (a *metricAccumulator) AddMetric(m telegraf.Metric) {
+	a.metrics = append(a.metrics, m)
+}
+
 type TestAggregator struct {
 	sum int64
 }
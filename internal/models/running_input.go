@@ -1,14 +1,44 @@
 package models
 
 import (
+	"context"
+	"sync"
 	"time"
 
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal/cron"
+	"github.com/influxdata/telegraf/internal/limiter"
 	"github.com/influxdata/telegraf/selfstat"
 )
 
 var GlobalMetricsGathered = selfstat.Register("agent", "metrics_gathered", map[string]string{})
 
+// Overlap policies decide what happens when an input is due to gather while
+// its previous gather is still running.
+const (
+	// OverlapQueue runs the next gather once the previous one returns.
+	OverlapQueue = "queue"
+	// OverlapSkip skips the gather.
+	OverlapSkip = "skip"
+	// OverlapCancel cancels the previous gather and runs the next gather
+	// once it returns.
+	OverlapCancel = "cancel"
+)
+
+// Metric limit policies decide which metrics are dropped when an input
+// exceeds its max_metrics_per_gather or max_metrics_per_second.
+const (
+	// LimitDrop drops the metrics once the limit is reached.
+	LimitDrop = "drop"
+	// LimitSample also drops metrics at random before the limit is reached
+	// when the previous period exceeded it.
+	LimitSample = "sample"
+)
+
+// throttleWarnInterval is the minimum time between warnings about dropped
+// metrics.
+const throttleWarnInterval = time.Minute
+
 type RunningInput struct {
 	Input  telegraf.Input
 	Config *InputConfig
@@ -16,8 +46,22 @@ type RunningInput struct {
 	log         telegraf.Logger
 	defaultTags map[string]string
 
-	MetricsGathered selfstat.Stat
-	GatherTime      selfstat.Stat
+	gatherQuota *limiter.Quota
+	secondQuota *limiter.Quota
+
+	throttleMu   sync.Mutex
+	throttled    int64
+	throttleWarn time.Time
+
+	MetricsGathered  selfstat.Stat
+	MetricsThrottled selfstat.Stat
+	GatherTime       selfstat.Stat
+	GatherTimeouts   selfstat.Stat
+	GatherSkipped    selfstat.Stat
+
+	// GatherRequest receives requests for an immediate gather, the result
+	// of the gather is sent on the request channel.
+	GatherRequest chan chan error
 }
 
 func NewRunningInput(input telegraf.Input, config *InputConfig) *RunningInput {
@@ -30,22 +74,56 @@ func NewRunningInput(input telegraf.Input, config *InputConfig) *RunningInput {
 		Name: logName("inputs", config.Name, config.Alias),
 		Errs: selfstat.Register("gather", "errors", tags),
 	}
+	if err := logger.SetLevel(config.LogLevel); err != nil {
+		logger.Error(err)
+	}
 	setLogIfExist(input, logger)
 
+	sample := config.MaxMetricsPolicy == LimitSample
+	var gatherQuota, secondQuota *limiter.Quota
+	// Service inputs add metrics outside of Gather, the quota would only be
+	// reset by their periodic Gather so they are exempt.
+	_, isService := input.(telegraf.ServiceInput)
+	if config.MaxMetricsPerGather > 0 && !isService {
+		gatherQuota = limiter.NewQuota(config.MaxMetricsPerGather, 0, sample)
+	}
+	if config.MaxMetricsPerSecond > 0 {
+		secondQuota = limiter.NewQuota(config.MaxMetricsPerSecond, time.Second, sample)
+	}
+
 	return &RunningInput{
-		Input:  input,
-		Config: config,
+		Input:       input,
+		Config:      config,
+		gatherQuota: gatherQuota,
+		secondQuota: secondQuota,
 		MetricsGathered: selfstat.Register(
 			"gather",
 			"metrics_gathered",
 			tags,
 		),
-		GatherTime: selfstat.RegisterTiming(
+		MetricsThrottled: selfstat.Register(
+			"gather",
+			"metrics_throttled",
+			tags,
+		),
+		GatherTime: selfstat.RegisterHistogram(
 			"gather",
 			"gather_time_ns",
 			tags,
+			selfstat.DefaultTimingBuckets,
 		),
-		log: logger,
+		GatherTimeouts: selfstat.Register(
+			"gather",
+			"gather_timeouts",
+			tags,
+		),
+		GatherSkipped: selfstat.Register(
+			"gather",
+			"gathers_skipped",
+			tags,
+		),
+		GatherRequest: make(chan chan error),
+		log:           logger,
 	}
 }
 
@@ -54,6 +132,25 @@ type InputConfig struct {
 	Name     string
 	Alias    string
 	Interval time.Duration
+	LogLevel string
+
+	// Schedule replaces the interval when set, gathering at the times
+	// matching a cron expression.
+	Schedule *cron.Schedule
+
+	// GatherTimeout is the time after which a gather is cancelled, zero
+	// disables the timeout.
+	GatherTimeout time.Duration
+	// OverlapPolicy is one of OverlapQueue, OverlapSkip or OverlapCancel,
+	// defaulting to OverlapQueue.
+	OverlapPolicy string
+
+	// MaxMetricsPerGather and MaxMetricsPerSecond limit the number of
+	// metrics emitted by the input, zero is unlimited.
+	MaxMetricsPerGather int
+	MaxMetricsPerSecond int
+	// MaxMetricsPolicy is LimitDrop or LimitSample, defaulting to LimitDrop.
+	MaxMetricsPolicy string
 
 	NameOverride      string
 	MeasurementPrefix string
@@ -66,6 +163,37 @@ func (r *RunningInput) metricFiltered(metric telegraf.Metric) {
 	metric.Drop()
 }
 
+// allow returns true if the metric is within the max_metrics_per_gather and
+// max_metrics_per_second limits.
+func (r *RunningInput) allow() bool {
+	now := time.Now()
+	// Both quotas record the metric, even if the first one rejects it.
+	allowed := true
+	if r.gatherQuota != nil && !r.gatherQuota.Allow(now) {
+		allowed = false
+	}
+	if r.secondQuota != nil && !r.secondQuota.Allow(now) {
+		allowed = false
+	}
+	return allowed
+}
+
+// metricThrottled drops a metric exceeding the limits, warning about the
+// dropped metrics at most every throttleWarnInterval.
+func (r *RunningInput) metricThrottled(metric telegraf.Metric) {
+	r.MetricsThrottled.Incr(1)
+	metric.Drop()
+
+	r.throttleMu.Lock()
+	defer r.throttleMu.Unlock()
+	r.throttled++
+	if now := time.Now(); now.Sub(r.throttleWarn) >= throttleWarnInterval {
+		r.log.Warnf("Dropped %d metrics exceeding the metric limits", r.throttled)
+		r.throttled = 0
+		r.throttleWarn = now
+	}
+}
+
 func (r *RunningInput) LogName() string {
 	return logName("inputs", r.Config.Name, r.Config.Alias)
 }
@@ -100,14 +228,30 @@ func (r *RunningInput) MakeMetric(metric telegraf.Metric) telegraf.Metric {
 		return nil
 	}
 
+	if !r.allow() {
+		r.metricThrottled(metric)
+		return nil
+	}
+
 	r.MetricsGathered.Incr(1)
 	GlobalMetricsGathered.Incr(1)
 	return m
 }
 
-func (r *RunningInput) Gather(acc telegraf.Accumulator) error {
+// Gather calls GatherContext if the input is a telegraf.ContextGatherer and
+// Gather otherwise.  Each call starts a new max_metrics_per_gather period.
+func (r *RunningInput) Gather(ctx context.Context, acc telegraf.Accumulator) error {
+	if r.gatherQuota != nil {
+		r.gatherQuota.Reset()
+	}
+
 	start := time.Now()
-	err := r.Input.Gather(acc)
+	var err error
+	if input, ok := r.Input.(telegraf.ContextGatherer); ok {
+		err = input.GatherContext(ctx, acc)
+	} else {
+		err = r.Input.Gather(acc)
+	}
 	elapsed := time.Since(start)
 	r.GatherTime.Incr(elapsed.Nanoseconds())
 	return err
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"context"
 	"testing"
 	"time"
 
@@ -256,8 +257,62 @@ func TestMakeMetricNameSuffix(t *testing.T) {
 	require.Equal(t, expected, m)
 }
 
+func TestMakeMetricMaxMetricsPerGather(t *testing.T) {
+	ri := NewRunningInput(&testInput{}, &InputConfig{
+		Name:                "TestMaxMetricsPerGather",
+		MaxMetricsPerGather: 2,
+	})
+	throttled := ri.MetricsThrottled.Get()
+
+	made := func() int {
+		count := 0
+		for i := 0; i < 5; i++ {
+			m, err := metric.New("RITest",
+				map[string]string{},
+				map[string]interface{}{"value": int64(i)},
+				time.Now())
+			require.NoError(t, err)
+			if ri.MakeMetric(m) != nil {
+				count++
+			}
+		}
+		return count
+	}
+
+	require.NoError(t, ri.Gather(context.Background(), &testutil.Accumulator{}))
+	require.Equal(t, 2, made())
+	require.Equal(t, throttled+3, ri.MetricsThrottled.Get())
+
+	// Each gather starts with a new quota.
+	require.NoError(t, ri.Gather(context.Background(), &testutil.Accumulator{}))
+	require.Equal(t, 2, made())
+}
+
+func TestMakeMetricMaxMetricsPerGatherServiceInput(t *testing.T) {
+	ri := NewRunningInput(&testServiceInput{}, &InputConfig{
+		Name:                "TestMaxMetricsPerGatherService",
+		MaxMetricsPerGather: 2,
+	})
+
+	for i := 0; i < 5; i++ {
+		m, err := metric.New("RITest",
+			map[string]string{},
+			map[string]interface{}{"value": int64(i)},
+			time.Now())
+		require.NoError(t, err)
+		require.NotNil(t, ri.MakeMetric(m))
+	}
+}
+
 type testInput struct{}
 
 func (t *testInput) Description() string                   { return "" }
 func (t *testInput) SampleConfig() string                  { return "" }
 func (t *testInput) Gather(acc telegraf.Accumulator) error { return nil }
+
+type testServiceInput struct {
+	testInput
+}
+
+func (t *testServiceInput) Start(acc telegraf.Accumulator) error { return nil }
+func (t *testServiceInput) Stop()                                {}
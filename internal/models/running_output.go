@@ -1,6 +1,9 @@
 package models
 
 import (
+	"context"
+	"fmt"
+	"math"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -15,18 +18,43 @@ const (
 
 	// Default number of metrics kept. It should be a multiple of batch size.
 	DEFAULT_METRIC_BUFFER_LIMIT = 10000
+
+	// Buffer strategies; the memory buffer is used by default.
+	BUFFER_STRATEGY_MEMORY = "memory"
+	BUFFER_STRATEGY_DISK   = "disk"
+
+	// Default growth factor of the delay between write retries.
+	DEFAULT_RETRY_MULTIPLIER = 2.0
 )
 
 // OutputConfig containing name and filter
 type OutputConfig struct {
-	Name   string
-	Alias  string
-	Filter Filter
+	Name     string
+	Alias    string
+	Filter   Filter
+	LogLevel string
 
 	FlushInterval     time.Duration
 	FlushJitter       *time.Duration
 	MetricBufferLimit int
 	MetricBatchSize   int
+
+	BufferStrategy  string
+	BufferDirectory string
+	BufferMaxSize   int64
+
+	// Delay before retrying a failed write, multiplied by RetryMultiplier
+	// after each consecutive failure up to RetryMaxInterval.  When zero,
+	// writes are retried on the next flush.
+	RetryInitialInterval time.Duration
+	RetryMaxInterval     time.Duration
+	RetryMultiplier      float64
+	// Number of failed writes after which a batch is rejected, zero retries
+	// forever.
+	RetryMaxAttempts int
+
+	// Alias of the output that receives rejected metrics.
+	DeadLetterOutput string
 }
 
 // RunningOutput contains the output configuration
@@ -41,14 +69,26 @@ type RunningOutput struct {
 	MetricBatchSize   int
 
 	MetricsFiltered selfstat.Stat
+	MetricsRejected selfstat.Stat
 	WriteTime       selfstat.Stat
 
 	BatchReady chan time.Time
+	// FlushRequest receives requests for an immediate write, the result of
+	// the write is sent on the request channel.
+	FlushRequest chan chan error
 
 	buffer *Buffer
 	log    telegraf.Logger
 
 	aggMutex sync.Mutex
+
+	// Retry state, only used by the goroutine writing to the output.
+	failures int
+	retryAt  time.Time
+
+	deadLetterMu sync.Mutex
+	deadLetter   *RunningOutput
+	isDeadLetter int32
 }
 
 func NewRunningOutput(
@@ -67,6 +107,9 @@ func NewRunningOutput(
 		Name: logName("outputs", config.Name, config.Alias),
 		Errs: selfstat.Register("write", "errors", tags),
 	}
+	if err := logger.SetLevel(config.LogLevel); err != nil {
+		logger.Error(err)
+	}
 	setLogIfExist(output, logger)
 
 	if config.MetricBufferLimit > 0 {
@@ -85,6 +128,7 @@ func NewRunningOutput(
 	ro := &RunningOutput{
 		buffer:            NewBuffer(config.Name, config.Alias, bufferLimit),
 		BatchReady:        make(chan time.Time, 1),
+		FlushRequest:      make(chan chan error),
 		Output:            output,
 		Config:            config,
 		MetricBufferLimit: bufferLimit,
@@ -94,10 +138,16 @@ func NewRunningOutput(
 			"metrics_filtered",
 			tags,
 		),
-		WriteTime: selfstat.RegisterTiming(
+		MetricsRejected: selfstat.Register(
+			"write",
+			"metrics_rejected",
+			tags,
+		),
+		WriteTime: selfstat.RegisterHistogram(
 			"write",
 			"write_time_ns",
 			tags,
+			selfstat.DefaultTimingBuckets,
 		),
 		log: logger,
 	}
@@ -122,9 +172,26 @@ func (r *RunningOutput) Init() error {
 		}
 
 	}
+
+	if r.Config.BufferStrategy == BUFFER_STRATEGY_DISK {
+		err := r.buffer.OpenLog(r.Config.BufferDirectory, r.Config.Name,
+			r.Config.Alias, r.Config.BufferMaxSize, r.log)
+		if err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
+// Connect calls ConnectContext if the output is a telegraf.ContextConnector
+// and Connect otherwise.
+func (r *RunningOutput) Connect(ctx context.Context) error {
+	if output, ok := r.Output.(telegraf.ContextConnector); ok {
+		return output.ConnectContext(ctx)
+	}
+	return r.Output.Connect()
+}
+
 // AddMetric adds a metric to the output.
 //
 // Takes ownership of metric
@@ -162,7 +229,7 @@ func (ro *RunningOutput) AddMetric(metric telegraf.Metric) {
 
 // Write writes all metrics to the output, stopping when all have been sent on
 // or error.
-func (ro *RunningOutput) Write() error {
+func (ro *RunningOutput) Write(ctx context.Context) error {
 	if output, ok := ro.Output.(telegraf.AggregatingOutput); ok {
 		ro.aggMutex.Lock()
 		metrics := output.Push()
@@ -173,6 +240,10 @@ func (ro *RunningOutput) Write() error {
 
 	atomic.StoreInt64(&ro.newMetricsCount, 0)
 
+	if ro.backingOff() {
+		return nil
+	}
+
 	// Only process the metrics in the buffer now.  Metrics added while we are
 	// writing will be sent on the next call.
 	nBuffer := ro.buffer.Len()
@@ -183,30 +254,194 @@ func (ro *RunningOutput) Write() error {
 			break
 		}
 
-		err := ro.write(batch)
+		err := ro.writeBatch(ctx, batch)
 		if err != nil {
-			ro.buffer.Reject(batch)
 			return err
 		}
-		ro.buffer.Accept(batch)
 	}
 	return nil
 }
 
+// FinalWrite writes all metrics to the output like Write, but also while
+// backing off after a failed write as it is the last attempt before the
+// output is stopped.
+func (ro *RunningOutput) FinalWrite(ctx context.Context) error {
+	ro.retryAt = time.Time{}
+	return ro.Write(ctx)
+}
+
 // WriteBatch writes a single batch of metrics to the output.
-func (ro *RunningOutput) WriteBatch() error {
+func (ro *RunningOutput) WriteBatch(ctx context.Context) error {
+	if ro.backingOff() {
+		return nil
+	}
+
 	batch := ro.buffer.Batch(ro.MetricBatchSize)
 	if len(batch) == 0 {
 		return nil
 	}
 
-	err := ro.write(batch)
-	if err != nil {
+	return ro.writeBatch(ctx, batch)
+}
+
+// writeBatch writes a batch acquired from the buffer.  Batches that fail are
+// returned to the buffer to be retried, unless the error is permanent or the
+// retries are exhausted, in which case the batch is rejected.  Writes
+// interrupted by the context are not counted as failures.
+func (ro *RunningOutput) writeBatch(ctx context.Context, batch []telegraf.Metric) error {
+	err := ro.write(ctx, batch)
+	if err == nil {
+		ro.failures = 0
+		ro.retryAt = time.Time{}
+		ro.buffer.Accept(batch)
+		return nil
+	}
+
+	if ctx.Err() != nil {
 		ro.buffer.Reject(batch)
-		return err
+		return ctx.Err()
+	}
+
+	ro.failures++
+	if telegraf.IsPermanentError(err) {
+		ro.failures = 0
+		ro.reject(batch, err)
+		return nil
+	}
+	if ro.Config.RetryMaxAttempts > 0 && ro.failures >= ro.Config.RetryMaxAttempts {
+		ro.failures = 0
+		ro.reject(batch, fmt.Errorf("giving up after %d attempts: %v",
+			ro.Config.RetryMaxAttempts, err))
+		return nil
 	}
-	ro.buffer.Accept(batch)
 
+	ro.buffer.Reject(batch)
+	if delay := ro.retryDelay(); delay > 0 {
+		ro.retryAt = time.Now().Add(delay)
+		return fmt.Errorf("%v; retrying in %s", err, delay)
+	}
+	return err
+}
+
+// reject removes the batch from the buffer, sending it to the dead letter
+// output if there is one.
+func (ro *RunningOutput) reject(batch []telegraf.Metric, err error) {
+	ro.MetricsRejected.Incr(int64(len(batch)))
+
+	deadLetter := ro.DeadLetter()
+	if deadLetter == nil {
+		ro.log.Errorf("Dropping %d rejected metrics: %v", len(batch), err)
+		ro.buffer.Drop(batch)
+		return
+	}
+
+	ro.log.Errorf("Sending %d rejected metrics to %s: %v",
+		len(batch), deadLetter.LogName(), err)
+	ro.buffer.Release(batch)
+	for _, m := range batch {
+		deadLetter.AddMetric(m)
+	}
+}
+
+// retryDelay returns the delay before the next write after the current
+// number of consecutive failures.
+func (ro *RunningOutput) retryDelay() time.Duration {
+	initial := ro.Config.RetryInitialInterval
+	if initial <= 0 {
+		return 0
+	}
+
+	multiplier := ro.Config.RetryMultiplier
+	if multiplier < 1 {
+		multiplier = DEFAULT_RETRY_MULTIPLIER
+	}
+
+	delay := float64(initial) * math.Pow(multiplier, float64(ro.failures-1))
+	if max := ro.Config.RetryMaxInterval; max > 0 && delay > float64(max) {
+		return max
+	}
+	if delay > math.MaxInt64 {
+		return time.Duration(math.MaxInt64)
+	}
+	return time.Duration(delay)
+}
+
+// backingOff returns true if writes are delayed after a failed write.
+func (ro *RunningOutput) backingOff() bool {
+	if ro.retryAt.IsZero() || !time.Now().Before(ro.retryAt) {
+		return false
+	}
+	ro.log.Debugf("Retrying failed write in %s", time.Until(ro.retryAt).Round(time.Millisecond))
+	return true
+}
+
+// DeadLetter returns the output that receives the metrics rejected by this
+// output, or nil.
+func (ro *RunningOutput) DeadLetter() *RunningOutput {
+	ro.deadLetterMu.Lock()
+	defer ro.deadLetterMu.Unlock()
+	return ro.deadLetter
+}
+
+// IsDeadLetter returns true if the output only receives the metrics rejected
+// by other outputs.
+func (ro *RunningOutput) IsDeadLetter() bool {
+	return atomic.LoadInt32(&ro.isDeadLetter) == 1
+}
+
+// LinkDeadLetterOutputs connects each output to the output named by its
+// dead_letter_output.  Outputs used as a dead letter output only receive
+// rejected metrics.
+func LinkDeadLetterOutputs(outputs []*RunningOutput) error {
+	aliases := make(map[string]*RunningOutput, len(outputs))
+	for _, output := range outputs {
+		if output.Config.Alias != "" {
+			aliases[output.Config.Alias] = output
+		}
+	}
+
+	targets := make(map[*RunningOutput]*RunningOutput, len(outputs))
+	for _, output := range outputs {
+		name := output.Config.DeadLetterOutput
+		if name == "" {
+			continue
+		}
+
+		target, ok := aliases[name]
+		if !ok {
+			return fmt.Errorf("%s: unknown dead_letter_output %q", output.LogName(), name)
+		}
+		targets[output] = target
+	}
+
+	// Rejected metrics must not be able to circle back to their output.
+	for output := range targets {
+		seen := map[*RunningOutput]bool{output: true}
+		for next := targets[output]; next != nil; next = targets[next] {
+			if seen[next] {
+				return fmt.Errorf("%s: dead_letter_output %q forms a cycle",
+					output.LogName(), output.Config.DeadLetterOutput)
+			}
+			seen[next] = true
+		}
+	}
+
+	isTarget := make(map[*RunningOutput]bool, len(targets))
+	for _, target := range targets {
+		isTarget[target] = true
+	}
+
+	for _, output := range outputs {
+		output.deadLetterMu.Lock()
+		output.deadLetter = targets[output]
+		output.deadLetterMu.Unlock()
+
+		var isDeadLetter int32
+		if isTarget[output] {
+			isDeadLetter = 1
+		}
+		atomic.StoreInt32(&output.isDeadLetter, isDeadLetter)
+	}
 	return nil
 }
 
@@ -215,9 +450,16 @@ func (r *RunningOutput) Close() {
 	if err != nil {
 		r.log.Errorf("Error closing output: %v", err)
 	}
+
+	err = r.buffer.Close()
+	if err != nil {
+		r.log.Errorf("Error closing buffer: %v", err)
+	}
 }
 
-func (r *RunningOutput) write(metrics []telegraf.Metric) error {
+// write calls WriteContext if the output is a telegraf.ContextWriter and Write
+// otherwise.
+func (r *RunningOutput) write(ctx context.Context, metrics []telegraf.Metric) error {
 	dropped := atomic.LoadInt64(&r.droppedMetrics)
 	if dropped > 0 {
 		r.log.Warnf("Metric buffer overflow; %d metrics have been dropped", dropped)
@@ -225,7 +467,12 @@ func (r *RunningOutput) write(metrics []telegraf.Metric) error {
 	}
 
 	start := time.Now()
-	err := r.Output.Write(metrics)
+	var err error
+	if output, ok := r.Output.(telegraf.ContextWriter); ok {
+		err = output.WriteContext(ctx, metrics)
+	} else {
+		err = r.Output.Write(metrics)
+	}
 	elapsed := time.Since(start)
 	r.WriteTime.Incr(elapsed.Nanoseconds())
 
@@ -235,6 +482,11 @@ func (r *RunningOutput) write(metrics []telegraf.Metric) error {
 	return err
 }
 
+// BufferLength returns the number of metrics waiting to be written.
+func (r *RunningOutput) BufferLength() int {
+	return r.buffer.Len()
+}
+
 func (r *RunningOutput) LogBufferStatus() {
 	nBuffer := r.buffer.Len()
 	r.log.Debugf("Buffer fullness: %d / %d metrics", nBuffer, r.MetricBufferLimit)
@@ -1,9 +1,12 @@
 package models
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/testutil"
@@ -46,7 +49,7 @@ func BenchmarkRunningOutputAddWrite(b *testing.B) {
 
 	for n := 0; n < b.N; n++ {
 		ro.AddMetric(testutil.TestMetric(101, "metric1"))
-		ro.Write()
+		ro.Write(context.Background())
 	}
 }
 
@@ -62,7 +65,7 @@ func BenchmarkRunningOutputAddWriteEvery100(b *testing.B) {
 	for n := 0; n < b.N; n++ {
 		ro.AddMetric(testutil.TestMetric(101, "metric1"))
 		if n%100 == 0 {
-			ro.Write()
+			ro.Write(context.Background())
 		}
 	}
 }
@@ -102,7 +105,7 @@ func TestRunningOutput_DropFilter(t *testing.T) {
 	}
 	assert.Len(t, m.Metrics(), 0)
 
-	err := ro.Write()
+	err := ro.Write(context.Background())
 	assert.NoError(t, err)
 	assert.Len(t, m.Metrics(), 8)
 }
@@ -127,7 +130,7 @@ func TestRunningOutput_PassFilter(t *testing.T) {
 	}
 	assert.Len(t, m.Metrics(), 0)
 
-	err := ro.Write()
+	err := ro.Write(context.Background())
 	assert.NoError(t, err)
 	assert.Len(t, m.Metrics(), 10)
 }
@@ -147,7 +150,7 @@ func TestRunningOutput_TagIncludeNoMatch(t *testing.T) {
 	ro.AddMetric(testutil.TestMetric(101, "metric1"))
 	assert.Len(t, m.Metrics(), 0)
 
-	err := ro.Write()
+	err := ro.Write(context.Background())
 	assert.NoError(t, err)
 	assert.Len(t, m.Metrics(), 1)
 	assert.Empty(t, m.Metrics()[0].Tags())
@@ -168,7 +171,7 @@ func TestRunningOutput_TagExcludeMatch(t *testing.T) {
 	ro.AddMetric(testutil.TestMetric(101, "metric1"))
 	assert.Len(t, m.Metrics(), 0)
 
-	err := ro.Write()
+	err := ro.Write(context.Background())
 	assert.NoError(t, err)
 	assert.Len(t, m.Metrics(), 1)
 	assert.Len(t, m.Metrics()[0].Tags(), 0)
@@ -189,7 +192,7 @@ func TestRunningOutput_TagExcludeNoMatch(t *testing.T) {
 	ro.AddMetric(testutil.TestMetric(101, "metric1"))
 	assert.Len(t, m.Metrics(), 0)
 
-	err := ro.Write()
+	err := ro.Write(context.Background())
 	assert.NoError(t, err)
 	assert.Len(t, m.Metrics(), 1)
 	assert.Len(t, m.Metrics()[0].Tags(), 1)
@@ -210,7 +213,7 @@ func TestRunningOutput_TagIncludeMatch(t *testing.T) {
 	ro.AddMetric(testutil.TestMetric(101, "metric1"))
 	assert.Len(t, m.Metrics(), 0)
 
-	err := ro.Write()
+	err := ro.Write(context.Background())
 	assert.NoError(t, err)
 	assert.Len(t, m.Metrics(), 1)
 	assert.Len(t, m.Metrics()[0].Tags(), 1)
@@ -233,7 +236,7 @@ func TestRunningOutputDefault(t *testing.T) {
 	}
 	assert.Len(t, m.Metrics(), 0)
 
-	err := ro.Write()
+	err := ro.Write(context.Background())
 	assert.NoError(t, err)
 	assert.Len(t, m.Metrics(), 10)
 }
@@ -258,13 +261,13 @@ func TestRunningOutputWriteFail(t *testing.T) {
 	assert.Len(t, m.Metrics(), 0)
 
 	// manual write fails
-	err := ro.Write()
+	err := ro.Write(context.Background())
 	require.Error(t, err)
 	// no successful flush yet
 	assert.Len(t, m.Metrics(), 0)
 
 	m.failWrite = false
-	err = ro.Write()
+	err = ro.Write(context.Background())
 	require.NoError(t, err)
 
 	assert.Len(t, m.Metrics(), 10)
@@ -288,7 +291,7 @@ func TestRunningOutputWriteFailOrder(t *testing.T) {
 	assert.Len(t, m.Metrics(), 0)
 
 	// Write fails
-	err := ro.Write()
+	err := ro.Write(context.Background())
 	require.Error(t, err)
 	// no successful flush yet
 	assert.Len(t, m.Metrics(), 0)
@@ -298,7 +301,7 @@ func TestRunningOutputWriteFailOrder(t *testing.T) {
 	for _, metric := range next5 {
 		ro.AddMetric(metric)
 	}
-	err = ro.Write()
+	err = ro.Write(context.Background())
 	require.NoError(t, err)
 
 	// Verify that 10 metrics were written
@@ -323,7 +326,7 @@ func TestRunningOutputWriteFailOrder2(t *testing.T) {
 		ro.AddMetric(metric)
 	}
 	// Write fails
-	err := ro.Write()
+	err := ro.Write(context.Background())
 	require.Error(t, err)
 	// no successful flush yet
 	assert.Len(t, m.Metrics(), 0)
@@ -333,7 +336,7 @@ func TestRunningOutputWriteFailOrder2(t *testing.T) {
 		ro.AddMetric(metric)
 	}
 	// Write fails
-	err = ro.Write()
+	err = ro.Write(context.Background())
 	require.Error(t, err)
 	// no successful flush yet
 	assert.Len(t, m.Metrics(), 0)
@@ -343,7 +346,7 @@ func TestRunningOutputWriteFailOrder2(t *testing.T) {
 		ro.AddMetric(metric)
 	}
 	// Write fails
-	err = ro.Write()
+	err = ro.Write(context.Background())
 	require.Error(t, err)
 	// no successful flush yet
 	assert.Len(t, m.Metrics(), 0)
@@ -353,13 +356,13 @@ func TestRunningOutputWriteFailOrder2(t *testing.T) {
 		ro.AddMetric(metric)
 	}
 	// Write fails
-	err = ro.Write()
+	err = ro.Write(context.Background())
 	require.Error(t, err)
 	// no successful flush yet
 	assert.Len(t, m.Metrics(), 0)
 
 	m.failWrite = false
-	err = ro.Write()
+	err = ro.Write(context.Background())
 	require.NoError(t, err)
 
 	// Verify that 20 metrics were written
@@ -390,19 +393,19 @@ func TestRunningOutputWriteFailOrder3(t *testing.T) {
 	assert.Len(t, m.Metrics(), 0)
 
 	// Write fails
-	err := ro.Write()
+	err := ro.Write(context.Background())
 	require.Error(t, err)
 	// no successful flush yet
 	assert.Len(t, m.Metrics(), 0)
 
 	// add and attempt to write a single metric:
 	ro.AddMetric(next5[0])
-	err = ro.Write()
+	err = ro.Write(context.Background())
 	require.Error(t, err)
 
 	// unset fail and write metrics
 	m.failWrite = false
-	err = ro.Write()
+	err = ro.Write(context.Background())
 	require.NoError(t, err)
 
 	// Verify that 6 metrics were written
@@ -412,6 +415,189 @@ func TestRunningOutputWriteFailOrder3(t *testing.T) {
 	assert.Equal(t, expected, m.Metrics())
 }
 
+func TestRunningOutputRetryBackoff(t *testing.T) {
+	conf := &OutputConfig{
+		Filter:               Filter{},
+		RetryInitialInterval: time.Hour,
+	}
+
+	m := &mockOutput{}
+	m.failWrite = true
+	ro := NewRunningOutput("test", m, conf, 100, 1000)
+
+	for _, metric := range first5 {
+		ro.AddMetric(metric)
+	}
+
+	err := ro.Write(context.Background())
+	require.Error(t, err)
+	require.Equal(t, time.Hour, ro.retryDelay())
+
+	// Writes are skipped until the delay expires.
+	m.failWrite = false
+	require.NoError(t, ro.Write(context.Background()))
+	require.Len(t, m.Metrics(), 0)
+
+	ro.retryAt = time.Now().Add(-time.Second)
+	require.NoError(t, ro.Write(context.Background()))
+	require.Len(t, m.Metrics(), 5)
+	require.True(t, ro.retryAt.IsZero())
+}
+
+func TestRunningOutputFinalWriteWhileBackingOff(t *testing.T) {
+	conf := &OutputConfig{
+		Filter:               Filter{},
+		RetryInitialInterval: time.Hour,
+	}
+
+	m := &mockOutput{}
+	m.failWrite = true
+	ro := NewRunningOutput("test", m, conf, 100, 1000)
+
+	for _, metric := range first5 {
+		ro.AddMetric(metric)
+	}
+
+	require.Error(t, ro.Write(context.Background()))
+
+	m.failWrite = false
+	require.NoError(t, ro.FinalWrite(context.Background()))
+	require.Len(t, m.Metrics(), 5)
+}
+
+func TestRunningOutputRetryDelay(t *testing.T) {
+	conf := &OutputConfig{
+		RetryInitialInterval: time.Second,
+		RetryMaxInterval:     10 * time.Second,
+		RetryMultiplier:      3,
+	}
+	ro := NewRunningOutput("test", &mockOutput{}, conf, 100, 1000)
+
+	expected := []time.Duration{
+		time.Second, 3 * time.Second, 9 * time.Second, 10 * time.Second, 10 * time.Second,
+	}
+	for _, delay := range expected {
+		ro.failures++
+		require.Equal(t, delay, ro.retryDelay())
+	}
+}
+
+func TestRunningOutputPermanentErrorDeadLetter(t *testing.T) {
+	m := &mockOutput{}
+	m.writeErr = telegraf.NewPermanentError(errors.New("invalid metric"))
+	ro := NewRunningOutput("permanent", m,
+		&OutputConfig{Name: "permanent", Filter: Filter{}, DeadLetterOutput: "rejected"}, 100, 1000)
+
+	dl := &mockOutput{}
+	dlo := NewRunningOutput("file", dl,
+		&OutputConfig{Filter: Filter{}, Alias: "rejected"}, 100, 1000)
+
+	require.NoError(t, LinkDeadLetterOutputs([]*RunningOutput{ro, dlo}))
+	require.False(t, ro.IsDeadLetter())
+	require.True(t, dlo.IsDeadLetter())
+
+	for _, metric := range first5 {
+		ro.AddMetric(metric)
+	}
+
+	require.NoError(t, ro.Write(context.Background()))
+	require.Len(t, m.Metrics(), 0)
+	require.Equal(t, 0, ro.buffer.Len())
+	require.Equal(t, int64(5), ro.MetricsRejected.Get())
+
+	require.NoError(t, dlo.Write(context.Background()))
+	require.Len(t, dl.Metrics(), 5)
+}
+
+func TestRunningOutputWrappedPermanentError(t *testing.T) {
+	m := &mockOutput{}
+	m.writeErr = wrappedError{telegraf.NewPermanentError(errors.New("invalid metric"))}
+	ro := NewRunningOutput("test", m, &OutputConfig{Filter: Filter{}}, 100, 1000)
+
+	for _, metric := range first5 {
+		ro.AddMetric(metric)
+	}
+
+	require.NoError(t, ro.Write(context.Background()))
+	require.Equal(t, 0, ro.buffer.Len())
+	require.Equal(t, int64(5), ro.MetricsRejected.Get())
+}
+
+func TestRunningOutputRetryMaxAttempts(t *testing.T) {
+	conf := &OutputConfig{
+		Name:             "attempts",
+		Filter:           Filter{},
+		RetryMaxAttempts: 2,
+	}
+
+	m := &mockOutput{}
+	m.failWrite = true
+	ro := NewRunningOutput("attempts", m, conf, 100, 1000)
+
+	for _, metric := range first5 {
+		ro.AddMetric(metric)
+	}
+
+	require.Error(t, ro.Write(context.Background()))
+	require.Equal(t, 5, ro.buffer.Len())
+
+	// Without a dead letter output the batch is dropped.
+	require.NoError(t, ro.Write(context.Background()))
+	require.Equal(t, 0, ro.buffer.Len())
+	require.Equal(t, int64(5), ro.MetricsRejected.Get())
+}
+
+func TestRunningOutputWriteContextCancelled(t *testing.T) {
+	conf := &OutputConfig{
+		Name:                 "cancelled",
+		Filter:               Filter{},
+		RetryInitialInterval: time.Minute,
+	}
+
+	m := &contextOutput{}
+	ro := NewRunningOutput("cancelled", m, conf, 100, 1000)
+
+	for _, metric := range first5 {
+		ro.AddMetric(metric)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// An interrupted write is not a failure and is retried right away.
+	require.Equal(t, context.Canceled, ro.Write(ctx))
+	require.Equal(t, 5, ro.buffer.Len())
+	require.Equal(t, 0, ro.failures)
+	require.True(t, ro.retryAt.IsZero())
+
+	require.NoError(t, ro.Write(context.Background()))
+	require.Len(t, m.Metrics(), 5)
+}
+
+func TestLinkDeadLetterOutputsErrors(t *testing.T) {
+	a := NewRunningOutput("a", &mockOutput{},
+		&OutputConfig{Alias: "a", DeadLetterOutput: "b"}, 100, 1000)
+	b := NewRunningOutput("b", &mockOutput{},
+		&OutputConfig{Alias: "b", DeadLetterOutput: "a"}, 100, 1000)
+	c := NewRunningOutput("c", &mockOutput{},
+		&OutputConfig{DeadLetterOutput: "missing"}, 100, 1000)
+
+	require.Error(t, LinkDeadLetterOutputs([]*RunningOutput{a, b}))
+	require.Error(t, LinkDeadLetterOutputs([]*RunningOutput{c}))
+}
+
+type wrappedError struct {
+	err error
+}
+
+func (e wrappedError) Error() string {
+	return "write failed: " + e.err.Error()
+}
+
+func (e wrappedError) Unwrap() error {
+	return e.err
+}
+
 type mockOutput struct {
 	sync.Mutex
 
@@ -419,6 +605,8 @@ type mockOutput struct {
 
 	// if true, mock a write failure
 	failWrite bool
+	// if set, returned by Write
+	writeErr error
 }
 
 func (m *mockOutput) Connect() error {
@@ -443,6 +631,9 @@ func (m *mockOutput) Write(metrics []telegraf.Metric) error {
 	if m.failWrite {
 		return fmt.Errorf("Failed Write!")
 	}
+	if m.writeErr != nil {
+		return m.writeErr
+	}
 
 	if m.metrics == nil {
 		m.metrics = []telegraf.Metric{}
@@ -460,6 +651,18 @@ func (m *mockOutput) Metrics() []telegraf.Metric {
 	return m.metrics
 }
 
+// contextOutput is a mockOutput whose writes fail once the context is done.
+type contextOutput struct {
+	mockOutput
+}
+
+func (m *contextOutput) WriteContext(ctx context.Context, metrics []telegraf.Metric) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return m.Write(metrics)
+}
+
 type perfOutput struct {
 	// if true, mock a write failure
 	failWrite bool
@@ -2,6 +2,7 @@ package models
 
 import (
 	"sync"
+	"time"
 
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/selfstat"
@@ -12,6 +13,18 @@ type RunningProcessor struct {
 	log       telegraf.Logger
 	Processor telegraf.Processor
 	Config    *ProcessorConfig
+
+	// Additional instances of the processor when the concurrency is greater
+	// than one.  Metrics are assigned to an instance by their series.
+	workers []*processorWorker
+
+	MetricsProcessed selfstat.Stat
+	ProcessTime      selfstat.Stat
+}
+
+type processorWorker struct {
+	sync.Mutex
+	processor telegraf.Processor
 }
 
 type RunningProcessors []*RunningProcessor
@@ -26,6 +39,9 @@ type ProcessorConfig struct {
 	Alias  string
 	Order  int64
 	Filter Filter
+
+	// Number of instances of the processor handling metrics in parallel.
+	Concurrency int
 }
 
 func NewRunningProcessor(processor telegraf.Processor, config *ProcessorConfig) *RunningProcessor {
@@ -44,7 +60,38 @@ func NewRunningProcessor(processor telegraf.Processor, config *ProcessorConfig)
 		Processor: processor,
 		Config:    config,
 		log:       logger,
+		MetricsProcessed: selfstat.Register(
+			"process",
+			"metrics_processed",
+			tags,
+		),
+		ProcessTime: selfstat.RegisterTiming(
+			"process",
+			"process_time_ns",
+			tags,
+		),
+	}
+}
+
+// LogName returns the name of the processor used in log messages.
+func (rp *RunningProcessor) LogName() string {
+	return logName("processors", rp.Config.Name, rp.Config.Alias)
+}
+
+// AddWorker adds an instance of the processor, configured like the first,
+// to handle metrics in parallel.
+func (rp *RunningProcessor) AddWorker(processor telegraf.Processor) {
+	setLogIfExist(processor, rp.log)
+	rp.workers = append(rp.workers, &processorWorker{processor: processor})
+}
+
+// Instances returns every instance of the processor.
+func (rp *RunningProcessor) Instances() []telegraf.Processor {
+	instances := []telegraf.Processor{rp.Processor}
+	for _, w := range rp.workers {
+		instances = append(instances, w.processor)
 	}
+	return instances
 }
 
 func (rp *RunningProcessor) metricFiltered(metric telegraf.Metric) {
@@ -67,13 +114,20 @@ func (r *RunningProcessor) Init() error {
 			return err
 		}
 	}
+	for _, w := range r.workers {
+		if p, ok := w.processor.(telegraf.Initializer); ok {
+			err := p.Init()
+			if err != nil {
+				return err
+			}
+		}
+	}
 	return nil
 }
 
+// Apply runs the processor on the metrics.  It is safe to call concurrently;
+// each instance of the processor handles one call at a time.
 func (rp *RunningProcessor) Apply(in ...telegraf.Metric) []telegraf.Metric {
-	rp.Lock()
-	defer rp.Unlock()
-
 	ret := []telegraf.Metric{}
 
 	for _, metric := range in {
@@ -92,8 +146,34 @@ func (rp *RunningProcessor) Apply(in ...telegraf.Metric) []telegraf.Metric {
 
 		// This metric should pass through the filter, so call the filter Apply
 		// function and append results to the output slice.
-		ret = append(ret, rp.Processor.Apply(metric)...)
+		ret = append(ret, rp.apply(metric)...)
+	}
+
+	return ret
+}
+
+// apply runs the instance of the processor assigned to the metric's series,
+// so that the metrics of a series are always handled by the same instance.
+func (rp *RunningProcessor) apply(metric telegraf.Metric) []telegraf.Metric {
+	var lock sync.Locker = rp
+	processor := rp.Processor
+	if len(rp.workers) > 0 {
+		i := metric.HashID() % uint64(len(rp.workers)+1)
+		if i > 0 {
+			lock = rp.workers[i-1]
+			processor = rp.workers[i-1].processor
+		}
 	}
 
+	lock.Lock()
+	start := time.Now()
+	ret := processor.Apply(metric)
+	elapsed := time.Since(start)
+	lock.Unlock()
+
+	if rp.MetricsProcessed != nil {
+		rp.MetricsProcessed.Incr(1)
+		rp.ProcessTime.Incr(elapsed.Nanoseconds())
+	}
 	return ret
 }
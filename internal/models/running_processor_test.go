@@ -1,6 +1,7 @@
 package models
 
 import (
+	"fmt"
 	"sort"
 	"testing"
 	"time"
@@ -187,3 +188,44 @@ func TestRunningProcessor_Order(t *testing.T) {
 		RunningProcessors{rp1, rp2, rp3},
 		procs)
 }
+
+func TestRunningProcessor_Workers(t *testing.T) {
+	// Each instance tags the metrics with its own id.
+	newProcessor := func(id string) *MockProcessor {
+		return TagProcessor("instance", id)
+	}
+
+	rp := NewRunningProcessor(newProcessor("0"), &ProcessorConfig{
+		Name:        "workers",
+		Concurrency: 4,
+	})
+	for _, id := range []string{"1", "2", "3"} {
+		rp.AddWorker(newProcessor(id))
+	}
+	require.NoError(t, rp.Init())
+
+	instances := make(map[string]string)
+	for i := 0; i < 100; i++ {
+		host := fmt.Sprintf("host%d", i%10)
+		m := testutil.MustMetric("cpu",
+			map[string]string{"host": host},
+			map[string]interface{}{"value": 42.0},
+			time.Unix(0, 0))
+
+		out := rp.Apply(m)
+		require.Len(t, out, 1)
+
+		id, _ := out[0].GetTag("instance")
+		if prev, ok := instances[host]; ok {
+			require.Equal(t, prev, id, "series moved to another instance")
+		}
+		instances[host] = id
+	}
+
+	used := make(map[string]bool)
+	for _, id := range instances {
+		used[id] = true
+	}
+	require.True(t, len(used) > 1)
+	require.Equal(t, int64(100), rp.MetricsProcessed.Get())
+}
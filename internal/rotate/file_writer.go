@@ -2,6 +2,7 @@ package rotate
 
 // Rotating things
 import (
+	"compress/gzip"
 	"fmt"
 	"io"
 	"os"
@@ -11,6 +12,8 @@ import (
 	"strings"
 	"sync"
 	"time"
+
+	"github.com/klauspost/compress/zstd"
 )
 
 // FilePerm defines the permissions that Writer will use for all
@@ -20,11 +23,26 @@ const (
 	DateFormat = "2006-01-02"
 )
 
+// Compression algorithms of the archives.
+const (
+	CompressionNone = ""
+	CompressionGzip = "gzip"
+	CompressionZstd = "zstd"
+)
+
+// compressionExtensions are the extensions appended to compressed archives.
+var compressionExtensions = map[string]string{
+	CompressionGzip: ".gz",
+	CompressionZstd: ".zst",
+}
+
 // FileWriter implements the io.Writer interface and writes to the
 // filename specified.
 // Will rotate at the specified interval and/or when the current file size exceeds maxSizeInBytes
 // At rotation time, current file is renamed and a new file is created.
-// If the number of archives exceeds maxArchives, older files are deleted.
+// If the number of archives exceeds maxArchives, or their total size exceeds
+// maxTotalSize, older files are deleted.
+// Archives are compressed in the background when a compression is set.
 type FileWriter struct {
 	filename                 string
 	filenameRotationTemplate string
@@ -32,18 +50,39 @@ type FileWriter struct {
 	interval                 time.Duration
 	maxSizeInBytes           int64
 	maxArchives              int
+	maxTotalSize             int64
+	compression              string
 	expireTime               time.Time
 	bytesWritten             int64
 	sync.Mutex
+
+	// archiving is held while compressing and purging archives, so that
+	// archives are processed in the order they were rotated.
+	archiving sync.Mutex
+	pending   sync.WaitGroup
 }
 
-// NewFileWriter creates a new file writer.
-func NewFileWriter(filename string, interval time.Duration, maxSizeInBytes int64, maxArchives int) (io.WriteCloser, error) {
-	if interval == 0 && maxSizeInBytes <= 0 {
-		// No rotation needed so a basic io.Writer will do the trick
-		return openFile(filename)
+// Option sets an optional setting of a FileWriter.
+type Option func(*FileWriter)
+
+// WithCompression compresses the archives with the algorithm, one of
+// CompressionNone, CompressionGzip or CompressionZstd.
+func WithCompression(compression string) Option {
+	return func(w *FileWriter) {
+		w.compression = compression
 	}
+}
 
+// WithMaxTotalSize deletes the oldest archives when the total size of the
+// archives exceeds maxTotalSize.  When 0 the total size is not limited.
+func WithMaxTotalSize(maxTotalSize int64) Option {
+	return func(w *FileWriter) {
+		w.maxTotalSize = maxTotalSize
+	}
+}
+
+// NewFileWriter creates a new file writer.
+func NewFileWriter(filename string, interval time.Duration, maxSizeInBytes int64, maxArchives int, options ...Option) (io.WriteCloser, error) {
 	w := &FileWriter{
 		filename:                 filename,
 		interval:                 interval,
@@ -51,6 +90,20 @@ func NewFileWriter(filename string, interval time.Duration, maxSizeInBytes int64
 		maxArchives:              maxArchives,
 		filenameRotationTemplate: getFilenameRotationTemplate(filename),
 	}
+	for _, option := range options {
+		option(w)
+	}
+
+	switch w.compression {
+	case CompressionNone, CompressionGzip, CompressionZstd:
+	default:
+		return nil, fmt.Errorf("unknown compression %q, must be one of gzip or zstd", w.compression)
+	}
+
+	if interval == 0 && maxSizeInBytes <= 0 {
+		// No rotation needed so a basic io.Writer will do the trick
+		return openFile(filename)
+	}
 
 	if err := w.openCurrent(); err != nil {
 		return nil, err
@@ -95,7 +148,11 @@ func (w *FileWriter) Close() (err error) {
 	defer w.Unlock()
 
 	// Rotate before closing
-	if err = w.rotate(); err != nil {
+	err = w.rotate()
+
+	// Wait for the archives to be compressed
+	w.pending.Wait()
+	if err != nil {
 		return err
 	}
 
@@ -129,7 +186,7 @@ func (w *FileWriter) rotateIfNeeded() error {
 		(w.maxSizeInBytes > 0 && w.bytesWritten >= w.maxSizeInBytes) {
 		if err := w.rotate(); err != nil {
 			//Ignore rotation errors and keep the log open
-			fmt.Printf("unable to rotate the file '%s', %s", w.filename, err.Error())
+			fmt.Fprintf(os.Stderr, "unable to rotate the file '%s', %s\n", w.filename, err.Error())
 		}
 		return w.openCurrent()
 	}
@@ -141,40 +198,177 @@ func (w *FileWriter) rotate() (err error) {
 		return err
 	}
 
-	// Use year-month-date for readability, unix time to make the file name unique with second precision
-	now := time.Now()
-	rotatedFilename := fmt.Sprintf(w.filenameRotationTemplate, now.Format(DateFormat), strconv.FormatInt(now.Unix(), 10))
+	rotatedFilename := w.rotatedFilename(time.Now())
 	if err = os.Rename(w.filename, rotatedFilename); err != nil {
 		return err
 	}
 
-	if err = w.purgeArchivesIfNeeded(); err != nil {
-		return err
+	if w.compression == CompressionNone {
+		return w.purgeArchivesIfNeeded()
 	}
 
+	// Compress in the background to not block writes on large files
+	w.pending.Add(1)
+	go func() {
+		defer w.pending.Done()
+		w.archiving.Lock()
+		defer w.archiving.Unlock()
+
+		if err := compressFile(rotatedFilename, w.compression); err != nil {
+			fmt.Fprintf(os.Stderr, "unable to compress the file '%s', %s\n", rotatedFilename, err.Error())
+		}
+		if err := w.purgeArchivesIfNeeded(); err != nil {
+			fmt.Fprintf(os.Stderr, "unable to purge the archives of '%s', %s\n", w.filename, err.Error())
+		}
+	}()
 	return nil
 }
 
+// rotatedFilename returns the name of the archive.  Use year-month-date for
+// readability, unix time to make the file name unique with second precision.
+// A sequence number is appended if the file was already rotated within the
+// same second.
+func (w *FileWriter) rotatedFilename(now time.Time) string {
+	date, unix := now.Format(DateFormat), strconv.FormatInt(now.Unix(), 10)
+	filename := fmt.Sprintf(w.filenameRotationTemplate, date, unix)
+	for i := 1; w.archiveExists(filename); i++ {
+		filename = fmt.Sprintf(w.filenameRotationTemplate, date, unix+"_"+strconv.Itoa(i))
+	}
+	return filename
+}
+
+// archiveExists returns true if the archive exists, compressed or not.
+func (w *FileWriter) archiveExists(filename string) bool {
+	if _, err := os.Stat(filename); err == nil {
+		return true
+	}
+	if w.compression != CompressionNone {
+		if _, err := os.Stat(filename + compressionExtensions[w.compression]); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// compressFile compresses the file to a file with the extension of the
+// compression appended, and removes the file.
+func compressFile(filename string, compression string) error {
+	src, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	compressed := filename + compressionExtensions[compression]
+	dst, err := os.OpenFile(compressed, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, FilePerm)
+	if err != nil {
+		return err
+	}
+
+	var cw io.WriteCloser
+	switch compression {
+	case CompressionGzip:
+		cw = gzip.NewWriter(dst)
+	case CompressionZstd:
+		cw, err = zstd.NewWriter(dst)
+	}
+	if err == nil {
+		_, err = io.Copy(cw, src)
+		if cerr := cw.Close(); err == nil {
+			err = cerr
+		}
+	}
+	if cerr := dst.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(compressed)
+		return err
+	}
+
+	src.Close()
+	return os.Remove(filename)
+}
+
+// archiveOrder returns the unix time and the sequence number in the name of
+// the archive, as written by rotatedFilename.
+func (w *FileWriter) archiveOrder(filename string) (unix int64, seq int64) {
+	prefix := strings.SplitN(w.filenameRotationTemplate, "%s", 2)[0]
+	rest := strings.TrimPrefix(filename, prefix)
+	if len(rest) <= len(DateFormat) {
+		return 0, 0
+	}
+	rest = rest[len(DateFormat)+1:]
+
+	unix, rest = leadingInt(rest)
+	if strings.HasPrefix(rest, "_") {
+		seq, _ = leadingInt(rest[1:])
+	}
+	return unix, seq
+}
+
+// leadingInt parses the digits at the start of s and returns the remainder.
+func leadingInt(s string) (int64, string) {
+	i := 0
+	for i < len(s) && s[i] >= '0' && s[i] <= '9' {
+		i++
+	}
+	n, _ := strconv.ParseInt(s[:i], 10, 64)
+	return n, s[i:]
+}
+
 func (w *FileWriter) purgeArchivesIfNeeded() (err error) {
-	if w.maxArchives == -1 {
+	if w.maxArchives == -1 && w.maxTotalSize <= 0 {
 		//Skip archiving
 		return nil
 	}
 
+	// Compressed archives have an additional extension
 	var matches []string
-	if matches, err = filepath.Glob(fmt.Sprintf(w.filenameRotationTemplate, "*", "*")); err != nil {
+	if matches, err = filepath.Glob(fmt.Sprintf(w.filenameRotationTemplate, "*", "*") + "*"); err != nil {
 		return err
 	}
 
+	//sort files by their rotation time and sequence number to delete older
+	//files first, a lexical sort would put "_10" before "_2"
+	sort.SliceStable(matches, func(i, j int) bool {
+		ti, si := w.archiveOrder(matches[i])
+		tj, sj := w.archiveOrder(matches[j])
+		if ti != tj {
+			return ti < tj
+		}
+		if si != sj {
+			return si < sj
+		}
+		return matches[i] < matches[j]
+	})
+
 	//if there are more archives than the configured maximum, then purge older files
-	if len(matches) > w.maxArchives {
-		//sort files alphanumerically to delete older files first
-		sort.Strings(matches)
+	if w.maxArchives != -1 && len(matches) > w.maxArchives {
 		for _, filename := range matches[:len(matches)-w.maxArchives] {
 			if err = os.Remove(filename); err != nil {
 				return err
 			}
 		}
+		matches = matches[len(matches)-w.maxArchives:]
+	}
+
+	//if the archives are larger than the configured total size, then purge older files
+	if w.maxTotalSize > 0 {
+		var totalSize int64
+		sizes := make([]int64, len(matches))
+		for i, filename := range matches {
+			if info, err := os.Stat(filename); err == nil {
+				sizes[i] = info.Size()
+				totalSize += sizes[i]
+			}
+		}
+		for i := 0; i < len(matches) && totalSize > w.maxTotalSize; i++ {
+			if err = os.Remove(matches[i]); err != nil {
+				return err
+			}
+			totalSize -= sizes[i]
+		}
 	}
 	return nil
 }
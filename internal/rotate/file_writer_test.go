@@ -1,12 +1,16 @@
 package rotate
 
 import (
+	"compress/gzip"
+	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
 	"path/filepath"
 	"testing"
 	"time"
 
+	"github.com/klauspost/compress/zstd"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -113,3 +117,132 @@ func TestFileWriter_CloseRotates(t *testing.T) {
 	assert.Equal(t, 1, len(files))
 	assert.Regexp(t, "^test\\.[^\\.]+\\.log$", files[0].Name())
 }
+
+func TestFileWriter_Compression(t *testing.T) {
+	tests := []struct {
+		compression string
+		extension   string
+		decompress  func(r io.Reader) (io.Reader, error)
+	}{
+		{
+			compression: CompressionGzip,
+			extension:   ".gz",
+			decompress: func(r io.Reader) (io.Reader, error) {
+				return gzip.NewReader(r)
+			},
+		},
+		{
+			compression: CompressionZstd,
+			extension:   ".zst",
+			decompress: func(r io.Reader) (io.Reader, error) {
+				return zstd.NewReader(r)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.compression, func(t *testing.T) {
+			tempDir, err := ioutil.TempDir("", "RotationCompression")
+			require.NoError(t, err)
+			defer os.RemoveAll(tempDir)
+
+			writer, err := NewFileWriter(filepath.Join(tempDir, "test.log"), 0, 5, -1,
+				WithCompression(tt.compression))
+			require.NoError(t, err)
+
+			_, err = writer.Write([]byte("First file"))
+			require.NoError(t, err)
+			// Closing rotates the empty current file within the same second
+			require.NoError(t, writer.Close())
+
+			files, _ := ioutil.ReadDir(tempDir)
+			require.Len(t, files, 2)
+			for _, file := range files {
+				require.Regexp(t, "^test\\.[^\\.]+\\.log\\"+tt.extension+"$", file.Name())
+			}
+
+			// The first archive sorts first, the second one has a sequence
+			// number appended
+			f, err := os.Open(filepath.Join(tempDir, files[0].Name()))
+			require.NoError(t, err)
+			defer f.Close()
+			r, err := tt.decompress(f)
+			require.NoError(t, err)
+			contents, err := ioutil.ReadAll(r)
+			require.NoError(t, err)
+			require.Equal(t, "First file", string(contents))
+		})
+	}
+}
+
+func TestFileWriter_UnknownCompression(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "RotationCompression")
+	require.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+
+	_, err = NewFileWriter(filepath.Join(tempDir, "test.log"), 0, 5, -1, WithCompression("lz4"))
+	require.Error(t, err)
+}
+
+func TestFileWriter_DeleteArchivesTotalSize(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "RotationDeleteArchivesTotalSize")
+	require.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+
+	writer, err := NewFileWriter(filepath.Join(tempDir, "test.log"), 0, 5, -1, WithMaxTotalSize(25))
+	require.NoError(t, err)
+	defer writer.Close()
+
+	_, err = writer.Write([]byte("First file"))
+	require.NoError(t, err)
+	// File names include the date with second precision
+	time.Sleep(1 * time.Second)
+	_, err = writer.Write([]byte("Second file"))
+	require.NoError(t, err)
+	time.Sleep(1 * time.Second)
+	_, err = writer.Write([]byte("Third file"))
+	require.NoError(t, err)
+
+	files, _ := ioutil.ReadDir(tempDir)
+	require.Len(t, files, 3)
+
+	for _, file := range files {
+		contents, err := ioutil.ReadFile(filepath.Join(tempDir, file.Name()))
+		require.NoError(t, err)
+		require.NotEqual(t, "First file", string(contents))
+	}
+}
+
+func TestFileWriter_DeleteArchivesOrder(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "RotationDeleteArchivesOrder")
+	require.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+
+	writer, err := NewFileWriter(filepath.Join(tempDir, "test.log"), 0, 1024, 3)
+	require.NoError(t, err)
+	defer writer.Close()
+
+	// Archives rotated within the same second, and an older one.
+	names := []string{"test.2020-01-01-1577836799.log", "test.2020-01-01-1577836800.log"}
+	for i := 1; i <= 11; i++ {
+		names = append(names, fmt.Sprintf("test.2020-01-01-1577836800_%d.log", i))
+	}
+	for _, name := range names {
+		require.NoError(t, ioutil.WriteFile(filepath.Join(tempDir, name), []byte(name), FilePerm))
+	}
+
+	require.NoError(t, writer.(*FileWriter).purgeArchivesIfNeeded())
+
+	files, err := ioutil.ReadDir(tempDir)
+	require.NoError(t, err)
+	var remaining []string
+	for _, file := range files {
+		remaining = append(remaining, file.Name())
+	}
+	require.ElementsMatch(t, []string{
+		"test.log",
+		"test.2020-01-01-1577836800_9.log",
+		"test.2020-01-01-1577836800_10.log",
+		"test.2020-01-01-1577836800_11.log",
+	}, remaining)
+}
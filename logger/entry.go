@@ -0,0 +1,149 @@
+package logger
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/influxdata/wlog"
+)
+
+// entryRegex matches the level prefix of a message and the name of its
+// source, such as "E! [inputs.cpu] ".
+var entryRegex = regexp.MustCompile(`^([DIWE])! (?:\[([^\]]+)\] )?`)
+
+var levelNames = map[wlog.Level]string{
+	wlog.DEBUG: "debug",
+	wlog.INFO:  "info",
+	wlog.WARN:  "warn",
+	wlog.ERROR: "error",
+}
+
+// pluginTypes are the types prefixing the names of plugins in log messages.
+var pluginTypes = map[string]bool{
+	"inputs":      true,
+	"outputs":     true,
+	"processors":  true,
+	"aggregators": true,
+}
+
+// ParseLevel returns the log level with the name, one of "debug", "info",
+// "warn" or "error".
+func ParseLevel(name string) (wlog.Level, error) {
+	for level, levelName := range levelNames {
+		if strings.EqualFold(name, levelName) {
+			return level, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid log level %q, must be one of debug, info, warn or error", name)
+}
+
+// entry is a log message split into its parts.
+type entry struct {
+	level   wlog.Level
+	source  string
+	message string
+}
+
+// parseEntry parses the message, messages without a level prefix are at
+// info level.
+func parseEntry(b []byte) entry {
+	match := entryRegex.FindSubmatchIndex(b)
+	if match == nil {
+		return entry{level: wlog.INFO, message: strings.TrimRight(string(b), "\n")}
+	}
+
+	e := entry{
+		level:   wlog.Levels[b[match[2]]],
+		message: strings.TrimRight(string(b[match[1]:]), "\n"),
+	}
+	if match[4] >= 0 {
+		e.source = string(b[match[4]:match[5]])
+	}
+	return e
+}
+
+// enabled returns true if the message is at or above the global log level.
+func (e *entry) enabled() bool {
+	return e.level >= wlog.LogLevel()
+}
+
+// plugin returns the type, name and alias of the plugin the message is
+// from, plugin sources are named "<type>.<name>" or "<type>.<name>::<alias>".
+// It returns false if the message is not from a plugin.
+func (e *entry) plugin() (typ, name, alias string, ok bool) {
+	i := strings.Index(e.source, ".")
+	if i <= 0 || !pluginTypes[e.source[:i]] {
+		return "", "", "", false
+	}
+
+	typ, name = e.source[:i], e.source[i+1:]
+	if j := strings.Index(name, "::"); j >= 0 {
+		name, alias = name[:j], name[j+2:]
+	}
+	return typ, name, alias, true
+}
+
+// severity returns the syslog severity of the message.
+func (e *entry) severity() int {
+	switch e.level {
+	case wlog.DEBUG:
+		return 7
+	case wlog.WARN:
+		return 4
+	case wlog.ERROR:
+		return 3
+	}
+	return 6
+}
+
+// jsonEntry is the format of a message with the "json" log format.  Messages
+// of plugins have the plugin type, name and alias, other messages have their
+// source.
+type jsonEntry struct {
+	Time    string `json:"time"`
+	Level   string `json:"level"`
+	Type    string `json:"type,omitempty"`
+	Plugin  string `json:"plugin,omitempty"`
+	Alias   string `json:"alias,omitempty"`
+	Source  string `json:"source,omitempty"`
+	Message string `json:"msg"`
+}
+
+// json returns the message as a JSON line.
+func (e *entry) json(t time.Time) ([]byte, error) {
+	je := jsonEntry{
+		Time:    t.UTC().Format(time.RFC3339),
+		Level:   levelNames[e.level],
+		Source:  e.source,
+		Message: e.message,
+	}
+
+	if typ, name, alias, ok := e.plugin(); ok {
+		je.Type, je.Plugin, je.Alias = typ, name, alias
+		je.Source = ""
+	}
+
+	line, err := json.Marshal(je)
+	if err != nil {
+		return nil, err
+	}
+	return append(line, '\n'), nil
+}
+
+// levelWriter drops the messages below the global log level.  Loggers with
+// a level of their own bypass it using Print.
+type levelWriter struct {
+	w io.Writer
+}
+
+func (w *levelWriter) Write(b []byte) (int, error) {
+	e := parseEntry(b)
+	if !e.enabled() {
+		return 0, nil
+	}
+	return w.w.Write(b)
+}
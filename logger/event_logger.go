@@ -4,7 +4,6 @@ import (
 	"io"
 	"strings"
 
-	"github.com/influxdata/wlog"
 	"github.com/kardianos/service"
 )
 
@@ -41,7 +40,7 @@ type eventLoggerCreator struct {
 }
 
 func (e *eventLoggerCreator) CreateLogger(config LogConfig) (io.Writer, error) {
-	return wlog.NewWriter(&eventLogger{logger: e.serviceLogger}), nil
+	return &eventLogger{logger: e.serviceLogger}, nil
 }
 
 func RegisterEventLogger(serviceLogger service.Logger) {
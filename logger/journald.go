@@ -0,0 +1,87 @@
+package logger
+
+import (
+	"encoding/binary"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+)
+
+const (
+	LogTargetJournald = "journald"
+)
+
+// journalSocket is the socket of the native journal protocol.
+var journalSocket = "/run/systemd/journal/socket"
+
+// journaldLog writes messages to the systemd journal.  The plugin type, name
+// and alias, or the source of the message, are set as journal fields.
+type journaldLog struct {
+	socketWriter
+}
+
+func (j *journaldLog) Write(b []byte) (int, error) {
+	e := parseEntry(b)
+
+	if err := j.write(journalMessage(&e)); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+// journalMessage returns the datagram of the message in the native journal
+// protocol.
+func journalMessage(e *entry) []byte {
+	var b []byte
+	b = appendJournalField(b, "MESSAGE", e.message)
+	b = appendJournalField(b, "PRIORITY", strconv.Itoa(e.severity()))
+	b = appendJournalField(b, "SYSLOG_IDENTIFIER", "telegraf")
+	if typ, name, alias, ok := e.plugin(); ok {
+		b = appendJournalField(b, "TELEGRAF_PLUGIN_TYPE", typ)
+		b = appendJournalField(b, "TELEGRAF_PLUGIN", name)
+		if alias != "" {
+			b = appendJournalField(b, "TELEGRAF_ALIAS", alias)
+		}
+	} else if e.source != "" {
+		b = appendJournalField(b, "TELEGRAF_SOURCE", e.source)
+	}
+	return b
+}
+
+// appendJournalField appends the field as "KEY=value\n".  Values with a
+// newline are written as the key, a newline, the length of the value as a
+// little endian 64 bit integer, the value and a newline.
+func appendJournalField(b []byte, key, value string) []byte {
+	b = append(b, key...)
+	if !strings.Contains(value, "\n") {
+		b = append(b, '=')
+		b = append(b, value...)
+		return append(b, '\n')
+	}
+
+	var size [8]byte
+	binary.LittleEndian.PutUint64(size[:], uint64(len(value)))
+	b = append(b, '\n')
+	b = append(b, size[:]...)
+	b = append(b, value...)
+	return append(b, '\n')
+}
+
+type journaldLogCreator struct {
+}
+
+func (c *journaldLogCreator) CreateLogger(config LogConfig) (io.Writer, error) {
+	j := &journaldLog{}
+	j.dial = func() (net.Conn, error) {
+		return net.Dial("unixgram", journalSocket)
+	}
+	if err := j.connect(); err != nil {
+		return nil, err
+	}
+	return j, nil
+}
+
+func init() {
+	registerLogger(LogTargetJournald, &journaldLogCreator{})
+}
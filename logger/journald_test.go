@@ -0,0 +1,54 @@
+// +build linux
+
+package logger
+
+import (
+	"encoding/binary"
+	"io/ioutil"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestJournald(t *testing.T) {
+	dir, err := ioutil.TempDir("", "journald")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	socket := filepath.Join(dir, "socket")
+	conn, err := net.ListenPacket("unixgram", socket)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	defer func(path string) { journalSocket = path }(journalSocket)
+	journalSocket = socket
+
+	SetupLogging(LogConfig{LogTarget: LogTargetJournald})
+	defer SetupLogging(LogConfig{})
+	_, ok := actualLogger.(*journaldLog)
+	require.True(t, ok)
+
+	log.Printf("D! [agent] ignored")
+	log.Printf("W! [outputs.file] line one\nline two")
+
+	buf := make([]byte, 1024)
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+	n, _, err := conn.ReadFrom(buf)
+	require.NoError(t, err)
+
+	message := "line one\nline two"
+	size := make([]byte, 8)
+	binary.LittleEndian.PutUint64(size, uint64(len(message)))
+
+	expected := "MESSAGE\n" + string(size) + message + "\n" +
+		"PRIORITY=4\n" +
+		"SYSLOG_IDENTIFIER=telegraf\n" +
+		"TELEGRAF_PLUGIN_TYPE=outputs\n" +
+		"TELEGRAF_PLUGIN=file\n"
+	require.Equal(t, expected, string(buf[:n]))
+}
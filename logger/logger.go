@@ -6,10 +6,13 @@ import (
 	"log"
 	"os"
 	"regexp"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/influxdata/telegraf/internal"
 	"github.com/influxdata/telegraf/internal/rotate"
+	"github.com/influxdata/telegraf/internal/syslog"
 	"github.com/influxdata/wlog"
 )
 
@@ -18,6 +21,9 @@ var prefixRegex = regexp.MustCompile("^[DIWE]!")
 const (
 	LogTargetFile   = "file"
 	LogTargetStderr = "stderr"
+
+	LogFormatText = "text"
+	LogFormatJSON = "json"
 )
 
 // LogConfig contains the log configuration settings
@@ -26,8 +32,10 @@ type LogConfig struct {
 	Debug bool
 	//will set the log level to ERROR
 	Quiet bool
-	//stderr, stdout, file or eventlog (Windows only)
+	//stderr, stdout, file, syslog, journald or eventlog (Windows only)
 	LogTarget string
+	// text or json, the format of the messages written to stderr or a file
+	LogFormat string
 	// will direct the logging output to a file. Empty string is
 	// interpreted as stderr. If there is an error opening the file the
 	// logger will fallback to stderr
@@ -38,6 +46,15 @@ type LogConfig struct {
 	RotationMaxSize internal.Size
 	// maximum rotated files to keep (older ones will be deleted)
 	RotationMaxArchives int
+	// maximum total size of the rotated files (older ones will be deleted)
+	RotationMaxTotalSize internal.Size
+	// compression of the rotated files, gzip or zstd
+	RotationCompression string
+	// address of the syslog server, such as udp://127.0.0.1:514.  Empty
+	// string is interpreted as the local syslog socket.
+	SyslogAddress string
+	// framing of the syslog messages on tcp and unix stream sockets
+	SyslogFraming syslog.Framing
 }
 
 type LoggerCreator interface {
@@ -56,9 +73,19 @@ func registerLogger(name string, loggerCreator LoggerCreator) {
 type telegrafLog struct {
 	writer         io.Writer
 	internalWriter io.Writer
+	format         string
 }
 
 func (t *telegrafLog) Write(b []byte) (n int, err error) {
+	if t.format == LogFormatJSON {
+		e := parseEntry(b)
+		line, err := e.json(time.Now())
+		if err != nil {
+			return 0, err
+		}
+		return t.writer.Write(line)
+	}
+
 	var line []byte
 	if !prefixRegex.Match(b) {
 		line = append([]byte(time.Now().UTC().Format(time.RFC3339)+" I! "), b...)
@@ -83,10 +110,11 @@ func (t *telegrafLog) Close() error {
 }
 
 // newTelegrafWriter returns a logging-wrapped writer.
-func newTelegrafWriter(w io.Writer) io.Writer {
+func newTelegrafWriter(w io.Writer, format string) io.Writer {
 	return &telegrafLog{
-		writer:         wlog.NewWriter(w),
+		writer:         w,
 		internalWriter: w,
+		format:         format,
 	}
 }
 
@@ -106,7 +134,8 @@ func (t *telegrafLogCreator) CreateLogger(config LogConfig) (io.Writer, error) {
 	case LogTargetFile:
 		if config.Logfile != "" {
 			var err error
-			if writer, err = rotate.NewFileWriter(config.Logfile, config.RotationInterval.Duration, config.RotationMaxSize.Size, config.RotationMaxArchives); err != nil {
+			if writer, err = rotate.NewFileWriter(config.Logfile, config.RotationInterval.Duration, config.RotationMaxSize.Size, config.RotationMaxArchives,
+				rotate.WithMaxTotalSize(config.RotationMaxTotalSize.Size), rotate.WithCompression(config.RotationCompression)); err != nil {
 				log.Printf("E! Unable to open %s (%s), using stderr", config.Logfile, err)
 				writer = defaultWriter
 			}
@@ -120,12 +149,39 @@ func (t *telegrafLogCreator) CreateLogger(config LogConfig) (io.Writer, error) {
 		writer = defaultWriter
 	}
 
-	return newTelegrafWriter(writer), nil
+	switch config.LogFormat {
+	case LogFormatText, LogFormatJSON, "":
+	default:
+		log.Printf("E! Unsupported logformat: %s, using text", config.LogFormat)
+	}
+
+	return newTelegrafWriter(writer, config.LogFormat), nil
 }
 
 // Keep track what is actually set as a log output, because log package doesn't provide a getter.
 // It allows closing previous writer if re-set and have possibility to test what is actually set
-var actualLogger io.Writer
+var (
+	actualLogger   io.Writer
+	actualLoggerMu sync.Mutex
+)
+
+// Print writes the message, starting with its level prefix, to the log
+// output without applying the global log level.  It is used by loggers
+// filtering their messages by a level of their own.
+func Print(message string) {
+	if !strings.HasSuffix(message, "\n") {
+		message += "\n"
+	}
+
+	actualLoggerMu.Lock()
+	defer actualLoggerMu.Unlock()
+
+	w := actualLogger
+	if w == nil {
+		w = os.Stderr
+	}
+	w.Write([]byte(message))
+}
 
 func newLogWriter(config LogConfig) io.Writer {
 	log.SetFlags(0)
@@ -139,18 +195,29 @@ func newLogWriter(config LogConfig) io.Writer {
 		wlog.SetLevel(wlog.INFO)
 	}
 	var logWriter io.Writer
+	var err error
 	if logCreator, ok := loggerRegistry[config.LogTarget]; ok {
-		logWriter, _ = logCreator.CreateLogger(config)
+		logWriter, err = logCreator.CreateLogger(config)
 	}
 	if logWriter == nil {
-		logWriter, _ = (&telegrafLogCreator{}).CreateLogger(config)
+		fallback := config
+		if err != nil {
+			fallback.LogTarget = LogTargetStderr
+		}
+		logWriter, _ = (&telegrafLogCreator{}).CreateLogger(fallback)
 	}
 
+	actualLoggerMu.Lock()
 	if closer, isCloser := actualLogger.(io.Closer); isCloser {
 		closer.Close()
 	}
-	log.SetOutput(logWriter)
+	log.SetOutput(&levelWriter{w: logWriter})
 	actualLogger = logWriter
+	actualLoggerMu.Unlock()
+
+	if err != nil {
+		log.Printf("E! Unable to log to %s (%s), using stderr", config.LogTarget, err)
+	}
 
 	return logWriter
 }
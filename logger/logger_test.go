@@ -2,6 +2,7 @@ package logger
 
 import (
 	"bytes"
+	"encoding/json"
 	"io"
 	"io/ioutil"
 	"log"
@@ -134,10 +135,70 @@ func TestLogTargetSettings(t *testing.T) {
 	assert.Equal(t, logger.internalWriter, os.Stderr)
 }
 
+func TestWriteJSONLogToFile(t *testing.T) {
+	tmpfile, err := ioutil.TempFile("", "")
+	require.NoError(t, err)
+	defer func() { os.Remove(tmpfile.Name()) }()
+
+	config := createBasicLogConfig(tmpfile.Name())
+	config.LogFormat = LogFormatJSON
+	SetupLogging(config)
+	log.Printf("E! [inputs.cpu::local] gather failed")
+	log.Printf("I! [agent] Starting Telegraf")
+	log.Printf("D! [agent] ignored")
+
+	f, err := ioutil.ReadFile(tmpfile.Name())
+	require.NoError(t, err)
+
+	lines := bytes.Split(bytes.TrimSpace(f), []byte("\n"))
+	require.Len(t, lines, 2)
+
+	var entry map[string]string
+	require.NoError(t, json.Unmarshal(lines[0], &entry))
+	require.NotEmpty(t, entry["time"])
+	delete(entry, "time")
+	require.Equal(t, map[string]string{
+		"level":  "error",
+		"type":   "inputs",
+		"plugin": "cpu",
+		"alias":  "local",
+		"msg":    "gather failed",
+	}, entry)
+
+	entry = nil
+	require.NoError(t, json.Unmarshal(lines[1], &entry))
+	delete(entry, "time")
+	require.Equal(t, map[string]string{
+		"level":  "info",
+		"source": "agent",
+		"msg":    "Starting Telegraf",
+	}, entry)
+}
+
+func TestPrint(t *testing.T) {
+	tmpfile, err := ioutil.TempFile("", "")
+	require.NoError(t, err)
+	defer func() { os.Remove(tmpfile.Name()) }()
+
+	config := createBasicLogConfig(tmpfile.Name())
+	SetupLogging(config)
+	log.Printf("D! [inputs.disk] ignored")
+	Print("D! [inputs.cpu] debug")
+	Print("E! [inputs.mem] error\n")
+
+	f, err := ioutil.ReadFile(tmpfile.Name())
+	require.NoError(t, err)
+
+	lines := bytes.Split(bytes.TrimSpace(f), []byte("\n"))
+	require.Len(t, lines, 2)
+	require.Equal(t, []byte("Z D! [inputs.cpu] debug"), lines[0][19:])
+	require.Equal(t, []byte("Z E! [inputs.mem] error"), lines[1][19:])
+}
+
 func BenchmarkTelegrafLogWrite(b *testing.B) {
 	var msg = []byte("test")
 	var buf bytes.Buffer
-	w := newTelegrafWriter(&buf)
+	w := newTelegrafWriter(&buf, LogFormatText)
 	for i := 0; i < b.N; i++ {
 		buf.Reset()
 		w.Write(msg)
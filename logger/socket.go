@@ -0,0 +1,126 @@
+package logger
+
+import (
+	"errors"
+	"net"
+	"sync"
+	"time"
+)
+
+const (
+	// Maximum time a message may take to be written to the socket.
+	defaultSocketWriteTimeout = 5 * time.Second
+	// Time between attempts to connect the socket again.
+	defaultSocketRetryInterval = time.Second
+)
+
+var errSocketReconnecting = errors.New("socket is reconnecting, message dropped")
+
+// socketWriter writes messages to a socket of a local or remote logging
+// service.  The socket is connected again in the background if a write
+// fails, as the service may have been restarted.  Messages are dropped
+// until the socket is connected, so that logging never blocks for long.
+type socketWriter struct {
+	dial          func() (net.Conn, error)
+	timeout       time.Duration
+	retryInterval time.Duration
+
+	mu           sync.Mutex
+	conn         net.Conn
+	reconnecting bool
+	done         chan struct{}
+}
+
+// connect connects the socket, it is called once when the writer is
+// created.
+func (w *socketWriter) connect() error {
+	conn, err := w.dial()
+	if err != nil {
+		return err
+	}
+
+	w.mu.Lock()
+	w.conn = conn
+	w.done = make(chan struct{})
+	w.mu.Unlock()
+	return nil
+}
+
+func (w *socketWriter) write(msg []byte) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.conn == nil {
+		return errSocketReconnecting
+	}
+
+	timeout := w.timeout
+	if timeout <= 0 {
+		timeout = defaultSocketWriteTimeout
+	}
+	err := w.conn.SetWriteDeadline(time.Now().Add(timeout))
+	if err == nil {
+		_, err = w.conn.Write(msg)
+	}
+	if err == nil {
+		return nil
+	}
+
+	w.conn.Close()
+	w.conn = nil
+	if !w.reconnecting {
+		w.reconnecting = true
+		go w.reconnect(w.done)
+	}
+	return err
+}
+
+// reconnect dials the socket until it is connected or the writer is closed.
+func (w *socketWriter) reconnect(done chan struct{}) {
+	interval := w.retryInterval
+	if interval <= 0 {
+		interval = defaultSocketRetryInterval
+	}
+
+	for {
+		conn, err := w.dial()
+		if err == nil {
+			w.mu.Lock()
+			defer w.mu.Unlock()
+
+			w.reconnecting = false
+			select {
+			case <-done:
+				conn.Close()
+			default:
+				w.conn = conn
+			}
+			return
+		}
+
+		select {
+		case <-done:
+			w.mu.Lock()
+			w.reconnecting = false
+			w.mu.Unlock()
+			return
+		case <-time.After(interval):
+		}
+	}
+}
+
+func (w *socketWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.done != nil {
+		close(w.done)
+		w.done = nil
+	}
+	if w.conn == nil {
+		return nil
+	}
+	err := w.conn.Close()
+	w.conn = nil
+	return err
+}
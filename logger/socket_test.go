@@ -0,0 +1,71 @@
+package logger
+
+import (
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSocketWriterReconnect(t *testing.T) {
+	var failing int32
+	peers := make(chan net.Conn, 10)
+	w := &socketWriter{
+		dial: func() (net.Conn, error) {
+			if atomic.LoadInt32(&failing) == 1 {
+				return nil, errSocketReconnecting
+			}
+			client, server := net.Pipe()
+			peers <- server
+			return client, nil
+		},
+		timeout:       50 * time.Millisecond,
+		retryInterval: 10 * time.Millisecond,
+	}
+	require.NoError(t, w.connect())
+	defer w.Close()
+	first := <-peers
+	defer first.Close()
+
+	// Nobody reads from the socket, so the write gives up after the
+	// timeout instead of blocking.
+	atomic.StoreInt32(&failing, 1)
+	start := time.Now()
+	require.Error(t, w.write([]byte("blocked")))
+	require.True(t, time.Since(start) < time.Second)
+
+	// Messages are dropped while the socket is reconnecting.
+	start = time.Now()
+	require.Equal(t, errSocketReconnecting, w.write([]byte("dropped")))
+	require.True(t, time.Since(start) < time.Second)
+
+	atomic.StoreInt32(&failing, 0)
+	var second net.Conn
+	select {
+	case second = <-peers:
+	case <-time.After(5 * time.Second):
+		t.Fatal("socket was not connected again")
+	}
+	defer second.Close()
+
+	for i := 0; i < 100; i++ {
+		w.mu.Lock()
+		connected := w.conn != nil
+		w.mu.Unlock()
+		if connected {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	received := make(chan string, 1)
+	go func() {
+		buf := make([]byte, 64)
+		n, _ := second.Read(buf)
+		received <- string(buf[:n])
+	}()
+	require.NoError(t, w.write([]byte("hello")))
+	require.Equal(t, "hello", <-received)
+}
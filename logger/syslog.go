@@ -0,0 +1,163 @@
+package logger
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf/internal/syslog"
+)
+
+const (
+	LogTargetSyslog = "syslog"
+)
+
+const (
+	// syslogFacility is the facility of the messages, "daemon".
+	syslogFacility = 3
+
+	// syslogSDID is the id of the structured data element holding the
+	// plugin of the message.
+	syslogSDID = "telegraf@32473"
+
+	// rfc5424Time is the timestamp format of RFC5424, which allows at most
+	// microseconds.
+	rfc5424Time = "2006-01-02T15:04:05.000000Z07:00"
+)
+
+// syslogSockets are the local syslog sockets tried when no address is set.
+var syslogSockets = []string{"/dev/log", "/var/run/syslog", "/var/run/log"}
+
+// syslogLog writes RFC5424 messages to a syslog server.
+type syslogLog struct {
+	socketWriter
+
+	// framing is used on stream sockets, datagrams hold a single message.
+	framing  syslog.Framing
+	stream   bool
+	hostname string
+	pid      int
+}
+
+func (s *syslogLog) Write(b []byte) (int, error) {
+	e := parseEntry(b)
+
+	if err := s.write(s.format(&e, time.Now())); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+// format returns the message in the RFC5424 format, the plugin type, name
+// and alias or the source of the message are set as structured data.
+func (s *syslogLog) format(e *entry, t time.Time) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "<%d>1 %s %s telegraf %d - ",
+		syslogFacility*8+e.severity(), t.Format(rfc5424Time), s.hostname, s.pid)
+
+	if typ, name, alias, ok := e.plugin(); ok {
+		b.WriteString("[" + syslogSDID)
+		writeSDParam(&b, "type", typ)
+		writeSDParam(&b, "plugin", name)
+		if alias != "" {
+			writeSDParam(&b, "alias", alias)
+		}
+		b.WriteString("]")
+	} else if e.source != "" {
+		b.WriteString("[" + syslogSDID)
+		writeSDParam(&b, "source", e.source)
+		b.WriteString("]")
+	} else {
+		b.WriteString("-")
+	}
+
+	if e.message != "" {
+		b.WriteString(" ")
+		b.WriteString(e.message)
+	}
+
+	if !s.stream {
+		return b.Bytes()
+	}
+	if s.framing == syslog.NonTransparent {
+		b.WriteString("\n")
+		return b.Bytes()
+	}
+	return append([]byte(strconv.Itoa(b.Len())+" "), b.Bytes()...)
+}
+
+// writeSDParam writes a structured data parameter, escaping the characters
+// reserved by RFC5424 in the value.
+func writeSDParam(b *bytes.Buffer, name, value string) {
+	b.WriteString(" " + name + `="`)
+	for _, c := range value {
+		if c == '"' || c == '\\' || c == ']' {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(c)
+	}
+	b.WriteString(`"`)
+}
+
+type syslogLogCreator struct {
+}
+
+// CreateLogger connects to the syslog server at the address of the config,
+// such as "udp://127.0.0.1:514" or "unix:///dev/log".  The local syslog
+// sockets are tried if no address is set.
+func (c *syslogLogCreator) CreateLogger(config LogConfig) (io.Writer, error) {
+	s := &syslogLog{
+		framing:  config.SyslogFraming,
+		hostname: "-",
+		pid:      os.Getpid(),
+	}
+	if hostname, err := os.Hostname(); err == nil && hostname != "" {
+		s.hostname = hostname
+	}
+
+	if config.SyslogAddress == "" {
+		s.dial = dialLocalSyslog
+	} else {
+		parts := strings.SplitN(config.SyslogAddress, "://", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid syslog address %q", config.SyslogAddress)
+		}
+		network, address := parts[0], parts[1]
+		switch network {
+		case "tcp", "tcp4", "tcp6", "unix":
+			s.stream = true
+		case "udp", "udp4", "udp6", "unixgram":
+		default:
+			return nil, fmt.Errorf("unsupported syslog network %q", network)
+		}
+		s.dial = func() (net.Conn, error) {
+			return net.DialTimeout(network, address, 5*time.Second)
+		}
+	}
+
+	if err := s.connect(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// dialLocalSyslog connects to the first local syslog socket found.  Local
+// syslog daemons listen on datagram sockets.
+func dialLocalSyslog() (net.Conn, error) {
+	for _, path := range syslogSockets {
+		conn, err := net.Dial("unixgram", path)
+		if err == nil {
+			return conn, nil
+		}
+	}
+	return nil, fmt.Errorf("no local syslog socket found in %s", strings.Join(syslogSockets, ", "))
+}
+
+func init() {
+	registerLogger(LogTargetSyslog, &syslogLogCreator{})
+}
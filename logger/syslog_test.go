@@ -0,0 +1,114 @@
+package logger
+
+import (
+	"bufio"
+	"log"
+	"net"
+	"os"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf/internal/syslog"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSyslogUDP(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer conn.Close()
+
+	SetupLogging(LogConfig{
+		LogTarget:     LogTargetSyslog,
+		SyslogAddress: "udp://" + conn.LocalAddr().String(),
+	})
+	defer SetupLogging(LogConfig{})
+	_, ok := actualLogger.(*syslogLog)
+	require.True(t, ok)
+
+	log.Printf(`E! [inputs.cpu::local] gather "failed"`)
+	log.Printf("D! [agent] ignored")
+	log.Printf("I! [agent] Starting Telegraf")
+
+	buf := make([]byte, 1024)
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+
+	n, _, err := conn.ReadFrom(buf)
+	require.NoError(t, err)
+	require.Regexp(t,
+		regexp.MustCompile(`^<27>1 \S+ \S+ telegraf \d+ - \[telegraf@32473 type="inputs" plugin="cpu" alias="local"\] gather "failed"$`),
+		string(buf[:n]))
+
+	n, _, err = conn.ReadFrom(buf)
+	require.NoError(t, err)
+	require.Regexp(t,
+		regexp.MustCompile(`^<30>1 \S+ \S+ telegraf \d+ - \[telegraf@32473 source="agent"\] Starting Telegraf$`),
+		string(buf[:n]))
+}
+
+func TestSyslogTCPFraming(t *testing.T) {
+	tests := []struct {
+		name     string
+		framing  syslog.Framing
+		expected *regexp.Regexp
+	}{
+		{
+			name:     "octet counting",
+			framing:  syslog.OctetCounting,
+			expected: regexp.MustCompile(`^\d+ <30>1 \S+ \S+ telegraf \d+ - - hello\n?$`),
+		},
+		{
+			name:     "non-transparent",
+			framing:  syslog.NonTransparent,
+			expected: regexp.MustCompile(`^<30>1 \S+ \S+ telegraf \d+ - - hello\n$`),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			listener, err := net.Listen("tcp", "127.0.0.1:0")
+			require.NoError(t, err)
+			defer listener.Close()
+
+			w, err := (&syslogLogCreator{}).CreateLogger(LogConfig{
+				SyslogAddress: "tcp://" + listener.Addr().String(),
+				SyslogFraming: tt.framing,
+			})
+			require.NoError(t, err)
+			defer w.(*syslogLog).Close()
+
+			conn, err := listener.Accept()
+			require.NoError(t, err)
+			defer conn.Close()
+
+			_, err = w.Write([]byte("hello\n"))
+			require.NoError(t, err)
+			w.(*syslogLog).Close()
+
+			require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+			line, err := bufio.NewReader(conn).ReadString(0)
+			require.Error(t, err)
+			require.Regexp(t, tt.expected, line)
+		})
+	}
+}
+
+func TestSyslogInvalidAddress(t *testing.T) {
+	_, err := (&syslogLogCreator{}).CreateLogger(LogConfig{SyslogAddress: "127.0.0.1:514"})
+	require.Error(t, err)
+
+	_, err = (&syslogLogCreator{}).CreateLogger(LogConfig{SyslogAddress: "http://127.0.0.1:514"})
+	require.Error(t, err)
+}
+
+func TestSyslogFallback(t *testing.T) {
+	SetupLogging(LogConfig{
+		LogTarget:     LogTargetSyslog,
+		SyslogAddress: "ftp://127.0.0.1:514",
+	})
+	defer SetupLogging(LogConfig{})
+
+	logger, ok := actualLogger.(*telegrafLog)
+	require.True(t, ok)
+	require.Equal(t, os.Stderr, logger.internalWriter)
+}
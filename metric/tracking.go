@@ -69,6 +69,19 @@ func (d *trackingData) reject() {
 	atomic.AddInt32(&d.rejectCount, 1)
 }
 
+// release drops a reference to the tracking data, notifying once the last
+// reference is dropped.
+func (d *trackingData) release() {
+	v := d.decr()
+	if v < 0 {
+		panic("negative refcount")
+	}
+
+	if v == 0 {
+		d.notify()
+	}
+}
+
 func (d *trackingData) notify() {
 	d.notifyFunc(
 		&deliveryInfo{
@@ -154,14 +167,62 @@ func (m *trackingMetric) Drop() {
 }
 
 func (m *trackingMetric) decr() {
-	v := m.d.decr()
-	if v < 0 {
-		panic("negative refcount")
+	m.d.release()
+}
+
+// DeliveryGroup collects the deliveries of tracking metrics combined into
+// new metrics, such as by an aggregator, so that the tracking metrics are
+// only delivered once the new metrics are.
+type DeliveryGroup struct {
+	sources map[*trackingData]bool
+}
+
+// Add keeps the delivery of the metric pending until the metrics passed to
+// the next call of Track are delivered.  Metrics without tracking are
+// ignored.  The metric itself must still be accepted, rejected or dropped.
+func (g *DeliveryGroup) Add(m telegraf.Metric) {
+	tm, ok := m.(*trackingMetric)
+	if !ok {
+		return
 	}
 
-	if v == 0 {
-		m.d.notify()
+	if g.sources == nil {
+		g.sources = make(map[*trackingData]bool)
+	}
+	if !g.sources[tm.d] {
+		tm.d.incr()
+		g.sources[tm.d] = true
+	}
+}
+
+// Len returns the number of pending deliveries.
+func (g *DeliveryGroup) Len() int {
+	return len(g.sources)
+}
+
+// Track adds tracking to the metrics and moves the pending deliveries to
+// them.  Once all the metrics are processed the pending deliveries are
+// accepted, or rejected if any metric was rejected.  If there are no
+// metrics the pending deliveries are accepted immediately.
+func (g *DeliveryGroup) Track(metrics []telegraf.Metric) []telegraf.Metric {
+	if len(g.sources) == 0 {
+		return metrics
 	}
+
+	sources := g.sources
+	g.sources = nil
+
+	metrics, _ = newTrackingMetricGroup(metrics, func(info telegraf.DeliveryInfo) {
+		for d := range sources {
+			if info.Delivered() {
+				d.accept()
+			} else {
+				d.reject()
+			}
+			d.release()
+		}
+	})
+	return metrics
 }
 
 type deliveryInfo struct {
@@ -258,3 +258,73 @@ func TestGroupTracking(t *testing.T) {
 		})
 	}
 }
+
+func TestDeliveryGroup(t *testing.T) {
+	tests := []struct {
+		name      string
+		metrics   []telegraf.Metric
+		actions   func(metrics []telegraf.Metric)
+		delivered bool
+	}{
+		{
+			name: "accept",
+			metrics: []telegraf.Metric{
+				mustMetric("agg", map[string]string{}, map[string]interface{}{"value": 1}, time.Unix(0, 0)),
+				mustMetric("agg", map[string]string{}, map[string]interface{}{"value": 2}, time.Unix(0, 0)),
+			},
+			actions: func(metrics []telegraf.Metric) {
+				metrics[0].Accept()
+				metrics[1].Accept()
+			},
+			delivered: true,
+		},
+		{
+			name: "reject",
+			metrics: []telegraf.Metric{
+				mustMetric("agg", map[string]string{}, map[string]interface{}{"value": 1}, time.Unix(0, 0)),
+				mustMetric("agg", map[string]string{}, map[string]interface{}{"value": 2}, time.Unix(0, 0)),
+			},
+			actions: func(metrics []telegraf.Metric) {
+				metrics[0].Accept()
+				metrics[1].Reject()
+			},
+			delivered: false,
+		},
+		{
+			name:      "no metrics",
+			actions:   func(metrics []telegraf.Metric) {},
+			delivered: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := &deliveries{
+				Info: make(map[telegraf.TrackingID]telegraf.DeliveryInfo),
+			}
+
+			source, id := WithTracking(mustMetric(
+				"cpu",
+				map[string]string{},
+				map[string]interface{}{
+					"value": 42,
+				},
+				time.Unix(0, 0),
+			), d.onDelivery)
+
+			var group DeliveryGroup
+			group.Add(source)
+			group.Add(source)
+			require.Equal(t, 1, group.Len())
+
+			// The source is only delivered once the tracked metrics are.
+			source.Drop()
+			require.Empty(t, d.Info)
+
+			metrics := group.Track(tt.metrics)
+			require.Equal(t, 0, group.Len())
+
+			tt.actions(metrics)
+			require.Equal(t, tt.delivered, d.Info[id].Delivered())
+		})
+	}
+}
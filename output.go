@@ -1,5 +1,7 @@
 package telegraf
 
+import "context"
+
 type Output interface {
 	// Connect to the Output
 	Connect() error
@@ -13,6 +15,28 @@ type Output interface {
 	Write(metrics []Metric) error
 }
 
+// ContextConnector is an Output that can stop connecting early.  When
+// implemented ConnectContext is called instead of Connect, and the context is
+// cancelled when the agent stops.
+type ContextConnector interface {
+	Output
+
+	// ConnectContext connects to the Output, returning early when the
+	// context is done.
+	ConnectContext(ctx context.Context) error
+}
+
+// ContextWriter is an Output that can stop writing early.  When implemented
+// WriteContext is called instead of Write, and the context is cancelled when
+// the output is stopped by a shutdown or reload.
+type ContextWriter interface {
+	Output
+
+	// WriteContext writes the metrics to the Output, returning early when
+	// the context is done.
+	WriteContext(ctx context.Context, metrics []Metric) error
+}
+
 // AggregatingOutput adds aggregating functionality to an Output.  May be used
 // if the Output only accepts a fixed set of aggregations over a time period.
 // These functions may be called concurrently to the Write function.
@@ -26,3 +50,38 @@ type AggregatingOutput interface {
 	// Reset signals the the aggregator period is completed.
 	Reset()
 }
+
+// PermanentError is returned by Output.Write when the metrics can never be
+// written, for example when they are rejected by the server as invalid.  The
+// metrics are not retried and are sent to the dead letter output, if any.
+type PermanentError struct {
+	Err error
+}
+
+// NewPermanentError marks err as a permanent write error.
+func NewPermanentError(err error) error {
+	return &PermanentError{Err: err}
+}
+
+func (e *PermanentError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *PermanentError) Unwrap() error {
+	return e.Err
+}
+
+// IsPermanentError returns true if err is, or wraps, a PermanentError.
+func IsPermanentError(err error) bool {
+	for err != nil {
+		if _, ok := err.(*PermanentError); ok {
+			return true
+		}
+		wrapper, ok := err.(interface{ Unwrap() error })
+		if !ok {
+			return false
+		}
+		err = wrapper.Unwrap()
+	}
+	return false
+}
@@ -169,3 +169,33 @@ func TestPrometheusGeneratesGaugeMetricsV2(t *testing.T) {
 	assert.True(t, acc.TagValue("prometheus", "url") == ts.URL+"/metrics")
 	assert.True(t, acc.HasTimestamp("prometheus", time.Unix(1490802350, 0)))
 }
+
+const sampleOpenMetricsFormat = `# TYPE http_requests counter
+http_requests_total{code="200"} 1027 # {trace_id="KOO5S4vxi0o"} 1
+http_requests_created{code="200"} 1490802350.0
+# EOF
+`
+
+func TestPrometheusGeneratesOpenMetrics(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/openmetrics-text; version=1.0.0; charset=utf-8")
+		fmt.Fprint(w, sampleOpenMetricsFormat)
+	}))
+	defer ts.Close()
+
+	p := &Prometheus{
+		Log:    testutil.Logger{},
+		URLs:   []string{ts.URL},
+		URLTag: "url",
+	}
+
+	var acc testutil.Accumulator
+
+	err := acc.GatherError(p.Gather)
+	require.NoError(t, err)
+
+	assert.True(t, acc.HasFloatField("http_requests_total", "counter"))
+	assert.True(t, acc.HasFloatField("http_requests_total", "created"))
+	assert.True(t, acc.HasFloatField("http_requests_total", "exemplar"))
+	assert.True(t, acc.TagValue("http_requests_total", "code") == "200")
+}
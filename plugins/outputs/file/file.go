@@ -14,10 +14,12 @@ import (
 )
 
 type File struct {
-	Files               []string          `toml:"files"`
-	RotationInterval    internal.Duration `toml:"rotation_interval"`
-	RotationMaxSize     internal.Size     `toml:"rotation_max_size"`
-	RotationMaxArchives int               `toml:"rotation_max_archives"`
+	Files                []string          `toml:"files"`
+	RotationInterval     internal.Duration `toml:"rotation_interval"`
+	RotationMaxSize      internal.Size     `toml:"rotation_max_size"`
+	RotationMaxArchives  int               `toml:"rotation_max_archives"`
+	RotationMaxTotalSize internal.Size     `toml:"rotation_max_total_size"`
+	RotationCompression  string            `toml:"rotation_compression"`
 
 	writer     io.Writer
 	closers    []io.Closer
@@ -40,6 +42,15 @@ var sampleConfig = `
   ## If set to -1, no archives are removed.
   # rotation_max_archives = 5
 
+  ## The oldest rotated archives are deleted when their total size becomes
+  ## larger than the specified size.  When set to 0 the total size is not
+  ## limited.
+  # rotation_max_total_size = "0MB"
+
+  ## Compression of the rotated archives, either "gzip" or "zstd".  Archives
+  ## are compressed in the background after rotation.
+  # rotation_compression = ""
+
   ## Data format to output.
   ## Each data format has its own unique set of configuration options, read
   ## more about them here:
@@ -63,7 +74,9 @@ func (f *File) Connect() error {
 			writers = append(writers, os.Stdout)
 		} else {
 			of, err := rotate.NewFileWriter(
-				file, f.RotationInterval.Duration, f.RotationMaxSize.Size, f.RotationMaxArchives)
+				file, f.RotationInterval.Duration, f.RotationMaxSize.Size, f.RotationMaxArchives,
+				rotate.WithMaxTotalSize(f.RotationMaxTotalSize.Size),
+				rotate.WithCompression(f.RotationCompression))
 			if err != nil {
 				return err
 			}
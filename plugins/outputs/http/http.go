@@ -159,19 +159,25 @@ func (h *HTTP) SampleConfig() string {
 }
 
 func (h *HTTP) Write(metrics []telegraf.Metric) error {
+	return h.WriteContext(context.Background(), metrics)
+}
+
+// WriteContext is Write with a request that is cancelled when the context is
+// done.
+func (h *HTTP) WriteContext(ctx context.Context, metrics []telegraf.Metric) error {
 	reqBody, err := h.serializer.SerializeBatch(metrics)
 	if err != nil {
 		return err
 	}
 
-	if err := h.write(reqBody); err != nil {
+	if err := h.write(ctx, reqBody); err != nil {
 		return err
 	}
 
 	return nil
 }
 
-func (h *HTTP) write(reqBody []byte) error {
+func (h *HTTP) write(ctx context.Context, reqBody []byte) error {
 	var reqBodyBuffer io.Reader = bytes.NewBuffer(reqBody)
 
 	var err error
@@ -188,6 +194,7 @@ func (h *HTTP) write(reqBody []byte) error {
 	if err != nil {
 		return err
 	}
+	req = req.WithContext(ctx)
 
 	if h.Username != "" || h.Password != "" {
 		req.SetBasicAuth(h.Username, h.Password)
@@ -213,7 +220,15 @@ func (h *HTTP) write(reqBody []byte) error {
 	_, err = ioutil.ReadAll(resp.Body)
 
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return fmt.Errorf("when writing to [%s] received status code: %d", h.URL, resp.StatusCode)
+		err := fmt.Errorf("when writing to [%s] received status code: %d", h.URL, resp.StatusCode)
+		// Client errors are not going away by retrying the same request,
+		// except when the server asks us to slow down.
+		if resp.StatusCode >= 400 && resp.StatusCode < 500 &&
+			resp.StatusCode != http.StatusRequestTimeout &&
+			resp.StatusCode != http.StatusTooManyRequests {
+			return telegraf.NewPermanentError(err)
+		}
+		return err
 	}
 
 	return nil
@@ -2,6 +2,7 @@ package http
 
 import (
 	"compress/gzip"
+	"context"
 	"fmt"
 	"io/ioutil"
 	"net/http"
@@ -152,16 +153,40 @@ func TestStatusCode(t *testing.T) {
 			statusCode: http.StatusMultipleChoices,
 			errFunc: func(t *testing.T, err error) {
 				require.Error(t, err)
+				require.False(t, telegraf.IsPermanentError(err))
 			},
 		},
 		{
-			name: "4xx status is an error",
+			name: "4xx status is a permanent error",
 			plugin: &HTTP{
 				URL: u.String(),
 			},
-			statusCode: http.StatusMultipleChoices,
+			statusCode: http.StatusBadRequest,
+			errFunc: func(t *testing.T, err error) {
+				require.Error(t, err)
+				require.True(t, telegraf.IsPermanentError(err))
+			},
+		},
+		{
+			name: "too many requests is retried",
+			plugin: &HTTP{
+				URL: u.String(),
+			},
+			statusCode: http.StatusTooManyRequests,
+			errFunc: func(t *testing.T, err error) {
+				require.Error(t, err)
+				require.False(t, telegraf.IsPermanentError(err))
+			},
+		},
+		{
+			name: "5xx status is retried",
+			plugin: &HTTP{
+				URL: u.String(),
+			},
+			statusCode: http.StatusServiceUnavailable,
 			errFunc: func(t *testing.T, err error) {
 				require.Error(t, err)
+				require.False(t, telegraf.IsPermanentError(err))
 			},
 		},
 	}
@@ -453,3 +478,26 @@ func TestDefaultUserAgent(t *testing.T) {
 		require.NoError(t, err)
 	})
 }
+
+func TestWriteContextCancelled(t *testing.T) {
+	done := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-done
+	}))
+	defer ts.Close()
+	defer close(done)
+
+	plugin := &HTTP{
+		URL: ts.URL,
+	}
+	serializer := influx.NewSerializer()
+	plugin.SetSerializer(serializer)
+	require.NoError(t, plugin.Connect())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	err := plugin.WriteContext(ctx, []telegraf.Metric{getMetric()})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), context.DeadlineExceeded.Error())
+}
@@ -132,8 +132,12 @@ var sampleConfig = `
 `
 
 func (i *InfluxDB) Connect() error {
-	ctx := context.Background()
+	return i.ConnectContext(context.Background())
+}
 
+// ConnectContext is Connect with the creation of the databases cancelled when
+// the context is done.
+func (i *InfluxDB) ConnectContext(ctx context.Context) error {
 	urls := make([]string, 0, len(i.URLs))
 	urls = append(urls, i.URLs...)
 	if i.URL != "" {
@@ -199,8 +203,12 @@ func (i *InfluxDB) SampleConfig() string {
 // Write sends metrics to one of the configured servers, logging each
 // unsuccessful. If all servers fail, return an error.
 func (i *InfluxDB) Write(metrics []telegraf.Metric) error {
-	ctx := context.Background()
+	return i.WriteContext(context.Background(), metrics)
+}
 
+// WriteContext is Write with requests that are cancelled when the context is
+// done.
+func (i *InfluxDB) WriteContext(ctx context.Context, metrics []telegraf.Metric) error {
 	var err error
 	p := rand.Perm(len(i.clients))
 	for _, n := range p {
@@ -215,3 +215,49 @@ func TestWriteRecreateDatabaseIfDatabaseNotFound(t *testing.T) {
 	// We only have one URL, so we expect an error
 	require.Error(t, err)
 }
+
+func TestContextPassedToClient(t *testing.T) {
+	type key struct{}
+	ctx := context.WithValue(context.Background(), key{}, "value")
+
+	var createCtx, writeCtx context.Context
+	output := influxdb.InfluxDB{
+		URLs: []string{"http://localhost:8086"},
+		CreateHTTPClientF: func(config *influxdb.HTTPConfig) (influxdb.Client, error) {
+			return &MockClient{
+				DatabaseF: func() string {
+					return "telegraf"
+				},
+				CreateDatabaseF: func(ctx context.Context, database string) error {
+					createCtx = ctx
+					return nil
+				},
+				WriteF: func(ctx context.Context, metrics []telegraf.Metric) error {
+					writeCtx = ctx
+					return nil
+				},
+				URLF: func() string {
+					return "http://localhost:8086"
+				},
+			}, nil
+		},
+	}
+
+	output.Log = testutil.Logger{}
+
+	require.NoError(t, output.ConnectContext(ctx))
+	require.Equal(t, "value", createCtx.Value(key{}))
+
+	m, err := metric.New(
+		"cpu",
+		map[string]string{},
+		map[string]interface{}{
+			"value": 42.0,
+		},
+		time.Unix(0, 0),
+	)
+	require.NoError(t, err)
+
+	require.NoError(t, output.WriteContext(ctx, []telegraf.Metric{m}))
+	require.Equal(t, "value", writeCtx.Value(key{}))
+}
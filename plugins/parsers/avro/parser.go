@@ -0,0 +1,328 @@
+package avro
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/parsers/internal/flat"
+	"github.com/linkedin/goavro"
+)
+
+// Config holds the options of the avro parser.
+type Config struct {
+	MetricName string
+	// SchemaFile is a local schema used for all messages.
+	SchemaFile string
+	// SchemaRegistry is the URL of a schema registry, messages are expected
+	// in the Confluent wire format with the id of their schema.
+	SchemaRegistry string
+
+	TagKeys         []string
+	FieldKeys       []string
+	TimestampKey    string
+	TimestampFormat string
+	Timezone        string
+	DefaultTags     map[string]string
+}
+
+// Parser decodes binary Avro messages.  Nested records are flattened, keys
+// are joined by "_".
+type Parser struct {
+	flat.MetricBuilder
+
+	schema   *schema
+	registry *schemaRegistry
+}
+
+// New returns a parser using either the schema file or the registry.
+func New(config *Config) (*Parser, error) {
+	p := &Parser{
+		MetricBuilder: flat.MetricBuilder{
+			MetricName:      config.MetricName,
+			TagKeys:         config.TagKeys,
+			FieldKeys:       config.FieldKeys,
+			TimestampKey:    config.TimestampKey,
+			TimestampFormat: config.TimestampFormat,
+			Timezone:        config.Timezone,
+			DefaultTags:     config.DefaultTags,
+			TimeFunc:        time.Now,
+		},
+	}
+	if p.TimestampFormat == "" {
+		p.TimestampFormat = "unix"
+	}
+
+	switch {
+	case config.SchemaFile != "" && config.SchemaRegistry != "":
+		return nil, fmt.Errorf("only one of avro_schema_file and avro_schema_registry can be set")
+	case config.SchemaFile != "":
+		b, err := ioutil.ReadFile(config.SchemaFile)
+		if err != nil {
+			return nil, err
+		}
+		p.schema, err = newSchema(string(b))
+		if err != nil {
+			return nil, err
+		}
+	case config.SchemaRegistry != "":
+		p.registry = newSchemaRegistry(config.SchemaRegistry)
+	default:
+		return nil, fmt.Errorf("avro_schema_file or avro_schema_registry must be set")
+	}
+	return p, nil
+}
+
+// Parse decodes a single message into a metric.
+func (p *Parser) Parse(buf []byte) ([]telegraf.Metric, error) {
+	s := p.schema
+	if p.registry != nil {
+		// The Confluent wire format prefixes the message with a zero byte
+		// and the schema id.
+		if len(buf) < 5 || buf[0] != 0 {
+			return nil, fmt.Errorf("message is not in the schema registry format")
+		}
+		var err error
+		s, err = p.registry.get(int(binary.BigEndian.Uint32(buf[1:5])))
+		if err != nil {
+			return nil, err
+		}
+		buf = buf[5:]
+	}
+
+	native, _, err := s.codec.NativeFromBinary(buf)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]interface{})
+	s.flatten(values, "", s.root, native)
+	return p.Metrics(values)
+}
+
+func (p *Parser) ParseLine(line string) (telegraf.Metric, error) {
+	return flat.ParseLine(p.Parse, line)
+}
+
+// schema is a codec with the named types of its schema, which are needed to
+// tell unions from records and maps when flattening.
+type schema struct {
+	codec *goavro.Codec
+	root  interface{}
+	named map[string]interface{}
+}
+
+func newSchema(spec string) (*schema, error) {
+	codec, err := goavro.NewCodec(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &schema{codec: codec, named: make(map[string]interface{})}
+	if err := json.Unmarshal([]byte(spec), &s.root); err != nil {
+		return nil, err
+	}
+	s.register(s.root, "")
+	return s, nil
+}
+
+// register adds the named types of the schema by name and full name.
+func (s *schema) register(node interface{}, namespace string) {
+	switch n := node.(type) {
+	case []interface{}:
+		for _, branch := range n {
+			s.register(branch, namespace)
+		}
+	case map[string]interface{}:
+		switch n["type"] {
+		case "record", "error", "enum", "fixed":
+			name, _ := n["name"].(string)
+			if ns, ok := n["namespace"].(string); ok {
+				namespace = ns
+			}
+			if i := strings.LastIndex(name, "."); i >= 0 {
+				namespace = name[:i]
+				name = name[i+1:]
+			}
+			s.named[name] = n
+			if namespace != "" {
+				s.named[namespace+"."+name] = n
+			}
+
+			fields, _ := n["fields"].([]interface{})
+			for _, field := range fields {
+				if f, ok := field.(map[string]interface{}); ok {
+					s.register(f["type"], namespace)
+				}
+			}
+		case "array":
+			s.register(n["items"], namespace)
+		case "map":
+			s.register(n["values"], namespace)
+		default:
+			s.register(n["type"], namespace)
+		}
+	}
+}
+
+// flatten adds the value described by the schema node to values.  Arrays
+// are suffixed by their index and maps by their key.
+func (s *schema) flatten(values map[string]interface{}, key string, node interface{}, value interface{}) {
+	join := func(name string) string {
+		if key == "" {
+			return name
+		}
+		return key + "_" + name
+	}
+
+	switch n := node.(type) {
+	case []interface{}:
+		// Unions are decoded as a map from the type name to the value.
+		if union, ok := value.(map[string]interface{}); ok {
+			for name, v := range union {
+				s.flatten(values, key, branch(n, name), v)
+			}
+		}
+	case string:
+		if named, ok := s.named[n]; ok {
+			s.flatten(values, key, named, value)
+			return
+		}
+		addValue(values, key, value)
+	case map[string]interface{}:
+		switch n["type"] {
+		case "record", "error":
+			record, ok := value.(map[string]interface{})
+			if !ok {
+				return
+			}
+			fields, _ := n["fields"].([]interface{})
+			for _, field := range fields {
+				f, ok := field.(map[string]interface{})
+				if !ok {
+					continue
+				}
+				name, _ := f["name"].(string)
+				s.flatten(values, join(name), f["type"], record[name])
+			}
+		case "array":
+			items, _ := value.([]interface{})
+			for i, v := range items {
+				s.flatten(values, join(strconv.Itoa(i)), n["items"], v)
+			}
+		case "map":
+			entries, _ := value.(map[string]interface{})
+			for k, v := range entries {
+				s.flatten(values, join(k), n["values"], v)
+			}
+		default:
+			// Enums, fixed and annotated types such as logical types.
+			s.flatten(values, key, n["type"], value)
+		}
+	}
+}
+
+// branch returns the node of the union branch with the type name.  Arrays
+// and maps are named by their type, other branches are looked up by name.
+func branch(union []interface{}, name string) interface{} {
+	for _, node := range union {
+		if n, ok := node.(map[string]interface{}); ok && n["type"] == name {
+			switch name {
+			case "array", "map":
+				return n
+			}
+		}
+	}
+	return name
+}
+
+func addValue(values map[string]interface{}, key string, value interface{}) {
+	switch v := value.(type) {
+	case int32:
+		values[key] = int64(v)
+	case int64:
+		values[key] = v
+	case float32:
+		values[key] = float64(v)
+	case float64:
+		values[key] = v
+	case bool:
+		values[key] = v
+	case string:
+		values[key] = v
+	case []byte:
+		values[key] = string(v)
+	case time.Time:
+		values[key] = v
+	}
+}
+
+// schemaRegistry fetches schemas by id from a Confluent compatible schema
+// registry.  Schemas never change, so they are cached forever.
+type schemaRegistry struct {
+	url    string
+	client *http.Client
+
+	sync.Mutex
+	cache map[int]*schema
+}
+
+func newSchemaRegistry(url string) *schemaRegistry {
+	return &schemaRegistry{
+		url:    strings.TrimRight(url, "/"),
+		client: &http.Client{Timeout: 10 * time.Second},
+		cache:  make(map[int]*schema),
+	}
+}
+
+func (r *schemaRegistry) get(id int) (*schema, error) {
+	r.Lock()
+	s, ok := r.cache[id]
+	r.Unlock()
+	if ok {
+		return s, nil
+	}
+
+	// The schema is fetched without holding the lock, so that a slow
+	// registry does not block the messages with cached schemas.
+	s, err := r.fetch(id)
+	if err != nil {
+		return nil, err
+	}
+
+	r.Lock()
+	defer r.Unlock()
+	if cached, ok := r.cache[id]; ok {
+		return cached, nil
+	}
+	r.cache[id] = s
+	return s, nil
+}
+
+func (r *schemaRegistry) fetch(id int) (*schema, error) {
+	resp, err := r.client.Get(fmt.Sprintf("%s/schemas/ids/%d", r.url, id))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching schema %d failed: %s", id, resp.Status)
+	}
+
+	var body struct {
+		Schema string `json:"schema"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, err
+	}
+
+	return newSchema(body.Schema)
+}
@@ -0,0 +1,243 @@
+package avro
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/linkedin/goavro"
+	"github.com/stretchr/testify/require"
+)
+
+const schemaFile = "testdata/reading.avsc"
+
+func newReading(t *testing.T) []byte {
+	spec, err := ioutil.ReadFile(schemaFile)
+	require.NoError(t, err)
+	codec, err := goavro.NewCodec(string(spec))
+	require.NoError(t, err)
+
+	buf, err := codec.BinaryFromNative(nil, map[string]interface{}{
+		"device":      "dev-1",
+		"time":        time.Unix(1614592800, 0),
+		"temperature": 21.5,
+		"count":       int32(3),
+		"active":      true,
+		"status":      "OK",
+		"location": goavro.Union("telegraf.test.Location", map[string]interface{}{
+			"building": "north",
+			"floor":    int32(2),
+		}),
+		"humidity": nil,
+		"voltages": []interface{}{1.5, 2.5},
+		"counters": map[string]interface{}{"errors": int64(4)},
+	})
+	require.NoError(t, err)
+	return buf
+}
+
+func TestParse(t *testing.T) {
+	p, err := New(&Config{
+		MetricName:   "sensor",
+		SchemaFile:   schemaFile,
+		TagKeys:      []string{"device", "location_building"},
+		TimestampKey: "time",
+	})
+	require.NoError(t, err)
+
+	metrics, err := p.Parse(newReading(t))
+	require.NoError(t, err)
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric("sensor",
+			map[string]string{"device": "dev-1", "location_building": "north"},
+			map[string]interface{}{
+				"temperature":     21.5,
+				"count":           int64(3),
+				"active":          true,
+				"status":          "OK",
+				"location_floor":  int64(2),
+				"voltages_0":      1.5,
+				"voltages_1":      2.5,
+				"counters_errors": int64(4),
+			},
+			time.Unix(1614592800, 0)),
+	}
+	testutil.RequireMetricsEqual(t, expected, metrics)
+}
+
+func TestParseOptionalArrayAndMap(t *testing.T) {
+	const optionalFile = "testdata/optional.avsc"
+	spec, err := ioutil.ReadFile(optionalFile)
+	require.NoError(t, err)
+	codec, err := goavro.NewCodec(string(spec))
+	require.NoError(t, err)
+
+	p, err := New(&Config{
+		MetricName: "sensor",
+		SchemaFile: optionalFile,
+		TagKeys:    []string{"device"},
+	})
+	require.NoError(t, err)
+	p.TimeFunc = func() time.Time { return time.Unix(42, 0) }
+
+	buf, err := codec.BinaryFromNative(nil, map[string]interface{}{
+		"device":  "dev-1",
+		"samples": goavro.Union("array", []interface{}{1.5, 2.5}),
+		"limits":  goavro.Union("map", map[string]interface{}{"max": int64(10)}),
+	})
+	require.NoError(t, err)
+
+	metrics, err := p.Parse(buf)
+	require.NoError(t, err)
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric("sensor",
+			map[string]string{"device": "dev-1"},
+			map[string]interface{}{
+				"samples_0":  1.5,
+				"samples_1":  2.5,
+				"limits_max": int64(10),
+			},
+			time.Unix(42, 0)),
+	}
+	testutil.RequireMetricsEqual(t, expected, metrics)
+
+	// Without the optional values no fields are left.
+	buf, err = codec.BinaryFromNative(nil, map[string]interface{}{
+		"device":  "dev-1",
+		"samples": nil,
+		"limits":  nil,
+	})
+	require.NoError(t, err)
+
+	metrics, err = p.Parse(buf)
+	require.NoError(t, err)
+	require.Len(t, metrics, 0)
+}
+
+func TestParseFieldKeys(t *testing.T) {
+	p, err := New(&Config{
+		MetricName: "sensor",
+		SchemaFile: schemaFile,
+		FieldKeys:  []string{"temperature", "time"},
+	})
+	require.NoError(t, err)
+	p.TimeFunc = func() time.Time { return time.Unix(42, 0) }
+	p.SetDefaultTags(map[string]string{"source": "kafka"})
+
+	m, err := p.ParseLine(string(newReading(t)))
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"source": "kafka"}, m.Tags())
+	require.Equal(t, map[string]interface{}{
+		"temperature": 21.5,
+		"time":        int64(1614592800000000000),
+	}, m.Fields())
+	require.Equal(t, time.Unix(42, 0), m.Time())
+}
+
+func TestParseSchemaRegistry(t *testing.T) {
+	spec, err := ioutil.ReadFile(schemaFile)
+	require.NoError(t, err)
+
+	var requests int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.URL.Path != "/schemas/ids/7" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"schema": string(spec)})
+	}))
+	defer ts.Close()
+
+	p, err := New(&Config{
+		MetricName:     "sensor",
+		SchemaRegistry: ts.URL,
+		TimestampKey:   "time",
+	})
+	require.NoError(t, err)
+
+	header := make([]byte, 5)
+	binary.BigEndian.PutUint32(header[1:], 7)
+	msg := append(header, newReading(t)...)
+
+	for i := 0; i < 2; i++ {
+		metrics, err := p.Parse(msg)
+		require.NoError(t, err)
+		require.Len(t, metrics, 1)
+		require.Equal(t, "dev-1", metrics[0].Fields()["device"])
+	}
+	require.Equal(t, 1, requests)
+
+	binary.BigEndian.PutUint32(header[1:], 8)
+	_, err = p.Parse(append(header, newReading(t)...))
+	require.Error(t, err)
+
+	_, err = p.Parse(newReading(t))
+	require.Error(t, err)
+}
+
+func TestParseSchemaRegistrySlowFetch(t *testing.T) {
+	spec, err := ioutil.ReadFile(schemaFile)
+	require.NoError(t, err)
+
+	fetching := make(chan struct{})
+	release := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/schemas/ids/8" {
+			close(fetching)
+			<-release
+		}
+		json.NewEncoder(w).Encode(map[string]string{"schema": string(spec)})
+	}))
+	defer ts.Close()
+
+	p, err := New(&Config{
+		MetricName:     "sensor",
+		SchemaRegistry: ts.URL,
+		TimestampKey:   "time",
+	})
+	require.NoError(t, err)
+
+	message := func(id uint32) []byte {
+		header := make([]byte, 5)
+		binary.BigEndian.PutUint32(header[1:], id)
+		return append(header, newReading(t)...)
+	}
+	_, err = p.Parse(message(7))
+	require.NoError(t, err)
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := p.Parse(message(8))
+		done <- err
+	}()
+	<-fetching
+
+	// Messages with a cached schema are parsed while another schema is
+	// being fetched.
+	metrics, err := p.Parse(message(7))
+	require.NoError(t, err)
+	require.Len(t, metrics, 1)
+
+	close(release)
+	require.NoError(t, <-done)
+}
+
+func TestNewInvalidConfig(t *testing.T) {
+	_, err := New(&Config{})
+	require.Error(t, err)
+
+	_, err = New(&Config{SchemaFile: schemaFile, SchemaRegistry: "http://localhost:8081"})
+	require.Error(t, err)
+
+	_, err = New(&Config{SchemaFile: "testdata/missing.avsc"})
+	require.Error(t, err)
+}
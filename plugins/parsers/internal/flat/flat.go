@@ -0,0 +1,105 @@
+// Package flat makes metrics from the flattened values of a decoded
+// message, as shared by the parsers of binary formats such as avro and
+// protobuf.
+package flat
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/metric"
+)
+
+// MetricBuilder selects the tags, fields and timestamp of a metric from the
+// flattened values of a message.
+type MetricBuilder struct {
+	MetricName      string
+	TagKeys         []string
+	FieldKeys       []string
+	TimestampKey    string
+	TimestampFormat string
+	Timezone        string
+	DefaultTags     map[string]string
+	TimeFunc        func() time.Time
+}
+
+func (b *MetricBuilder) SetDefaultTags(tags map[string]string) {
+	b.DefaultTags = tags
+}
+
+// Metrics returns the metric made from the values, or no metric if none of
+// the values is a field.  Values are removed from the map as they are used.
+func (b *MetricBuilder) Metrics(values map[string]interface{}) ([]telegraf.Metric, error) {
+	timestamp := b.TimeFunc()
+	if b.TimestampKey != "" {
+		value, ok := values[b.TimestampKey]
+		if !ok {
+			return nil, fmt.Errorf("timestamp key %q not found", b.TimestampKey)
+		}
+		if t, ok := value.(time.Time); ok {
+			timestamp = t
+		} else {
+			// ParseTimestamp does not accept unsigned integers.
+			if v, ok := value.(uint64); ok {
+				value = int64(v)
+			}
+			var err error
+			timestamp, err = internal.ParseTimestamp(b.TimestampFormat, value, b.Timezone)
+			if err != nil {
+				return nil, err
+			}
+		}
+		delete(values, b.TimestampKey)
+	}
+
+	tags := make(map[string]string)
+	for k, v := range b.DefaultTags {
+		tags[k] = v
+	}
+	for _, key := range b.TagKeys {
+		if value, ok := values[key]; ok {
+			tags[key] = fmt.Sprintf("%v", value)
+			delete(values, key)
+		}
+	}
+
+	fields := values
+	if len(b.FieldKeys) > 0 {
+		fields = make(map[string]interface{})
+		for _, key := range b.FieldKeys {
+			if value, ok := values[key]; ok {
+				fields[key] = value
+			}
+		}
+	}
+	for k, v := range fields {
+		if t, ok := v.(time.Time); ok {
+			fields[k] = t.UnixNano()
+		}
+	}
+
+	if len(fields) == 0 {
+		return []telegraf.Metric{}, nil
+	}
+	m, err := metric.New(b.MetricName, tags, fields, timestamp)
+	if err != nil {
+		return nil, err
+	}
+	return []telegraf.Metric{m}, nil
+}
+
+// ParseLine returns the first metric parsed from the line by parse, for
+// parsers of binary messages which only implement Parse.
+func ParseLine(parse func(buf []byte) ([]telegraf.Metric, error), line string) (telegraf.Metric, error) {
+	metrics, err := parse([]byte(line))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(metrics) < 1 {
+		return nil, fmt.Errorf("no metric in line")
+	}
+	return metrics[0], nil
+}
@@ -0,0 +1,59 @@
+package flat
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetrics(t *testing.T) {
+	b := &MetricBuilder{
+		MetricName:      "reading",
+		TagKeys:         []string{"device"},
+		TimestampKey:    "time",
+		TimestampFormat: "unix",
+		DefaultTags:     map[string]string{"source": "test"},
+		TimeFunc:        func() time.Time { return time.Unix(42, 0) },
+	}
+
+	metrics, err := b.Metrics(map[string]interface{}{
+		"device":  "dev-1",
+		"time":    uint64(1614592800),
+		"value":   21.5,
+		"updated": time.Unix(1614592801, 0),
+	})
+	require.NoError(t, err)
+	testutil.RequireMetricsEqual(t, []telegraf.Metric{
+		testutil.MustMetric("reading",
+			map[string]string{"device": "dev-1", "source": "test"},
+			map[string]interface{}{
+				"value":   21.5,
+				"updated": int64(1614592801000000000),
+			},
+			time.Unix(1614592800, 0)),
+	}, metrics)
+
+	_, err = b.Metrics(map[string]interface{}{"value": 21.5})
+	require.Error(t, err)
+}
+
+func TestMetricsFieldKeys(t *testing.T) {
+	b := &MetricBuilder{
+		MetricName: "reading",
+		FieldKeys:  []string{"value"},
+		TimeFunc:   func() time.Time { return time.Unix(42, 0) },
+	}
+
+	metrics, err := b.Metrics(map[string]interface{}{"value": 21.5, "other": 1})
+	require.NoError(t, err)
+	require.Len(t, metrics, 1)
+	require.Equal(t, map[string]interface{}{"value": 21.5}, metrics[0].Fields())
+	require.Equal(t, time.Unix(42, 0), metrics[0].Time())
+
+	metrics, err = b.Metrics(map[string]interface{}{"other": 1})
+	require.NoError(t, err)
+	require.Len(t, metrics, 0)
+}
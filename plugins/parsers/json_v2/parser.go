@@ -0,0 +1,385 @@
+package json_v2
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/tidwall/gjson"
+)
+
+// Config describes the metrics created from a document.
+type Config struct {
+	// MeasurementName is the name of the metrics, defaults to the name of
+	// the plugin.
+	MeasurementName string `toml:"measurement_name"`
+	// MeasurementNamePath is a GJSON path to the name of the metrics, it
+	// takes precedence over MeasurementName.
+	MeasurementNamePath string `toml:"measurement_name_path"`
+
+	// TimestampPath is a GJSON path to the time of the metrics, defaults to
+	// the current time.
+	TimestampPath     string `toml:"timestamp_path"`
+	TimestampFormat   string `toml:"timestamp_format"`
+	TimestampTimezone string `toml:"timestamp_timezone"`
+
+	Objects []Object `toml:"object"`
+}
+
+// Object selects a part of the document to create metrics from.
+type Object struct {
+	// Path is a GJSON path to the object, an array creates a metric per
+	// element.
+	Path string `toml:"path"`
+
+	// TimestampKey is the key holding the metric time, it takes precedence
+	// over the timestamp of the Config.
+	TimestampKey      string `toml:"timestamp_key"`
+	TimestampFormat   string `toml:"timestamp_format"`
+	TimestampTimezone string `toml:"timestamp_timezone"`
+
+	// DisablePrependKeys names nested keys by their own name instead of
+	// prefixing them with the keys of the parents.
+	DisablePrependKeys bool `toml:"disable_prepend_keys"`
+	// IncludedKeys limits the keys added to the metric, keys in Tags and
+	// Fields are always included.
+	IncludedKeys []string `toml:"included_keys"`
+	// ExcludedKeys are keys not added to the metric.
+	ExcludedKeys []string `toml:"excluded_keys"`
+	// Tags are the keys added as tags.
+	Tags []string `toml:"tags"`
+	// Fields maps keys to their type: int, uint, float, string or bool.
+	Fields map[string]string `toml:"fields"`
+	// Renames maps keys to the name of the tag or field.
+	Renames map[string]string `toml:"renames"`
+}
+
+// Parser creates metrics from JSON documents using GJSON paths.  Unlike the
+// json parser, arrays of objects create a metric per element which inherits
+// the keys of its parents.
+type Parser struct {
+	Configs     []Config
+	MetricName  string
+	DefaultTags map[string]string
+	TimeFunc    func() time.Time
+}
+
+// New returns a parser after checking the configuration.
+func New(metricName string, configs []Config, defaultTags map[string]string) (*Parser, error) {
+	if len(configs) == 0 {
+		return nil, fmt.Errorf("no json_v2 configuration given")
+	}
+
+	for _, config := range configs {
+		if len(config.Objects) == 0 {
+			return nil, fmt.Errorf("no json_v2 object given")
+		}
+		for _, object := range config.Objects {
+			if object.Path == "" {
+				return nil, fmt.Errorf("json_v2 object path is required")
+			}
+			for key, typ := range object.Fields {
+				if _, err := convert(gjson.Result{}, typ); err != nil {
+					return nil, fmt.Errorf("field %q: %v", key, err)
+				}
+			}
+		}
+	}
+
+	return &Parser{
+		Configs:     configs,
+		MetricName:  metricName,
+		DefaultTags: defaultTags,
+		TimeFunc:    time.Now,
+	}, nil
+}
+
+// row holds the values of one metric by key.
+type row map[string]gjson.Result
+
+func (p *Parser) Parse(buf []byte) ([]telegraf.Metric, error) {
+	if !gjson.ValidBytes(buf) {
+		return nil, fmt.Errorf("invalid JSON")
+	}
+
+	now := p.TimeFunc()
+	metrics := make([]telegraf.Metric, 0)
+	for _, config := range p.Configs {
+		name := p.MetricName
+		if config.MeasurementName != "" {
+			name = config.MeasurementName
+		}
+		if config.MeasurementNamePath != "" {
+			if result := gjson.GetBytes(buf, config.MeasurementNamePath); result.Exists() {
+				name = result.String()
+			}
+		}
+
+		timestamp := now
+		if config.TimestampPath != "" {
+			result := gjson.GetBytes(buf, config.TimestampPath)
+			if !result.Exists() {
+				return nil, fmt.Errorf("timestamp path %q not found", config.TimestampPath)
+			}
+			var err error
+			timestamp, err = parseTime(result, config.TimestampFormat, config.TimestampTimezone)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		for _, object := range config.Objects {
+			result := gjson.GetBytes(buf, object.Path)
+			if !result.Exists() {
+				continue
+			}
+
+			rows := expandRoot(result, lastKey(object.Path), &object)
+			for _, r := range rows {
+				m, err := p.makeMetric(r, name, timestamp, &object)
+				if err != nil {
+					return nil, err
+				}
+				if m != nil {
+					metrics = append(metrics, m)
+				}
+			}
+		}
+	}
+	return metrics, nil
+}
+
+func (p *Parser) ParseLine(line string) (telegraf.Metric, error) {
+	metrics, err := p.Parse([]byte(line))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(metrics) < 1 {
+		return nil, fmt.Errorf("no metric in line")
+	}
+	return metrics[0], nil
+}
+
+func (p *Parser) SetDefaultTags(tags map[string]string) {
+	p.DefaultTags = tags
+}
+
+func (p *Parser) makeMetric(r row, name string, timestamp time.Time, object *Object) (telegraf.Metric, error) {
+	if object.TimestampKey != "" {
+		if result, ok := r[object.TimestampKey]; ok {
+			var err error
+			timestamp, err = parseTime(result, object.TimestampFormat, object.TimestampTimezone)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	tags := make(map[string]string)
+	for k, v := range p.DefaultTags {
+		tags[k] = v
+	}
+	fields := make(map[string]interface{})
+	for key, result := range r {
+		if key == object.TimestampKey {
+			continue
+		}
+
+		rename := key
+		if to, ok := object.Renames[key]; ok {
+			rename = to
+		}
+
+		if contains(object.Tags, key) {
+			tags[rename] = result.String()
+			continue
+		}
+
+		value, err := convert(result, object.Fields[key])
+		if err != nil {
+			return nil, fmt.Errorf("field %q: %v", key, err)
+		}
+		fields[rename] = value
+	}
+
+	if len(fields) == 0 {
+		return nil, nil
+	}
+	return metric.New(name, tags, fields, timestamp)
+}
+
+// expandRoot returns the rows of the value selected by the path of an object.
+// The keys of the selected objects are not prefixed, a selected value which
+// is not an object is named after the last key of the path.
+func expandRoot(result gjson.Result, key string, object *Object) []row {
+	switch {
+	case result.IsArray():
+		var rows []row
+		for _, elem := range result.Array() {
+			rows = append(rows, expandRoot(elem, key, object)...)
+		}
+		return rows
+	case result.IsObject():
+		rows, _ := expand(result, "", object)
+		return rows
+	default:
+		rows, _ := expand(result, key, object)
+		return rows
+	}
+}
+
+// expand returns the rows of a value, and whether they are the elements of
+// an array.  Each element of the arrays of an object inherits the other keys
+// of the object.  Sibling arrays create rows of their own, they are not
+// combined with each other.
+func expand(result gjson.Result, key string, object *Object) ([]row, bool) {
+	switch {
+	case result.IsArray():
+		// Elements without any included key don't create a row.
+		var rows []row
+		for _, elem := range result.Array() {
+			elemRows, _ := expand(elem, key, object)
+			for _, r := range elemRows {
+				if len(r) > 0 {
+					rows = append(rows, r)
+				}
+			}
+		}
+		return rows, true
+	case result.IsObject():
+		base := row{}
+		var elements []row
+		result.ForEach(func(k, v gjson.Result) bool {
+			name := k.String()
+			if key != "" && !object.DisablePrependKeys {
+				name = key + "_" + name
+			}
+			rows, isArray := expand(v, name, object)
+			if isArray {
+				elements = append(elements, rows...)
+				return true
+			}
+			for _, r := range rows {
+				for k, v := range r {
+					base[k] = v
+				}
+			}
+			return true
+		})
+		if len(elements) == 0 {
+			return []row{base}, false
+		}
+		return inherit(base, elements), true
+	default:
+		// Null values are left out like missing keys.
+		if !object.included(key) || result.Type == gjson.Null {
+			return []row{{}}, false
+		}
+		return []row{{key: result}}, false
+	}
+}
+
+// inherit returns the rows with the keys of the base row added.
+func inherit(base row, rows []row) []row {
+	for i, r := range rows {
+		merged := make(row, len(base)+len(r))
+		for k, v := range base {
+			merged[k] = v
+		}
+		for k, v := range r {
+			merged[k] = v
+		}
+		rows[i] = merged
+	}
+	return rows
+}
+
+func (o *Object) included(key string) bool {
+	if key == "" || contains(o.ExcludedKeys, key) {
+		return false
+	}
+	if len(o.IncludedKeys) == 0 || key == o.TimestampKey || contains(o.Tags, key) {
+		return true
+	}
+	if _, ok := o.Fields[key]; ok {
+		return true
+	}
+	return contains(o.IncludedKeys, key)
+}
+
+// convert returns the value of the result as the type, without a type the
+// JSON type is used.
+func convert(result gjson.Result, typ string) (interface{}, error) {
+	switch typ {
+	case "":
+		switch result.Type {
+		case gjson.Number:
+			return result.Float(), nil
+		case gjson.True, gjson.False:
+			return result.Bool(), nil
+		default:
+			return result.String(), nil
+		}
+	case "int":
+		if result.Type == gjson.String {
+			return strconv.ParseInt(result.String(), 10, 64)
+		}
+		return result.Int(), nil
+	case "uint":
+		if result.Type == gjson.String {
+			return strconv.ParseUint(result.String(), 10, 64)
+		}
+		return result.Uint(), nil
+	case "float":
+		if result.Type == gjson.String {
+			return strconv.ParseFloat(result.String(), 64)
+		}
+		return result.Float(), nil
+	case "string":
+		return result.String(), nil
+	case "bool":
+		if result.Type == gjson.String {
+			return strconv.ParseBool(result.String())
+		}
+		return result.Bool(), nil
+	default:
+		return nil, fmt.Errorf("unknown type %q", typ)
+	}
+}
+
+func parseTime(result gjson.Result, format, timezone string) (time.Time, error) {
+	if format == "" {
+		format = time.RFC3339
+	}
+
+	var value interface{} = result.String()
+	if result.Type == gjson.Number {
+		switch format {
+		case "unix":
+			value = result.Float()
+		case "unix_ms", "unix_us", "unix_ns":
+			value = result.Int()
+		}
+	}
+	return internal.ParseTimestamp(format, value, timezone)
+}
+
+// lastKey returns the name of the last key of a path.
+func lastKey(path string) string {
+	parts := strings.Split(path, ".")
+	return parts[len(parts)-1]
+}
+
+func contains(keys []string, key string) bool {
+	for _, k := range keys {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
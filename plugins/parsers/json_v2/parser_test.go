@@ -0,0 +1,239 @@
+package json_v2
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+const libraryJSON = `
+{
+  "library": "central",
+  "timestamp": "2021-03-01T10:00:00Z",
+  "books": [
+    {
+      "title": "The Lord Of The Rings",
+      "author": "Tolkien",
+      "pages": 1216,
+      "chapters": [
+        {"name": "A Long-expected Party", "pages": 20},
+        {"name": "The Shadow of the Past", "pages": 26}
+      ]
+    },
+    {
+      "title": "Mistborn",
+      "author": "Sanderson",
+      "pages": 541,
+      "chapters": []
+    }
+  ]
+}
+`
+
+func newParser(t *testing.T, configs ...Config) *Parser {
+	p, err := New("file", configs, nil)
+	require.NoError(t, err)
+	p.TimeFunc = func() time.Time { return time.Unix(42, 0) }
+	return p
+}
+
+func TestParseArrayOfObjects(t *testing.T) {
+	p := newParser(t, Config{
+		Objects: []Object{
+			{
+				Path:         "books",
+				Tags:         []string{"title"},
+				ExcludedKeys: []string{"author"},
+				Fields:       map[string]string{"pages": "int"},
+			},
+		},
+	})
+
+	metrics, err := p.Parse([]byte(libraryJSON))
+	require.NoError(t, err)
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric("file",
+			map[string]string{"title": "The Lord Of The Rings"},
+			map[string]interface{}{"pages": int64(1216), "chapters_name": "A Long-expected Party", "chapters_pages": 20.0},
+			time.Unix(42, 0)),
+		testutil.MustMetric("file",
+			map[string]string{"title": "The Lord Of The Rings"},
+			map[string]interface{}{"pages": int64(1216), "chapters_name": "The Shadow of the Past", "chapters_pages": 26.0},
+			time.Unix(42, 0)),
+		testutil.MustMetric("file",
+			map[string]string{"title": "Mistborn"},
+			map[string]interface{}{"pages": int64(541)},
+			time.Unix(42, 0)),
+	}
+	testutil.RequireMetricsEqual(t, expected, metrics)
+}
+
+func TestParseNestedArrayInheritsParentKeys(t *testing.T) {
+	p := newParser(t, Config{
+		MeasurementName: "chapters",
+		TimestampPath:   "timestamp",
+		Objects: []Object{
+			{
+				Path:               "books.#(title==\"The Lord Of The Rings\")",
+				DisablePrependKeys: true,
+				IncludedKeys:       []string{"name"},
+				Tags:               []string{"author"},
+				Fields:             map[string]string{"pages": "uint"},
+				Renames:            map[string]string{"name": "chapter"},
+			},
+		},
+	})
+
+	metrics, err := p.Parse([]byte(libraryJSON))
+	require.NoError(t, err)
+
+	ts := time.Date(2021, 3, 1, 10, 0, 0, 0, time.UTC)
+	expected := []telegraf.Metric{
+		testutil.MustMetric("chapters",
+			map[string]string{"author": "Tolkien"},
+			map[string]interface{}{"chapter": "A Long-expected Party", "pages": uint64(20)},
+			ts),
+		testutil.MustMetric("chapters",
+			map[string]string{"author": "Tolkien"},
+			map[string]interface{}{"chapter": "The Shadow of the Past", "pages": uint64(26)},
+			ts),
+	}
+	testutil.RequireMetricsEqual(t, expected, metrics)
+}
+
+func TestParseMultipleObjects(t *testing.T) {
+	p := newParser(t, Config{
+		MeasurementNamePath: "library",
+		Objects: []Object{
+			{Path: "books.#.pages"},
+			{Path: "books.0", IncludedKeys: []string{"author"}},
+		},
+	})
+	p.SetDefaultTags(map[string]string{"host": "localhost"})
+
+	metrics, err := p.Parse([]byte(libraryJSON))
+	require.NoError(t, err)
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric("central",
+			map[string]string{"host": "localhost"},
+			map[string]interface{}{"pages": 1216.0},
+			time.Unix(42, 0)),
+		testutil.MustMetric("central",
+			map[string]string{"host": "localhost"},
+			map[string]interface{}{"pages": 541.0},
+			time.Unix(42, 0)),
+		testutil.MustMetric("central",
+			map[string]string{"host": "localhost"},
+			map[string]interface{}{"author": "Tolkien"},
+			time.Unix(42, 0)),
+	}
+	testutil.RequireMetricsEqual(t, expected, metrics)
+}
+
+func TestParseSiblingArrays(t *testing.T) {
+	p := newParser(t, Config{
+		Objects: []Object{
+			{
+				Path: "@this",
+				Tags: []string{"host"},
+			},
+		},
+	})
+
+	metrics, err := p.Parse([]byte(`
+{
+  "host": "server01",
+  "disks": [{"name": "sda"}, {"name": "sdb"}],
+  "nics": [{"name": "eth0"}, {"name": "eth1"}]
+}`))
+	require.NoError(t, err)
+
+	tags := map[string]string{"host": "server01"}
+	expected := []telegraf.Metric{
+		testutil.MustMetric("file", tags, map[string]interface{}{"disks_name": "sda"}, time.Unix(42, 0)),
+		testutil.MustMetric("file", tags, map[string]interface{}{"disks_name": "sdb"}, time.Unix(42, 0)),
+		testutil.MustMetric("file", tags, map[string]interface{}{"nics_name": "eth0"}, time.Unix(42, 0)),
+		testutil.MustMetric("file", tags, map[string]interface{}{"nics_name": "eth1"}, time.Unix(42, 0)),
+	}
+	testutil.RequireMetricsEqual(t, expected, metrics, testutil.SortMetrics())
+}
+
+func TestParseNull(t *testing.T) {
+	p := newParser(t, Config{
+		Objects: []Object{
+			{
+				Path:   "@this",
+				Tags:   []string{"host"},
+				Fields: map[string]string{"load": "float", "status": "string"},
+			},
+		},
+	})
+
+	metrics, err := p.Parse([]byte(`{"host": null, "load": 0.5, "status": null, "uptime": null}`))
+	require.NoError(t, err)
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric("file",
+			map[string]string{},
+			map[string]interface{}{"load": 0.5},
+			time.Unix(42, 0)),
+	}
+	testutil.RequireMetricsEqual(t, expected, metrics)
+}
+
+func TestParseTimestampKey(t *testing.T) {
+	p := newParser(t, Config{
+		Objects: []Object{
+			{
+				Path:            "readings",
+				TimestampKey:    "time",
+				TimestampFormat: "unix_ms",
+				Fields:          map[string]string{"ok": "bool"},
+			},
+		},
+	})
+
+	metrics, err := p.Parse([]byte(`{"readings": [{"time": 1614592800000, "value": 1.5, "ok": "true"}]}`))
+	require.NoError(t, err)
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric("file",
+			map[string]string{},
+			map[string]interface{}{"value": 1.5, "ok": true},
+			time.Unix(1614592800, 0)),
+	}
+	testutil.RequireMetricsEqual(t, expected, metrics)
+}
+
+func TestParseInvalidType(t *testing.T) {
+	p := newParser(t, Config{
+		Objects: []Object{
+			{Path: "books", Fields: map[string]string{"author": "int"}},
+		},
+	})
+
+	_, err := p.Parse([]byte(libraryJSON))
+	require.Error(t, err)
+
+	_, err = p.Parse([]byte(`{"books": [`))
+	require.Error(t, err)
+}
+
+func TestNewInvalidConfig(t *testing.T) {
+	_, err := New("file", nil, nil)
+	require.Error(t, err)
+
+	_, err = New("file", []Config{{}}, nil)
+	require.Error(t, err)
+
+	_, err = New("file", []Config{{Objects: []Object{{}}}}, nil)
+	require.Error(t, err)
+
+	_, err = New("file", []Config{{Objects: []Object{{Path: "a", Fields: map[string]string{"b": "integer"}}}}}, nil)
+	require.Error(t, err)
+}
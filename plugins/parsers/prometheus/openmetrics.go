@@ -0,0 +1,608 @@
+package prometheus
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// OpenMetrics parser following
+// https://github.com/OpenObservability/OpenMetrics/blob/main/specification/OpenMetrics.md
+
+var eofLine = []byte("# EOF")
+
+// extras holds the parts of an OpenMetrics metric that have no place in the
+// Prometheus data model.
+type extras struct {
+	hasCreated  bool
+	created     float64
+	createdName string
+
+	exemplars []*exemplar
+}
+
+// exemplar is a reference to data outside of the metric set, such as a
+// trace, attached to a sample.
+type exemplar struct {
+	// Name of the sample the exemplar is attached to.
+	name string
+	// Upper bound of the bucket of histogram samples.
+	le     string
+	labels map[string]string
+	value  float64
+	ts     *time.Time
+}
+
+// tags returns the tags of the metric of the exemplar.
+func (e *exemplar) tags(tags map[string]string) map[string]string {
+	if e.le == "" {
+		return tags
+	}
+	result := make(map[string]string, len(tags)+1)
+	for k, v := range tags {
+		result[k] = v
+	}
+	result["le"] = e.le
+	return result
+}
+
+// fields returns the value of the exemplar in the named field, and its labels
+// as string fields.
+func (e *exemplar) fields(name string) map[string]interface{} {
+	fields := make(map[string]interface{}, len(e.labels)+1)
+	for k, v := range e.labels {
+		fields[k] = v
+	}
+	fields[name] = e.value
+	return fields
+}
+
+func (e *exemplar) time(t time.Time) time.Time {
+	if e.ts != nil {
+		return *e.ts
+	}
+	return t
+}
+
+// omFamily is a metric family as named by OpenMetrics, such as "foo" for
+// the samples of a "foo_total" counter.
+type omFamily struct {
+	name    string
+	typ     string
+	mf      *dto.MetricFamily
+	metrics map[string]*dto.Metric
+}
+
+// Suffixes of the sample names of each type.
+var omSuffixes = map[string][]string{
+	"counter":        {"_total", "_created"},
+	"summary":        {"_count", "_sum", "_created"},
+	"histogram":      {"_bucket", "_count", "_sum", "_created"},
+	"gaugehistogram": {"_bucket", "_gcount", "_gsum"},
+	"info":           {"_info"},
+}
+
+// isOpenMetrics returns true if the data ends with the "# EOF" line required
+// by OpenMetrics.
+func isOpenMetrics(buf []byte) bool {
+	buf = bytes.TrimRight(buf, "\n")
+	return bytes.HasSuffix(buf, eofLine) &&
+		(len(buf) == len(eofLine) || buf[len(buf)-len(eofLine)-1] == '\n')
+}
+
+type openMetricsParser struct {
+	families []*omFamily
+	byName   map[string]*omFamily
+	current  *omFamily
+	extras   map[*dto.Metric]*extras
+}
+
+// parseOpenMetrics parses data in the OpenMetrics text format.  Counters and
+// info metrics are named after their samples, as in the Prometheus text
+// format.  Info and stateset metrics are gauges, and gauge histograms are
+// histograms.
+func parseOpenMetrics(buf []byte) ([]*dto.MetricFamily, map[*dto.Metric]*extras, error) {
+	p := &openMetricsParser{
+		byName: make(map[string]*omFamily),
+		extras: make(map[*dto.Metric]*extras),
+	}
+
+	lines := strings.Split(string(buf), "\n")
+	eof := false
+	for i, line := range lines {
+		if eof {
+			if line != "" || i != len(lines)-1 {
+				return nil, nil, fmt.Errorf("line %d: data after # EOF", i+1)
+			}
+			continue
+		}
+
+		var err error
+		switch {
+		case line == string(eofLine):
+			eof = true
+		case strings.HasPrefix(line, "#"):
+			err = p.parseMetadata(line)
+		case line == "":
+			err = fmt.Errorf("empty line")
+		default:
+			err = p.parseSample(line)
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("line %d: %v", i+1, err)
+		}
+	}
+	if !eof {
+		return nil, nil, fmt.Errorf("missing # EOF")
+	}
+
+	families := make([]*dto.MetricFamily, 0, len(p.families))
+	for _, f := range p.families {
+		if len(f.mf.Metric) > 0 {
+			families = append(families, f.mf)
+		}
+	}
+	return families, p.extras, nil
+}
+
+func (p *openMetricsParser) parseMetadata(line string) error {
+	parts := strings.SplitN(line, " ", 4)
+	if len(parts) < 3 || parts[0] != "#" {
+		return fmt.Errorf("invalid metadata %q", line)
+	}
+
+	name := parts[2]
+	text := ""
+	if len(parts) == 4 {
+		text = parts[3]
+	}
+
+	switch parts[1] {
+	case "TYPE":
+		if f, ok := p.byName[name]; ok && f.typ != "" {
+			return fmt.Errorf("second TYPE for metric family %q", name)
+		}
+		switch text {
+		case "counter", "gauge", "histogram", "gaugehistogram",
+			"summary", "info", "stateset", "unknown":
+		default:
+			return fmt.Errorf("unknown type %q for metric family %q", text, name)
+		}
+		f := p.family(name)
+		f.typ = text
+		f.mf.Type = dtoType(text).Enum()
+	case "HELP":
+		p.family(name).mf.Help = proto.String(unescape(text, false))
+	case "UNIT":
+		p.family(name)
+	default:
+		return fmt.Errorf("unknown metadata %q", parts[1])
+	}
+	return nil
+}
+
+// family returns the family with the name, it is created if needed.
+func (p *openMetricsParser) family(name string) *omFamily {
+	f, ok := p.byName[name]
+	if !ok {
+		f = &omFamily{
+			name: name,
+			mf: &dto.MetricFamily{
+				Name: proto.String(name),
+				Type: dto.MetricType_UNTYPED.Enum(),
+			},
+			metrics: make(map[string]*dto.Metric),
+		}
+		p.families = append(p.families, f)
+		p.byName[name] = f
+	}
+	p.current = f
+	return f
+}
+
+// sampleFamily returns the family of a sample and the suffix of the sample
+// name.
+func (p *openMetricsParser) sampleFamily(name string) (*omFamily, string) {
+	if f := p.current; f != nil {
+		if suffix, ok := f.suffix(name); ok {
+			return f, suffix
+		}
+	}
+	for _, suffixes := range omSuffixes {
+		for _, suffix := range suffixes {
+			if !strings.HasSuffix(name, suffix) {
+				continue
+			}
+			if f, ok := p.byName[strings.TrimSuffix(name, suffix)]; ok {
+				if _, ok := f.suffix(name); ok {
+					return f, suffix
+				}
+			}
+		}
+	}
+	if f, ok := p.byName[name]; ok {
+		return f, ""
+	}
+
+	// Samples without metadata are of an unknown type.
+	f := p.family(name)
+	f.typ = "unknown"
+	return f, ""
+}
+
+func (f *omFamily) suffix(name string) (string, bool) {
+	if name == f.name {
+		return "", true
+	}
+	for _, suffix := range omSuffixes[f.typ] {
+		if name == f.name+suffix {
+			return suffix, true
+		}
+	}
+	return "", false
+}
+
+func (p *openMetricsParser) parseSample(line string) error {
+	s := &scanner{text: line}
+	name := s.name()
+	if name == "" {
+		return fmt.Errorf("invalid metric name")
+	}
+
+	labels, err := s.labels()
+	if err != nil {
+		return err
+	}
+
+	if !s.consume(' ') {
+		return fmt.Errorf("missing value")
+	}
+	value, err := s.float()
+	if err != nil {
+		return err
+	}
+
+	var ts *time.Time
+	if s.consume(' ') && !s.peek('#') {
+		t, err := s.timestamp()
+		if err != nil {
+			return err
+		}
+		ts = &t
+	}
+
+	var ex *exemplar
+	if s.consume(' ') || s.peek('#') {
+		ex, err = s.exemplar(name)
+		if err != nil {
+			return err
+		}
+	}
+	if !s.done() {
+		return fmt.Errorf("unexpected %q", s.rest())
+	}
+
+	f, suffix := p.sampleFamily(name)
+	p.current = f
+
+	// Buckets and quantiles of the same metric only differ by these labels.
+	var le, quantile string
+	switch {
+	case (f.typ == "histogram" || f.typ == "gaugehistogram") && suffix == "_bucket":
+		le = labels["le"]
+		delete(labels, "le")
+	case f.typ == "summary" && suffix == "":
+		quantile = labels["quantile"]
+		delete(labels, "quantile")
+	}
+
+	m := f.metric(labels)
+	if ts != nil {
+		m.TimestampMs = proto.Int64(ts.UnixNano() / int64(time.Millisecond))
+	}
+
+	switch f.typ {
+	case "counter":
+		switch suffix {
+		case "_created":
+			p.setCreated(f, m, value)
+		default:
+			// Counters are named after their samples.
+			f.mf.Name = proto.String(name)
+			m.Counter = &dto.Counter{Value: proto.Float64(value)}
+		}
+	case "info":
+		// Info metrics are named after their samples.
+		f.mf.Name = proto.String(name)
+		m.Gauge = &dto.Gauge{Value: proto.Float64(value)}
+	case "gauge", "stateset":
+		m.Gauge = &dto.Gauge{Value: proto.Float64(value)}
+	case "summary":
+		if m.Summary == nil {
+			m.Summary = &dto.Summary{}
+		}
+		switch suffix {
+		case "_count":
+			m.Summary.SampleCount = proto.Uint64(uint64(value))
+		case "_sum":
+			m.Summary.SampleSum = proto.Float64(value)
+		case "_created":
+			p.setCreated(f, m, value)
+		default:
+			q, err := strconv.ParseFloat(quantile, 64)
+			if err != nil {
+				return fmt.Errorf("invalid quantile %q", quantile)
+			}
+			m.Summary.Quantile = append(m.Summary.Quantile, &dto.Quantile{
+				Quantile: proto.Float64(q),
+				Value:    proto.Float64(value),
+			})
+		}
+	case "histogram", "gaugehistogram":
+		if m.Histogram == nil {
+			m.Histogram = &dto.Histogram{}
+		}
+		switch suffix {
+		case "_count", "_gcount":
+			m.Histogram.SampleCount = proto.Uint64(uint64(value))
+		case "_sum", "_gsum":
+			m.Histogram.SampleSum = proto.Float64(value)
+		case "_created":
+			p.setCreated(f, m, value)
+		case "_bucket":
+			bound, err := strconv.ParseFloat(le, 64)
+			if err != nil {
+				return fmt.Errorf("invalid le %q", le)
+			}
+			m.Histogram.Bucket = append(m.Histogram.Bucket, &dto.Bucket{
+				UpperBound:      proto.Float64(bound),
+				CumulativeCount: proto.Uint64(uint64(value)),
+			})
+			if ex != nil {
+				ex.le = fmt.Sprint(bound)
+			}
+		default:
+			return fmt.Errorf("invalid sample %q of histogram %q", name, f.name)
+		}
+	default:
+		m.Untyped = &dto.Untyped{Value: proto.Float64(value)}
+	}
+
+	if ex != nil {
+		e := p.metricExtras(m)
+		e.exemplars = append(e.exemplars, ex)
+	}
+	return nil
+}
+
+// metric returns the metric of the family with the labels, it is created if
+// needed.
+func (f *omFamily) metric(labels map[string]string) *dto.Metric {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var sig strings.Builder
+	for _, k := range keys {
+		sig.WriteString(k)
+		sig.WriteByte(0xff)
+		sig.WriteString(labels[k])
+		sig.WriteByte(0xff)
+	}
+
+	m, ok := f.metrics[sig.String()]
+	if !ok {
+		m = &dto.Metric{}
+		for _, k := range keys {
+			m.Label = append(m.Label, &dto.LabelPair{
+				Name:  proto.String(k),
+				Value: proto.String(labels[k]),
+			})
+		}
+		f.metrics[sig.String()] = m
+		f.mf.Metric = append(f.mf.Metric, m)
+	}
+	return m
+}
+
+func (p *openMetricsParser) metricExtras(m *dto.Metric) *extras {
+	e, ok := p.extras[m]
+	if !ok {
+		e = &extras{}
+		p.extras[m] = e
+	}
+	return e
+}
+
+func (p *openMetricsParser) setCreated(f *omFamily, m *dto.Metric, value float64) {
+	e := p.metricExtras(m)
+	e.hasCreated = true
+	e.created = value
+	e.createdName = f.name + "_created"
+}
+
+func dtoType(typ string) dto.MetricType {
+	switch typ {
+	case "counter":
+		return dto.MetricType_COUNTER
+	case "gauge", "info", "stateset":
+		return dto.MetricType_GAUGE
+	case "summary":
+		return dto.MetricType_SUMMARY
+	case "histogram", "gaugehistogram":
+		return dto.MetricType_HISTOGRAM
+	default:
+		return dto.MetricType_UNTYPED
+	}
+}
+
+// scanner reads the parts of a sample line.
+type scanner struct {
+	text string
+	pos  int
+}
+
+func (s *scanner) done() bool {
+	return s.pos >= len(s.text)
+}
+
+func (s *scanner) rest() string {
+	return s.text[s.pos:]
+}
+
+func (s *scanner) peek(c byte) bool {
+	return !s.done() && s.text[s.pos] == c
+}
+
+func (s *scanner) consume(c byte) bool {
+	if s.peek(c) {
+		s.pos++
+		return true
+	}
+	return false
+}
+
+func (s *scanner) name() string {
+	start := s.pos
+	for !s.done() {
+		c := s.text[s.pos]
+		if c == '_' || c == ':' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' ||
+			s.pos > start && c >= '0' && c <= '9' {
+			s.pos++
+			continue
+		}
+		break
+	}
+	return s.text[start:s.pos]
+}
+
+// labels reads an optional label set.
+func (s *scanner) labels() (map[string]string, error) {
+	labels := make(map[string]string)
+	if !s.consume('{') {
+		return labels, nil
+	}
+	for !s.consume('}') {
+		name := s.name()
+		if name == "" {
+			return nil, fmt.Errorf("invalid label name at %q", s.rest())
+		}
+		if !s.consume('=') || !s.consume('"') {
+			return nil, fmt.Errorf("invalid label %q", name)
+		}
+
+		start := s.pos
+		for !s.peek('"') {
+			if s.done() {
+				return nil, fmt.Errorf("unterminated value of label %q", name)
+			}
+			if s.text[s.pos] == '\\' {
+				s.pos++
+			}
+			s.pos++
+		}
+		if _, ok := labels[name]; ok {
+			return nil, fmt.Errorf("duplicate label %q", name)
+		}
+		labels[name] = unescape(s.text[start:s.pos], true)
+		s.pos++
+
+		if !s.consume(',') && !s.peek('}') {
+			return nil, fmt.Errorf("invalid label set at %q", s.rest())
+		}
+	}
+	return labels, nil
+}
+
+// token reads up to the next space.
+func (s *scanner) token() string {
+	start := s.pos
+	for !s.done() && s.text[s.pos] != ' ' {
+		s.pos++
+	}
+	return s.text[start:s.pos]
+}
+
+func (s *scanner) float() (float64, error) {
+	text := s.token()
+	v, err := strconv.ParseFloat(text, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value %q", text)
+	}
+	return v, nil
+}
+
+// timestamp reads a timestamp in seconds since the epoch.
+func (s *scanner) timestamp() (time.Time, error) {
+	text := s.token()
+	v, err := strconv.ParseFloat(text, 64)
+	if err != nil || math.IsNaN(v) || math.IsInf(v, 0) {
+		return time.Time{}, fmt.Errorf("invalid timestamp %q", text)
+	}
+	sec, frac := math.Modf(v)
+	return time.Unix(int64(sec), int64(frac*1e9)), nil
+}
+
+// exemplar reads an exemplar of the form `# {labels} value [timestamp]`.
+func (s *scanner) exemplar(name string) (*exemplar, error) {
+	if !s.consume('#') || !s.consume(' ') || !s.peek('{') {
+		return nil, fmt.Errorf("invalid exemplar %q", s.rest())
+	}
+	labels, err := s.labels()
+	if err != nil {
+		return nil, err
+	}
+	if !s.consume(' ') {
+		return nil, fmt.Errorf("missing exemplar value")
+	}
+	value, err := s.float()
+	if err != nil {
+		return nil, err
+	}
+
+	e := &exemplar{name: name, labels: labels, value: value}
+	if s.consume(' ') {
+		t, err := s.timestamp()
+		if err != nil {
+			return nil, err
+		}
+		e.ts = &t
+	}
+	return e, nil
+}
+
+// unescape replaces the escape sequences of label values and help texts.
+func unescape(text string, quotes bool) string {
+	if !strings.Contains(text, `\`) {
+		return text
+	}
+	var b strings.Builder
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		if c == '\\' && i+1 < len(text) {
+			switch text[i+1] {
+			case 'n':
+				c = '\n'
+				i++
+			case '\\':
+				i++
+			case '"':
+				if quotes {
+					c = '"'
+					i++
+				}
+			}
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
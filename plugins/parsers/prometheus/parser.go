@@ -0,0 +1,386 @@
+package prometheus
+
+// Parser inspired from
+// https://github.com/prometheus/prom2json/blob/master/main.go
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"mime"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+
+	"github.com/matttproud/golang_protobuf_extensions/pbutil"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+)
+
+// Parser parses metrics in the Prometheus text exposition format, the
+// OpenMetrics text format or the delimited protocol buffer format.
+type Parser struct {
+	// MetricVersion selects how the metrics are converted, 1 (the default)
+	// uses the metric name as the measurement and 2 uses the "prometheus"
+	// measurement with the metric name as the field.
+	MetricVersion int
+
+	// Header of the response the data was read from, its Content-Type selects
+	// the format.  Without it the format is detected from the data.
+	Header http.Header
+
+	DefaultTags map[string]string
+
+	TimeFunc func() time.Time
+}
+
+// Parse returns a slice of Metrics from a text representation of a
+// metrics
+func (p *Parser) Parse(buf []byte) ([]telegraf.Metric, error) {
+	families, extras, err := p.readFamilies(buf)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if p.TimeFunc != nil {
+		now = p.TimeFunc()
+	}
+
+	var metrics []telegraf.Metric
+	for _, mf := range families {
+		for _, m := range mf.Metric {
+			t := now
+			if m.TimestampMs != nil && *m.TimestampMs > 0 {
+				t = time.Unix(0, *m.TimestampMs*1000000)
+			}
+
+			var ms []telegraf.Metric
+			if p.MetricVersion == 2 {
+				ms = makeMetricsV2(mf, m, extras[m], t)
+			} else {
+				ms = makeMetrics(mf, m, extras[m], t)
+			}
+			metrics = append(metrics, ms...)
+		}
+	}
+
+	for _, m := range metrics {
+		for k, v := range p.DefaultTags {
+			if !m.HasTag(k) {
+				m.AddTag(k, v)
+			}
+		}
+	}
+	return metrics, nil
+}
+
+// ParseLine returns the first metric of the line.
+func (p *Parser) ParseLine(line string) (telegraf.Metric, error) {
+	metrics, err := p.Parse([]byte(line + "\n"))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(metrics) < 1 {
+		return nil, fmt.Errorf("no metrics in line")
+	}
+
+	return metrics[0], nil
+}
+
+// SetDefaultTags sets the default tags for every metric
+func (p *Parser) SetDefaultTags(tags map[string]string) {
+	p.DefaultTags = tags
+}
+
+// readFamilies reads the metric families in the format given by the header,
+// or the one detected from the data, sorted by name.
+func (p *Parser) readFamilies(buf []byte) ([]*dto.MetricFamily, map[*dto.Metric]*extras, error) {
+	var mediatype string
+	var params map[string]string
+	if p.Header != nil {
+		mediatype, params, _ = mime.ParseMediaType(p.Header.Get("Content-Type"))
+	}
+
+	switch {
+	case mediatype == "application/vnd.google.protobuf" &&
+		params["encoding"] == "delimited" &&
+		params["proto"] == "io.prometheus.client.MetricFamily":
+		var families []*dto.MetricFamily
+		reader := bytes.NewReader(buf)
+		for {
+			mf := &dto.MetricFamily{}
+			if _, ierr := pbutil.ReadDelimited(reader, mf); ierr != nil {
+				if ierr == io.EOF {
+					break
+				}
+				return nil, nil, fmt.Errorf("reading metric family protocol buffer failed: %s", ierr)
+			}
+			families = append(families, mf)
+		}
+		return families, nil, nil
+	case mediatype == "application/openmetrics-text",
+		mediatype == "" && isOpenMetrics(buf):
+		families, extras, err := parseOpenMetrics(buf)
+		if err != nil {
+			return nil, nil, fmt.Errorf("reading openmetrics format failed: %s", err)
+		}
+		return families, extras, nil
+	}
+
+	var parser expfmt.TextParser
+	// parse even if the buffer begins with a newline
+	buf = bytes.TrimPrefix(buf, []byte("\n"))
+	reader := bufio.NewReader(bytes.NewReader(buf))
+	metricFamilies, err := parser.TextToMetricFamilies(reader)
+	if err != nil {
+		return nil, nil, fmt.Errorf("reading text format failed: %s", err)
+	}
+
+	families := make([]*dto.MetricFamily, 0, len(metricFamilies))
+	for _, mf := range metricFamilies {
+		families = append(families, mf)
+	}
+	sort.Slice(families, func(i, j int) bool {
+		return families[i].GetName() < families[j].GetName()
+	})
+	return families, nil, nil
+}
+
+// makeMetrics converts a metric to a telegraf metric named after the family,
+// with fields named after the type of the metric.
+func makeMetrics(mf *dto.MetricFamily, m *dto.Metric, ex *extras, t time.Time) []telegraf.Metric {
+	metricName := mf.GetName()
+	// reading tags
+	tags := makeLabels(m)
+	// reading fields
+	var fields map[string]interface{}
+	if mf.GetType() == dto.MetricType_SUMMARY {
+		// summary metric
+		fields = makeQuantiles(m)
+		fields["count"] = float64(m.GetSummary().GetSampleCount())
+		fields["sum"] = float64(m.GetSummary().GetSampleSum())
+	} else if mf.GetType() == dto.MetricType_HISTOGRAM {
+		// histogram metric
+		fields = makeBuckets(m)
+		fields["count"] = float64(m.GetHistogram().GetSampleCount())
+		fields["sum"] = float64(m.GetHistogram().GetSampleSum())
+	} else {
+		// standard metric
+		fields = getNameAndValue(m)
+	}
+	if ex != nil && ex.hasCreated {
+		fields["created"] = ex.created
+	}
+
+	var metrics []telegraf.Metric
+	// converting to telegraf metric
+	if len(fields) > 0 {
+		metric, err := metric.New(metricName, tags, fields, t, valueType(mf.GetType()))
+		if err == nil {
+			metrics = append(metrics, metric)
+		}
+	}
+
+	if ex != nil {
+		for _, e := range ex.exemplars {
+			fields := e.fields("exemplar")
+			metric, err := metric.New(metricName, e.tags(tags), fields, e.time(t), telegraf.Untyped)
+			if err == nil {
+				metrics = append(metrics, metric)
+			}
+		}
+	}
+	return metrics
+}
+
+// makeMetricsV2 converts a metric to telegraf metrics named "prometheus",
+// with fields named after the metric.
+func makeMetricsV2(mf *dto.MetricFamily, m *dto.Metric, ex *extras, t time.Time) []telegraf.Metric {
+	var metrics []telegraf.Metric
+	metricName := mf.GetName()
+	// reading tags
+	tags := makeLabels(m)
+
+	if mf.GetType() == dto.MetricType_SUMMARY {
+		// summary metric
+		metrics = makeQuantilesV2(m, tags, metricName, mf.GetType(), ex, t)
+	} else if mf.GetType() == dto.MetricType_HISTOGRAM {
+		// histogram metric
+		metrics = makeBucketsV2(m, tags, metricName, mf.GetType(), ex, t)
+	} else {
+		// standard metric
+		// reading fields
+		fields := getNameAndValueV2(m, metricName)
+		if ex != nil && ex.hasCreated {
+			fields[ex.createdName] = ex.created
+		}
+		// converting to telegraf metric
+		if len(fields) > 0 {
+			metric, err := metric.New("prometheus", tags, fields, t, valueType(mf.GetType()))
+			if err == nil {
+				metrics = append(metrics, metric)
+			}
+		}
+	}
+
+	if ex != nil {
+		for _, e := range ex.exemplars {
+			fields := e.fields(e.name + "_exemplar")
+			metric, err := metric.New("prometheus", e.tags(tags), fields, e.time(t), telegraf.Untyped)
+			if err == nil {
+				metrics = append(metrics, metric)
+			}
+		}
+	}
+	return metrics
+}
+
+// Get Quantiles for summary metric & Buckets for histogram
+func makeQuantilesV2(m *dto.Metric, tags map[string]string, metricName string, metricType dto.MetricType, ex *extras, t time.Time) []telegraf.Metric {
+	var metrics []telegraf.Metric
+	fields := make(map[string]interface{})
+	fields[metricName+"_count"] = float64(m.GetSummary().GetSampleCount())
+	fields[metricName+"_sum"] = float64(m.GetSummary().GetSampleSum())
+	if ex != nil && ex.hasCreated {
+		fields[ex.createdName] = ex.created
+	}
+	met, err := metric.New("prometheus", tags, fields, t, valueType(metricType))
+	if err == nil {
+		metrics = append(metrics, met)
+	}
+
+	for _, q := range m.GetSummary().Quantile {
+		newTags := tags
+		fields = make(map[string]interface{})
+		if !math.IsNaN(q.GetValue()) {
+			newTags["quantile"] = fmt.Sprint(q.GetQuantile())
+			fields[metricName] = float64(q.GetValue())
+
+			quantileMetric, err := metric.New("prometheus", newTags, fields, t, valueType(metricType))
+			if err == nil {
+				metrics = append(metrics, quantileMetric)
+			}
+		}
+	}
+	delete(tags, "quantile")
+	return metrics
+}
+
+// Get Buckets  from histogram metric
+func makeBucketsV2(m *dto.Metric, tags map[string]string, metricName string, metricType dto.MetricType, ex *extras, t time.Time) []telegraf.Metric {
+	var metrics []telegraf.Metric
+	fields := make(map[string]interface{})
+	fields[metricName+"_count"] = float64(m.GetHistogram().GetSampleCount())
+	fields[metricName+"_sum"] = float64(m.GetHistogram().GetSampleSum())
+	if ex != nil && ex.hasCreated {
+		fields[ex.createdName] = ex.created
+	}
+
+	met, err := metric.New("prometheus", tags, fields, t, valueType(metricType))
+	if err == nil {
+		metrics = append(metrics, met)
+	}
+
+	for _, b := range m.GetHistogram().Bucket {
+		newTags := tags
+		fields = make(map[string]interface{})
+		newTags["le"] = fmt.Sprint(b.GetUpperBound())
+		fields[metricName+"_bucket"] = float64(b.GetCumulativeCount())
+
+		histogramMetric, err := metric.New("prometheus", newTags, fields, t, valueType(metricType))
+		if err == nil {
+			metrics = append(metrics, histogramMetric)
+		}
+	}
+	delete(tags, "le")
+	return metrics
+}
+
+func valueType(mt dto.MetricType) telegraf.ValueType {
+	switch mt {
+	case dto.MetricType_COUNTER:
+		return telegraf.Counter
+	case dto.MetricType_GAUGE:
+		return telegraf.Gauge
+	case dto.MetricType_SUMMARY:
+		return telegraf.Summary
+	case dto.MetricType_HISTOGRAM:
+		return telegraf.Histogram
+	default:
+		return telegraf.Untyped
+	}
+}
+
+// Get Quantiles from summary metric
+func makeQuantiles(m *dto.Metric) map[string]interface{} {
+	fields := make(map[string]interface{})
+	for _, q := range m.GetSummary().Quantile {
+		if !math.IsNaN(q.GetValue()) {
+			fields[fmt.Sprint(q.GetQuantile())] = float64(q.GetValue())
+		}
+	}
+	return fields
+}
+
+// Get Buckets  from histogram metric
+func makeBuckets(m *dto.Metric) map[string]interface{} {
+	fields := make(map[string]interface{})
+	for _, b := range m.GetHistogram().Bucket {
+		fields[fmt.Sprint(b.GetUpperBound())] = float64(b.GetCumulativeCount())
+	}
+	return fields
+}
+
+// Get labels from metric
+func makeLabels(m *dto.Metric) map[string]string {
+	result := map[string]string{}
+	for _, lp := range m.Label {
+		result[lp.GetName()] = lp.GetValue()
+	}
+	return result
+}
+
+// Get name and value from metric
+func getNameAndValue(m *dto.Metric) map[string]interface{} {
+	fields := make(map[string]interface{})
+	if m.Gauge != nil {
+		if !math.IsNaN(m.GetGauge().GetValue()) {
+			fields["gauge"] = float64(m.GetGauge().GetValue())
+		}
+	} else if m.Counter != nil {
+		if !math.IsNaN(m.GetCounter().GetValue()) {
+			fields["counter"] = float64(m.GetCounter().GetValue())
+		}
+	} else if m.Untyped != nil {
+		if !math.IsNaN(m.GetUntyped().GetValue()) {
+			fields["value"] = float64(m.GetUntyped().GetValue())
+		}
+	}
+	return fields
+}
+
+// Get name and value from metric
+func getNameAndValueV2(m *dto.Metric, metricName string) map[string]interface{} {
+	fields := make(map[string]interface{})
+	if m.Gauge != nil {
+		if !math.IsNaN(m.GetGauge().GetValue()) {
+			fields[metricName] = float64(m.GetGauge().GetValue())
+		}
+	} else if m.Counter != nil {
+		if !math.IsNaN(m.GetCounter().GetValue()) {
+			fields[metricName] = float64(m.GetCounter().GetValue())
+		}
+	} else if m.Untyped != nil {
+		if !math.IsNaN(m.GetUntyped().GetValue()) {
+			fields[metricName] = float64(m.GetUntyped().GetValue())
+		}
+	}
+	return fields
+}
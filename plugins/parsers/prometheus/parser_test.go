@@ -0,0 +1,390 @@
+package prometheus
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var exptime = time.Date(2009, time.November, 10, 23, 0, 0, 0, time.UTC)
+
+const validUniqueGauge = `# HELP cadvisor_version_info A metric with a constant '1' value labeled by kernel version, OS version, docker version, cadvisor version & cadvisor revision.
+# TYPE cadvisor_version_info gauge
+cadvisor_version_info{cadvisorRevision="",cadvisorVersion="",dockerVersion="1.8.2",kernelVersion="3.10.0-229.20.1.el7.x86_64",osVersion="CentOS Linux 7 (Core)"} 1
+`
+
+const validUniqueCounter = `# HELP get_token_fail_count Counter of failed Token() requests to the alternate token source
+# TYPE get_token_fail_count counter
+get_token_fail_count 0
+`
+
+const validUniqueLine = `# HELP get_token_fail_count Counter of failed Token() requests to the alternate token source
+`
+
+const validUniqueSummary = `# HELP http_request_duration_microseconds The HTTP request latencies in microseconds.
+# TYPE http_request_duration_microseconds summary
+http_request_duration_microseconds{handler="prometheus",quantile="0.5"} 552048.506
+http_request_duration_microseconds{handler="prometheus",quantile="0.9"} 5.876804288e+06
+http_request_duration_microseconds{handler="prometheus",quantile="0.99"} 5.876804288e+06
+http_request_duration_microseconds_sum{handler="prometheus"} 1.8909097205e+07
+http_request_duration_microseconds_count{handler="prometheus"} 9
+`
+
+const validUniqueHistogram = `# HELP apiserver_request_latencies Response latency distribution in microseconds for each verb, resource and client.
+# TYPE apiserver_request_latencies histogram
+apiserver_request_latencies_bucket{resource="bindings",verb="POST",le="125000"} 1994
+apiserver_request_latencies_bucket{resource="bindings",verb="POST",le="250000"} 1997
+apiserver_request_latencies_bucket{resource="bindings",verb="POST",le="500000"} 2000
+apiserver_request_latencies_bucket{resource="bindings",verb="POST",le="1e+06"} 2005
+apiserver_request_latencies_bucket{resource="bindings",verb="POST",le="2e+06"} 2012
+apiserver_request_latencies_bucket{resource="bindings",verb="POST",le="4e+06"} 2017
+apiserver_request_latencies_bucket{resource="bindings",verb="POST",le="8e+06"} 2024
+apiserver_request_latencies_bucket{resource="bindings",verb="POST",le="+Inf"} 2025
+apiserver_request_latencies_sum{resource="bindings",verb="POST"} 1.02726334e+08
+apiserver_request_latencies_count{resource="bindings",verb="POST"} 2025
+`
+
+const validData = `# HELP cadvisor_version_info A metric with a constant '1' value labeled by kernel version, OS version, docker version, cadvisor version & cadvisor revision.
+# TYPE cadvisor_version_info gauge
+cadvisor_version_info{cadvisorRevision="",cadvisorVersion="",dockerVersion="1.8.2",kernelVersion="3.10.0-229.20.1.el7.x86_64",osVersion="CentOS Linux 7 (Core)"} 1
+# HELP go_gc_duration_seconds A summary of the GC invocation durations.
+# TYPE go_gc_duration_seconds summary
+go_gc_duration_seconds{quantile="0"} 0.013534896000000001
+go_gc_duration_seconds{quantile="0.25"} 0.02469263
+go_gc_duration_seconds{quantile="0.5"} 0.033727822000000005
+go_gc_duration_seconds{quantile="0.75"} 0.03840335
+go_gc_duration_seconds{quantile="1"} 0.049956604
+go_gc_duration_seconds_sum 1970.341293002
+go_gc_duration_seconds_count 65952
+# HELP http_request_duration_microseconds The HTTP request latencies in microseconds.
+# TYPE http_request_duration_microseconds summary
+http_request_duration_microseconds{handler="prometheus",quantile="0.5"} 552048.506
+http_request_duration_microseconds{handler="prometheus",quantile="0.9"} 5.876804288e+06
+http_request_duration_microseconds{handler="prometheus",quantile="0.99"} 5.876804288e+06
+http_request_duration_microseconds_sum{handler="prometheus"} 1.8909097205e+07
+http_request_duration_microseconds_count{handler="prometheus"} 9
+# HELP get_token_fail_count Counter of failed Token() requests to the alternate token source
+# TYPE get_token_fail_count counter
+get_token_fail_count 0
+# HELP apiserver_request_latencies Response latency distribution in microseconds for each verb, resource and client.
+# TYPE apiserver_request_latencies histogram
+apiserver_request_latencies_bucket{resource="bindings",verb="POST",le="125000"} 1994
+apiserver_request_latencies_bucket{resource="bindings",verb="POST",le="250000"} 1997
+apiserver_request_latencies_bucket{resource="bindings",verb="POST",le="500000"} 2000
+apiserver_request_latencies_bucket{resource="bindings",verb="POST",le="1e+06"} 2005
+apiserver_request_latencies_bucket{resource="bindings",verb="POST",le="2e+06"} 2012
+apiserver_request_latencies_bucket{resource="bindings",verb="POST",le="4e+06"} 2017
+apiserver_request_latencies_bucket{resource="bindings",verb="POST",le="8e+06"} 2024
+apiserver_request_latencies_bucket{resource="bindings",verb="POST",le="+Inf"} 2025
+apiserver_request_latencies_sum{resource="bindings",verb="POST"} 1.02726334e+08
+apiserver_request_latencies_count{resource="bindings",verb="POST"} 2025
+`
+
+const prometheusMulti = `
+cpu,host=foo,datacenter=us-east usage_idle=99,usage_busy=1
+cpu,host=foo,datacenter=us-east usage_idle=99,usage_busy=1
+cpu,host=foo,datacenter=us-east usage_idle=99,usage_busy=1
+cpu,host=foo,datacenter=us-east usage_idle=99,usage_busy=1
+cpu,host=foo,datacenter=us-east usage_idle=99,usage_busy=1
+cpu,host=foo,datacenter=us-east usage_idle=99,usage_busy=1
+cpu,host=foo,datacenter=us-east usage_idle=99,usage_busy=1
+`
+
+const prometheusMultiSomeInvalid = `
+cpu,host=foo,datacenter=us-east usage_idle=99,usage_busy=1
+cpu,host=foo,datacenter=us-east usage_idle=99,usage_busy=1
+cpu,host=foo,datacenter=us-east usage_idle=99,usage_busy=1
+cpu,cpu=cpu3, host=foo,datacenter=us-east usage_idle=99,usage_busy=1
+cpu,cpu=cpu4 , usage_idle=99,usage_busy=1
+cpu,host=foo,datacenter=us-east usage_idle=99,usage_busy=1
+`
+
+func TestParseValidPrometheus(t *testing.T) {
+	parser := &Parser{}
+
+	// Gauge value
+	metrics, err := parser.Parse([]byte(validUniqueGauge))
+	assert.NoError(t, err)
+	assert.Len(t, metrics, 1)
+	assert.Equal(t, "cadvisor_version_info", metrics[0].Name())
+	assert.Equal(t, map[string]interface{}{
+		"gauge": float64(1),
+	}, metrics[0].Fields())
+	assert.Equal(t, map[string]string{
+		"osVersion":        "CentOS Linux 7 (Core)",
+		"cadvisorRevision": "",
+		"cadvisorVersion":  "",
+		"dockerVersion":    "1.8.2",
+		"kernelVersion":    "3.10.0-229.20.1.el7.x86_64",
+	}, metrics[0].Tags())
+
+	// Counter value
+	metrics, err = parser.Parse([]byte(validUniqueCounter))
+	assert.NoError(t, err)
+	assert.Len(t, metrics, 1)
+	assert.Equal(t, "get_token_fail_count", metrics[0].Name())
+	assert.Equal(t, map[string]interface{}{
+		"counter": float64(0),
+	}, metrics[0].Fields())
+	assert.Equal(t, map[string]string{}, metrics[0].Tags())
+
+	// Summary data
+	//SetDefaultTags(map[string]string{})
+	metrics, err = parser.Parse([]byte(validUniqueSummary))
+	assert.NoError(t, err)
+	assert.Len(t, metrics, 1)
+	assert.Equal(t, "http_request_duration_microseconds", metrics[0].Name())
+	assert.Equal(t, map[string]interface{}{
+		"0.5":   552048.506,
+		"0.9":   5.876804288e+06,
+		"0.99":  5.876804288e+06,
+		"count": 9.0,
+		"sum":   1.8909097205e+07,
+	}, metrics[0].Fields())
+	assert.Equal(t, map[string]string{"handler": "prometheus"}, metrics[0].Tags())
+
+	// histogram data
+	metrics, err = parser.Parse([]byte(validUniqueHistogram))
+	assert.NoError(t, err)
+	assert.Len(t, metrics, 1)
+	assert.Equal(t, "apiserver_request_latencies", metrics[0].Name())
+	assert.Equal(t, map[string]interface{}{
+		"500000": 2000.0,
+		"count":  2025.0,
+		"sum":    1.02726334e+08,
+		"250000": 1997.0,
+		"2e+06":  2012.0,
+		"4e+06":  2017.0,
+		"8e+06":  2024.0,
+		"+Inf":   2025.0,
+		"125000": 1994.0,
+		"1e+06":  2005.0,
+	}, metrics[0].Fields())
+	assert.Equal(t,
+		map[string]string{"verb": "POST", "resource": "bindings"},
+		metrics[0].Tags())
+
+}
+
+func TestParseValidPrometheusV2(t *testing.T) {
+	parser := &Parser{MetricVersion: 2, TimeFunc: func() time.Time { return exptime }}
+
+	metrics, err := parser.Parse([]byte(validUniqueSummary))
+	require.NoError(t, err)
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric("prometheus",
+			map[string]string{"handler": "prometheus"},
+			map[string]interface{}{
+				"http_request_duration_microseconds_count": 9.0,
+				"http_request_duration_microseconds_sum":   1.8909097205e+07,
+			},
+			exptime, telegraf.Summary),
+		testutil.MustMetric("prometheus",
+			map[string]string{"handler": "prometheus", "quantile": "0.5"},
+			map[string]interface{}{"http_request_duration_microseconds": 552048.506},
+			exptime, telegraf.Summary),
+		testutil.MustMetric("prometheus",
+			map[string]string{"handler": "prometheus", "quantile": "0.9"},
+			map[string]interface{}{"http_request_duration_microseconds": 5.876804288e+06},
+			exptime, telegraf.Summary),
+		testutil.MustMetric("prometheus",
+			map[string]string{"handler": "prometheus", "quantile": "0.99"},
+			map[string]interface{}{"http_request_duration_microseconds": 5.876804288e+06},
+			exptime, telegraf.Summary),
+	}
+	testutil.RequireMetricsEqual(t, expected, metrics)
+}
+
+func TestParseDefaultTags(t *testing.T) {
+	parser := &Parser{}
+	parser.SetDefaultTags(map[string]string{"source": "file", "osVersion": "unknown"})
+
+	metrics, err := parser.Parse([]byte(validUniqueGauge))
+	require.NoError(t, err)
+	require.Len(t, metrics, 1)
+	require.Equal(t, "file", metrics[0].Tags()["source"])
+	require.Equal(t, "CentOS Linux 7 (Core)", metrics[0].Tags()["osVersion"])
+}
+
+const validOpenMetrics = `# TYPE acme_http_router_request_seconds summary
+# UNIT acme_http_router_request_seconds seconds
+# HELP acme_http_router_request_seconds Latency though all of ACME's HTTP request router.
+acme_http_router_request_seconds_sum{path="/api/v1",method="GET"} 9036.32
+acme_http_router_request_seconds_count{path="/api/v1",method="GET"} 807283.0
+acme_http_router_request_seconds_created{path="/api/v1",method="GET"} 1605281325.0
+# TYPE go_goroutines gauge
+go_goroutines 69
+# TYPE process_cpu_seconds counter
+# UNIT process_cpu_seconds seconds
+# HELP process_cpu_seconds Total user and system CPU time spent in seconds.
+process_cpu_seconds_total 4.2 1605281325.5 # {trace_id="KOO5S4vxi0o"} 0.67
+process_cpu_seconds_created 1605281300.0
+# TYPE build info
+build_info{version="1.2.3",revision="abc"} 1
+# TYPE feature stateset
+feature{feature="a"} 1
+feature{feature="b"} 0
+# TYPE request_size histogram
+request_size_bucket{le="100"} 2 # {trace_id="oHg5SJYRHA0"} 42 1605281325.0
+request_size_bucket{le="+Inf"} 3
+request_size_count 3
+request_size_sum 242
+# TYPE queue_size gaugehistogram
+queue_size_bucket{le="10"} 4
+queue_size_bucket{le="+Inf"} 5
+queue_size_gcount 5
+queue_size_gsum 21
+untyped_value{label="a \"quoted\" value"} 7
+# EOF
+`
+
+func TestParseOpenMetrics(t *testing.T) {
+	parser := &Parser{TimeFunc: func() time.Time { return exptime }}
+
+	metrics, err := parser.Parse([]byte(validOpenMetrics))
+	require.NoError(t, err)
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric("acme_http_router_request_seconds",
+			map[string]string{"path": "/api/v1", "method": "GET"},
+			map[string]interface{}{
+				"count":   807283.0,
+				"sum":     9036.32,
+				"created": 1605281325.0,
+			},
+			exptime, telegraf.Summary),
+		testutil.MustMetric("go_goroutines",
+			map[string]string{},
+			map[string]interface{}{"gauge": 69.0},
+			exptime, telegraf.Gauge),
+		testutil.MustMetric("process_cpu_seconds_total",
+			map[string]string{},
+			map[string]interface{}{"counter": 4.2, "created": 1605281300.0},
+			time.Unix(1605281325, 500000000), telegraf.Counter),
+		testutil.MustMetric("process_cpu_seconds_total",
+			map[string]string{},
+			map[string]interface{}{"exemplar": 0.67, "trace_id": "KOO5S4vxi0o"},
+			time.Unix(1605281325, 500000000), telegraf.Untyped),
+		testutil.MustMetric("build_info",
+			map[string]string{"version": "1.2.3", "revision": "abc"},
+			map[string]interface{}{"gauge": 1.0},
+			exptime, telegraf.Gauge),
+		testutil.MustMetric("feature",
+			map[string]string{"feature": "a"},
+			map[string]interface{}{"gauge": 1.0},
+			exptime, telegraf.Gauge),
+		testutil.MustMetric("feature",
+			map[string]string{"feature": "b"},
+			map[string]interface{}{"gauge": 0.0},
+			exptime, telegraf.Gauge),
+		testutil.MustMetric("request_size",
+			map[string]string{},
+			map[string]interface{}{"100": 2.0, "+Inf": 3.0, "count": 3.0, "sum": 242.0},
+			exptime, telegraf.Histogram),
+		testutil.MustMetric("request_size",
+			map[string]string{"le": "100"},
+			map[string]interface{}{"exemplar": 42.0, "trace_id": "oHg5SJYRHA0"},
+			time.Unix(1605281325, 0), telegraf.Untyped),
+		testutil.MustMetric("queue_size",
+			map[string]string{},
+			map[string]interface{}{"10": 4.0, "+Inf": 5.0, "count": 5.0, "sum": 21.0},
+			exptime, telegraf.Histogram),
+		testutil.MustMetric("untyped_value",
+			map[string]string{"label": `a "quoted" value`},
+			map[string]interface{}{"value": 7.0},
+			exptime, telegraf.Untyped),
+	}
+	testutil.RequireMetricsEqual(t, expected, metrics)
+}
+
+func TestParseOpenMetricsV2(t *testing.T) {
+	parser := &Parser{MetricVersion: 2, TimeFunc: func() time.Time { return exptime }}
+
+	metrics, err := parser.Parse([]byte(`# TYPE process_cpu_seconds counter
+process_cpu_seconds_total 4.2 # {trace_id="KOO5S4vxi0o"} 0.67
+process_cpu_seconds_created 1605281300.0
+# EOF
+`))
+	require.NoError(t, err)
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric("prometheus",
+			map[string]string{},
+			map[string]interface{}{
+				"process_cpu_seconds_total":   4.2,
+				"process_cpu_seconds_created": 1605281300.0,
+			},
+			exptime, telegraf.Counter),
+		testutil.MustMetric("prometheus",
+			map[string]string{},
+			map[string]interface{}{
+				"process_cpu_seconds_total_exemplar": 0.67,
+				"trace_id":                           "KOO5S4vxi0o",
+			},
+			exptime, telegraf.Untyped),
+	}
+	testutil.RequireMetricsEqual(t, expected, metrics)
+}
+
+func TestParseOpenMetricsContentType(t *testing.T) {
+	header := http.Header{}
+	header.Set("Content-Type", "application/openmetrics-text; version=1.0.0; charset=utf-8")
+	parser := &Parser{Header: header}
+
+	// The content type selects OpenMetrics, which requires the # EOF line.
+	_, err := parser.Parse([]byte(validUniqueCounter))
+	require.Error(t, err)
+
+	metrics, err := parser.Parse([]byte(validUniqueCounter + "# EOF\n"))
+	require.NoError(t, err)
+	require.Len(t, metrics, 1)
+	require.Equal(t, "get_token_fail_count", metrics[0].Name())
+}
+
+func TestParseOpenMetricsInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{
+			name: "data after eof",
+			data: "foo 1\n# EOF\nbar 2\n",
+		},
+		{
+			name: "empty line",
+			data: "foo 1\n\nbar 2\n# EOF\n",
+		},
+		{
+			name: "unknown type",
+			data: "# TYPE foo bar\nfoo 1\n# EOF\n",
+		},
+		{
+			name: "second type",
+			data: "# TYPE foo gauge\n# TYPE foo counter\nfoo 1\n# EOF\n",
+		},
+		{
+			name: "invalid value",
+			data: "foo one\n# EOF\n",
+		},
+		{
+			name: "unterminated label",
+			data: "foo{a=\"b} 1\n# EOF\n",
+		},
+		{
+			name: "invalid exemplar",
+			data: "foo_total 1 # 2\n# EOF\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := parseOpenMetrics([]byte(tt.data))
+			require.Error(t, err)
+		})
+	}
+}
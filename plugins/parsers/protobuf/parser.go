@@ -0,0 +1,184 @@
+package protobuf
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/parsers/internal/flat"
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/desc/protoparse"
+	"github.com/jhump/protoreflect/dynamic"
+)
+
+const timestampType = "google.protobuf.Timestamp"
+
+// Config holds the options of the protobuf parser.
+type Config struct {
+	MetricName string
+	// Files are the .proto files defining the message type.
+	Files []string
+	// ImportPaths are searched for the files and their imports.
+	ImportPaths []string
+	// MessageType is the fully qualified name of the message.
+	MessageType string
+
+	TagKeys         []string
+	FieldKeys       []string
+	TimestampKey    string
+	TimestampFormat string
+	Timezone        string
+	DefaultTags     map[string]string
+}
+
+// Parser decodes binary protocol buffer messages using descriptors loaded at
+// runtime.  Nested messages are flattened, keys are joined by "_".
+type Parser struct {
+	flat.MetricBuilder
+
+	descriptor *desc.MessageDescriptor
+}
+
+// New loads the message type from the .proto files.
+func New(config *Config) (*Parser, error) {
+	if len(config.Files) == 0 {
+		return nil, fmt.Errorf("protobuf_files must be set")
+	}
+	if config.MessageType == "" {
+		return nil, fmt.Errorf("protobuf_message_type must be set")
+	}
+
+	p := protoparse.Parser{ImportPaths: config.ImportPaths}
+	fds, err := p.ParseFiles(config.Files...)
+	if err != nil {
+		return nil, fmt.Errorf("loading protobuf files failed: %v", err)
+	}
+
+	md := findMessage(fds, config.MessageType)
+	if md == nil {
+		return nil, fmt.Errorf("message type %q not found", config.MessageType)
+	}
+
+	format := config.TimestampFormat
+	if format == "" {
+		format = "unix"
+	}
+
+	return &Parser{
+		MetricBuilder: flat.MetricBuilder{
+			MetricName:      config.MetricName,
+			TagKeys:         config.TagKeys,
+			FieldKeys:       config.FieldKeys,
+			TimestampKey:    config.TimestampKey,
+			TimestampFormat: format,
+			Timezone:        config.Timezone,
+			DefaultTags:     config.DefaultTags,
+			TimeFunc:        time.Now,
+		},
+		descriptor: md,
+	}, nil
+}
+
+// Parse decodes a single message into a metric.
+func (p *Parser) Parse(buf []byte) ([]telegraf.Metric, error) {
+	msg := dynamic.NewMessage(p.descriptor)
+	if err := msg.Unmarshal(buf); err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]interface{})
+	flatten(values, "", msg)
+	return p.Metrics(values)
+}
+
+func (p *Parser) ParseLine(line string) (telegraf.Metric, error) {
+	return flat.ParseLine(p.Parse, line)
+}
+
+// flatten adds the fields of the message to values.  Repeated fields are
+// suffixed by their index and map fields by their key.
+func flatten(values map[string]interface{}, prefix string, msg *dynamic.Message) {
+	for _, fd := range msg.GetKnownFields() {
+		// Unset messages are left out, scalar fields use their default.
+		if fd.GetMessageType() != nil && !msg.HasField(fd) {
+			continue
+		}
+
+		key := fd.GetName()
+		if prefix != "" {
+			key = prefix + "_" + key
+		}
+
+		switch value := msg.GetField(fd).(type) {
+		case []interface{}:
+			for i, v := range value {
+				addValue(values, key+"_"+strconv.Itoa(i), fd, v)
+			}
+		case map[interface{}]interface{}:
+			vd := fd.GetMapValueType()
+			for k, v := range value {
+				addValue(values, fmt.Sprintf("%s_%v", key, k), vd, v)
+			}
+		default:
+			addValue(values, key, fd, value)
+		}
+	}
+}
+
+func addValue(values map[string]interface{}, key string, fd *desc.FieldDescriptor, value interface{}) {
+	switch v := value.(type) {
+	case *dynamic.Message:
+		if v.GetMessageDescriptor().GetFullyQualifiedName() == timestampType {
+			seconds, _ := v.GetFieldByName("seconds").(int64)
+			nanos, _ := v.GetFieldByName("nanos").(int32)
+			values[key] = time.Unix(seconds, int64(nanos)).UTC()
+			return
+		}
+		flatten(values, key, v)
+	case proto.Message:
+		// Well-known types may be decoded to their generated type.
+		if msg, err := dynamic.AsDynamicMessage(v); err == nil {
+			addValue(values, key, fd, msg)
+		}
+	case int32:
+		if fd.GetType() == descriptor.FieldDescriptorProto_TYPE_ENUM {
+			if ev := fd.GetEnumType().FindValueByNumber(v); ev != nil {
+				values[key] = ev.GetName()
+				return
+			}
+		}
+		values[key] = int64(v)
+	case int64:
+		values[key] = v
+	case uint32:
+		values[key] = uint64(v)
+	case uint64:
+		values[key] = v
+	case float32:
+		values[key] = float64(v)
+	case float64:
+		values[key] = v
+	case bool:
+		values[key] = v
+	case string:
+		values[key] = v
+	case []byte:
+		values[key] = string(v)
+	}
+}
+
+// findMessage returns the message from the files or their dependencies.
+func findMessage(fds []*desc.FileDescriptor, name string) *desc.MessageDescriptor {
+	for _, fd := range fds {
+		if md := fd.FindMessage(name); md != nil {
+			return md
+		}
+		if md := findMessage(fd.GetDependencies(), name); md != nil {
+			return md
+		}
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+package protobuf
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/jhump/protoreflect/dynamic"
+	"github.com/stretchr/testify/require"
+)
+
+func newParser(t *testing.T, config *Config) *Parser {
+	config.MetricName = "sensor"
+	config.Files = []string{"sensor.proto"}
+	config.ImportPaths = []string{"testdata"}
+	config.MessageType = "telegraf.test.Reading"
+
+	p, err := New(config)
+	require.NoError(t, err)
+	p.TimeFunc = func() time.Time { return time.Unix(42, 0) }
+	return p
+}
+
+func newReading(t *testing.T, p *Parser) []byte {
+	msg := dynamic.NewMessage(p.descriptor)
+	msg.SetFieldByName("device", "dev-1")
+	msg.SetFieldByName("temperature", 21.5)
+	msg.SetFieldByName("uptime", uint64(3600))
+	msg.SetFieldByName("active", true)
+	msg.SetFieldByName("status", int32(1))
+	msg.SetFieldByName("sequence", int64(7))
+
+	ts := dynamic.NewMessage(p.descriptor.FindFieldByName("time").GetMessageType())
+	ts.SetFieldByName("seconds", int64(1614592800))
+	ts.SetFieldByName("nanos", int32(500))
+	msg.SetFieldByName("time", ts)
+
+	loc := dynamic.NewMessage(p.descriptor.FindFieldByName("location").GetMessageType())
+	loc.SetFieldByName("building", "north")
+	loc.SetFieldByName("floor", int32(3))
+	msg.SetFieldByName("location", loc)
+
+	msg.AddRepeatedFieldByName("voltages", float32(1.5))
+	msg.AddRepeatedFieldByName("voltages", float32(2.5))
+	msg.PutMapFieldByName("counters", "errors", int64(2))
+
+	buf, err := msg.Marshal()
+	require.NoError(t, err)
+	return buf
+}
+
+func TestParse(t *testing.T) {
+	p := newParser(t, &Config{
+		TagKeys:      []string{"device", "location_building"},
+		TimestampKey: "time",
+	})
+
+	metrics, err := p.Parse(newReading(t, p))
+	require.NoError(t, err)
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric("sensor",
+			map[string]string{"device": "dev-1", "location_building": "north"},
+			map[string]interface{}{
+				"temperature":     21.5,
+				"uptime":          uint64(3600),
+				"active":          true,
+				"status":          "OK",
+				"location_floor":  int64(3),
+				"voltages_0":      1.5,
+				"voltages_1":      2.5,
+				"counters_errors": int64(2),
+				"sequence":        int64(7),
+			},
+			time.Unix(1614592800, 500)),
+	}
+	testutil.RequireMetricsEqual(t, expected, metrics)
+}
+
+func TestParseFieldKeys(t *testing.T) {
+	p := newParser(t, &Config{
+		FieldKeys:       []string{"temperature"},
+		TimestampKey:    "sequence",
+		TimestampFormat: "unix_ms",
+	})
+	p.SetDefaultTags(map[string]string{"source": "kafka"})
+
+	m, err := p.ParseLine(string(newReading(t, p)))
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"source": "kafka"}, m.Tags())
+	require.Equal(t, map[string]interface{}{"temperature": 21.5}, m.Fields())
+	require.Equal(t, time.Unix(0, 7*int64(time.Millisecond)).UTC(), m.Time())
+}
+
+func TestParseDefaultValues(t *testing.T) {
+	p := newParser(t, &Config{})
+
+	metrics, err := p.Parse([]byte{})
+	require.NoError(t, err)
+	require.Len(t, metrics, 1)
+	require.Equal(t, time.Unix(42, 0), metrics[0].Time())
+	require.Equal(t, "UNKNOWN", metrics[0].Fields()["status"])
+	require.Equal(t, int64(0), metrics[0].Fields()["sequence"])
+	require.NotContains(t, metrics[0].Fields(), "location_floor")
+}
+
+func TestParseInvalidMessage(t *testing.T) {
+	p := newParser(t, &Config{})
+
+	_, err := p.Parse([]byte{0xff, 0xff, 0xff})
+	require.Error(t, err)
+}
+
+func TestNewInvalidConfig(t *testing.T) {
+	_, err := New(&Config{MessageType: "telegraf.test.Reading"})
+	require.Error(t, err)
+
+	_, err = New(&Config{
+		Files:       []string{"sensor.proto"},
+		ImportPaths: []string{"testdata"},
+		MessageType: "telegraf.test.Missing",
+	})
+	require.Error(t, err)
+
+	_, err = New(&Config{
+		Files:       []string{"missing.proto"},
+		ImportPaths: []string{"testdata"},
+		MessageType: "telegraf.test.Reading",
+	})
+	require.Error(t, err)
+}
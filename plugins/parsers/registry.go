@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/parsers/avro"
 	"github.com/influxdata/telegraf/plugins/parsers/collectd"
 	"github.com/influxdata/telegraf/plugins/parsers/csv"
 	"github.com/influxdata/telegraf/plugins/parsers/dropwizard"
@@ -13,10 +14,14 @@ import (
 	"github.com/influxdata/telegraf/plugins/parsers/grok"
 	"github.com/influxdata/telegraf/plugins/parsers/influx"
 	"github.com/influxdata/telegraf/plugins/parsers/json"
+	"github.com/influxdata/telegraf/plugins/parsers/json_v2"
 	"github.com/influxdata/telegraf/plugins/parsers/logfmt"
 	"github.com/influxdata/telegraf/plugins/parsers/nagios"
+	"github.com/influxdata/telegraf/plugins/parsers/prometheus"
+	"github.com/influxdata/telegraf/plugins/parsers/protobuf"
 	"github.com/influxdata/telegraf/plugins/parsers/value"
 	"github.com/influxdata/telegraf/plugins/parsers/wavefront"
+	"github.com/influxdata/telegraf/plugins/parsers/xml"
 )
 
 type ParserFunc func() (Parser, error)
@@ -92,6 +97,9 @@ type Config struct {
 	// Whether to continue if a JSON object can't be coerced
 	JSONStrict bool `toml:"json_strict"`
 
+	// JSONV2Config only applies to the json_v2 data format
+	JSONV2Config []json_v2.Config `toml:"json_v2"`
+
 	// Authentication file for collectd
 	CollectdAuthFile string `toml:"collectd_auth_file"`
 	// One of none (default), sign, or encrypt
@@ -148,6 +156,33 @@ type Config struct {
 
 	// FormData configuration
 	FormUrlencodedTagKeys []string `toml:"form_urlencoded_tag_keys"`
+
+	// Prometheus configuration, the metric version is 1 or 2
+	PrometheusMetricVersion int `toml:"prometheus_metric_version"`
+
+	// Protobuf configuration, messages are decoded using the message type
+	// defined in the .proto files
+	ProtobufFiles           []string `toml:"protobuf_files"`
+	ProtobufImportPaths     []string `toml:"protobuf_import_paths"`
+	ProtobufMessageType     string   `toml:"protobuf_message_type"`
+	ProtobufTagKeys         []string `toml:"protobuf_tag_keys"`
+	ProtobufFieldKeys       []string `toml:"protobuf_field_keys"`
+	ProtobufTimestampKey    string   `toml:"protobuf_timestamp_key"`
+	ProtobufTimestampFormat string   `toml:"protobuf_timestamp_format"`
+	ProtobufTimezone        string   `toml:"protobuf_timezone"`
+
+	// Avro configuration, the schema is read from a file or fetched from a
+	// schema registry
+	AvroSchemaFile      string   `toml:"avro_schema_file"`
+	AvroSchemaRegistry  string   `toml:"avro_schema_registry"`
+	AvroTagKeys         []string `toml:"avro_tag_keys"`
+	AvroFieldKeys       []string `toml:"avro_field_keys"`
+	AvroTimestampKey    string   `toml:"avro_timestamp_key"`
+	AvroTimestampFormat string   `toml:"avro_timestamp_format"`
+	AvroTimezone        string   `toml:"avro_timezone"`
+
+	// XML configuration, one entry per kind of metric in the document
+	XMLConfig []xml.Config `toml:"xml"`
 }
 
 // NewParser returns a Parser interface based on the given config.
@@ -170,6 +205,12 @@ func NewParser(config *Config) (Parser, error) {
 				Strict:       config.JSONStrict,
 			},
 		)
+	case "json_v2":
+		parser, err = NewJSONV2Parser(
+			config.MetricName,
+			config.JSONV2Config,
+			config.DefaultTags,
+		)
 	case "value":
 		parser, err = NewValueParser(config.MetricName,
 			config.DataType, config.DefaultTags)
@@ -227,6 +268,46 @@ func NewParser(config *Config) (Parser, error) {
 			config.DefaultTags,
 			config.FormUrlencodedTagKeys,
 		)
+	case "prometheus":
+		parser, err = NewPrometheusParser(
+			config.PrometheusMetricVersion,
+			config.DefaultTags,
+		)
+	case "protobuf":
+		parser, err = protobuf.New(
+			&protobuf.Config{
+				MetricName:      config.MetricName,
+				Files:           config.ProtobufFiles,
+				ImportPaths:     config.ProtobufImportPaths,
+				MessageType:     config.ProtobufMessageType,
+				TagKeys:         config.ProtobufTagKeys,
+				FieldKeys:       config.ProtobufFieldKeys,
+				TimestampKey:    config.ProtobufTimestampKey,
+				TimestampFormat: config.ProtobufTimestampFormat,
+				Timezone:        config.ProtobufTimezone,
+				DefaultTags:     config.DefaultTags,
+			},
+		)
+	case "avro":
+		parser, err = avro.New(
+			&avro.Config{
+				MetricName:      config.MetricName,
+				SchemaFile:      config.AvroSchemaFile,
+				SchemaRegistry:  config.AvroSchemaRegistry,
+				TagKeys:         config.AvroTagKeys,
+				FieldKeys:       config.AvroFieldKeys,
+				TimestampKey:    config.AvroTimestampKey,
+				TimestampFormat: config.AvroTimestampFormat,
+				Timezone:        config.AvroTimezone,
+				DefaultTags:     config.DefaultTags,
+			},
+		)
+	case "xml":
+		parser, err = NewXMLParser(
+			config.MetricName,
+			config.XMLConfig,
+			config.DefaultTags,
+		)
 	default:
 		err = fmt.Errorf("Invalid data format: %s", config.DataFormat)
 	}
@@ -392,3 +473,36 @@ func NewFormUrlencodedParser(
 		TagKeys:     tagKeys,
 	}, nil
 }
+
+// NewPrometheusParser returns a parser for the Prometheus and OpenMetrics
+// text formats.
+func NewPrometheusParser(
+	metricVersion int,
+	defaultTags map[string]string,
+) (Parser, error) {
+	switch metricVersion {
+	case 0, 1, 2:
+	default:
+		return nil, fmt.Errorf("invalid prometheus_metric_version %d, must be 1 or 2", metricVersion)
+	}
+	return &prometheus.Parser{
+		MetricVersion: metricVersion,
+		DefaultTags:   defaultTags,
+	}, nil
+}
+
+func NewJSONV2Parser(
+	metricName string,
+	configs []json_v2.Config,
+	defaultTags map[string]string,
+) (Parser, error) {
+	return json_v2.New(metricName, configs, defaultTags)
+}
+
+func NewXMLParser(
+	metricName string,
+	configs []xml.Config,
+	defaultTags map[string]string,
+) (Parser, error) {
+	return xml.New(metricName, configs, defaultTags)
+}
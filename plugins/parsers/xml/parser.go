@@ -0,0 +1,281 @@
+package xml
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/antchfx/xmlquery"
+	"github.com/antchfx/xpath"
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/metric"
+)
+
+// Config describes how metrics are built from the nodes of a document.  All
+// expressions except MetricSelection are evaluated relative to the selected
+// node.
+type Config struct {
+	// MetricSelection selects the nodes to create a metric from.  Defaults
+	// to the document root.
+	MetricSelection string `toml:"metric_selection"`
+	// MetricName is evaluated to get the measurement name.  Defaults to
+	// the name of the plugin.
+	MetricName string `toml:"metric_name"`
+	// Timestamp is evaluated to get the metric time.  Defaults to the
+	// current time.
+	Timestamp       string `toml:"timestamp"`
+	TimestampFormat string `toml:"timestamp_format"`
+	Timezone        string `toml:"timezone"`
+
+	// Tags maps tag keys to expressions.
+	Tags map[string]string `toml:"tags"`
+	// Fields maps field keys to expressions, the type of the field is the
+	// type of the result: number, boolean or string.
+	Fields map[string]string `toml:"fields"`
+	// FieldsInt maps field keys to expressions converted to integers.
+	FieldsInt map[string]string `toml:"fields_int"`
+}
+
+// Parser creates metrics from XML documents using XPath expressions.
+type Parser struct {
+	Configs     []Config
+	MetricName  string
+	DefaultTags map[string]string
+	TimeFunc    func() time.Time
+
+	exprs map[string]*xpath.Expr
+}
+
+// New returns a parser after checking that all expressions compile.
+func New(metricName string, configs []Config, defaultTags map[string]string) (*Parser, error) {
+	if len(configs) == 0 {
+		return nil, fmt.Errorf("no xml configuration given")
+	}
+
+	p := &Parser{
+		Configs:     configs,
+		MetricName:  metricName,
+		DefaultTags: defaultTags,
+		TimeFunc:    time.Now,
+		exprs:       make(map[string]*xpath.Expr),
+	}
+
+	for _, config := range configs {
+		exprs := []string{selection(config), config.MetricName, config.Timestamp}
+		for _, m := range []map[string]string{config.Tags, config.Fields, config.FieldsInt} {
+			for _, expr := range m {
+				exprs = append(exprs, expr)
+			}
+		}
+		for _, expr := range exprs {
+			if expr == "" {
+				continue
+			}
+			if _, err := p.compile(expr); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return p, nil
+}
+
+// Parse returns a metric for every node selected by each configuration.
+func (p *Parser) Parse(buf []byte) ([]telegraf.Metric, error) {
+	doc, err := xmlquery.Parse(bytes.NewReader(buf))
+	if err != nil {
+		return nil, err
+	}
+
+	now := p.TimeFunc()
+	metrics := make([]telegraf.Metric, 0)
+	for _, config := range p.Configs {
+		expr, err := p.compile(selection(config))
+		if err != nil {
+			return nil, err
+		}
+
+		for _, node := range xmlquery.QuerySelectorAll(doc, expr) {
+			m, err := p.parseNode(node, config, now)
+			if err != nil {
+				return nil, err
+			}
+			if m != nil {
+				metrics = append(metrics, m)
+			}
+		}
+	}
+	return metrics, nil
+}
+
+// ParseLine parses a single line as a document, it returns the first metric
+// created.
+func (p *Parser) ParseLine(line string) (telegraf.Metric, error) {
+	metrics, err := p.Parse([]byte(line))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(metrics) < 1 {
+		return nil, fmt.Errorf("no metric in line")
+	}
+	return metrics[0], nil
+}
+
+func (p *Parser) SetDefaultTags(tags map[string]string) {
+	p.DefaultTags = tags
+}
+
+// parseNode returns the metric of the node, or nil if the node has no fields.
+func (p *Parser) parseNode(node *xmlquery.Node, config Config, now time.Time) (telegraf.Metric, error) {
+	name := p.MetricName
+	if config.MetricName != "" {
+		v, err := p.evaluate(node, config.MetricName)
+		if err != nil {
+			return nil, err
+		}
+		if s := toString(v); s != "" {
+			name = s
+		}
+	}
+
+	timestamp := now
+	if config.Timestamp != "" {
+		v, err := p.evaluate(node, config.Timestamp)
+		if err != nil {
+			return nil, err
+		}
+		format := config.TimestampFormat
+		if format == "" {
+			format = time.RFC3339
+		}
+		if _, ok := v.(float64); !ok || !strings.HasPrefix(format, "unix") {
+			v = toString(v)
+		}
+		timestamp, err = internal.ParseTimestamp(format, v, config.Timezone)
+		if err != nil {
+			return nil, fmt.Errorf("parsing timestamp %q failed: %v", config.Timestamp, err)
+		}
+	}
+
+	tags := make(map[string]string)
+	for k, v := range p.DefaultTags {
+		tags[k] = v
+	}
+	for k, expr := range config.Tags {
+		v, err := p.evaluate(node, expr)
+		if err != nil {
+			return nil, err
+		}
+		if s := toString(v); s != "" {
+			tags[k] = s
+		}
+	}
+
+	fields := make(map[string]interface{})
+	for k, expr := range config.Fields {
+		v, err := p.evaluate(node, expr)
+		if err != nil {
+			return nil, err
+		}
+		switch v := v.(type) {
+		case float64, bool:
+			fields[k] = v
+		default:
+			if s := toString(v); s != "" {
+				fields[k] = s
+			}
+		}
+	}
+	for k, expr := range config.FieldsInt {
+		v, err := p.evaluate(node, expr)
+		if err != nil {
+			return nil, err
+		}
+		switch v := v.(type) {
+		case float64:
+			if math.IsNaN(v) || math.IsInf(v, 0) {
+				return nil, fmt.Errorf("field %q: %v is not an integer", k, v)
+			}
+			fields[k] = int64(v)
+		case bool:
+			if v {
+				fields[k] = int64(1)
+			} else {
+				fields[k] = int64(0)
+			}
+		default:
+			s := toString(v)
+			if s == "" {
+				continue
+			}
+			i, err := strconv.ParseInt(strings.TrimSpace(s), 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("field %q: %v", k, err)
+			}
+			fields[k] = i
+		}
+	}
+
+	if len(fields) == 0 {
+		return nil, nil
+	}
+	return metric.New(name, tags, fields, timestamp)
+}
+
+// compile returns the compiled expression, expressions are compiled once.
+func (p *Parser) compile(expr string) (*xpath.Expr, error) {
+	if p.exprs == nil {
+		p.exprs = make(map[string]*xpath.Expr)
+	}
+	if e, ok := p.exprs[expr]; ok {
+		return e, nil
+	}
+	e, err := xpath.Compile(expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid xpath %q: %v", expr, err)
+	}
+	p.exprs[expr] = e
+	return e, nil
+}
+
+func selection(config Config) string {
+	if config.MetricSelection == "" {
+		return "/"
+	}
+	return config.MetricSelection
+}
+
+// evaluate returns the result of the expression relative to the node, a node
+// set is returned as its first node.
+func (p *Parser) evaluate(node *xmlquery.Node, expr string) (interface{}, error) {
+	e, err := p.compile(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	result := e.Evaluate(xmlquery.CreateXPathNavigator(node))
+	if iter, ok := result.(*xpath.NodeIterator); ok {
+		if !iter.MoveNext() {
+			return nil, nil
+		}
+		return iter.Current().Value(), nil
+	}
+	return result, nil
+}
+
+func toString(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
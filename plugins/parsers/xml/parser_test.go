@@ -0,0 +1,201 @@
+package xml
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+const sensorsDoc = `<?xml version="1.0"?>
+<Gateway>
+  <Name>Main Gateway</Name>
+  <Timestamp>2020-08-01T15:04:03Z</Timestamp>
+  <Sequence>12</Sequence>
+  <Status>ok</Status>
+  <Bus>
+    <Sensor name="Sensor Facility A">
+      <Variable temperature="20.0"/>
+      <Variable power="123.4"/>
+      <Variable frequency="49.78"/>
+      <Variable consumers="3"/>
+      <Mode>busy</Mode>
+    </Sensor>
+    <Sensor name="Sensor Facility B">
+      <Variable temperature="23.1"/>
+      <Variable power="14.3"/>
+      <Variable frequency="49.78"/>
+      <Variable consumers="1"/>
+      <Mode>standby</Mode>
+    </Sensor>
+  </Bus>
+</Gateway>
+`
+
+func newParser(t *testing.T, configs ...Config) *Parser {
+	p, err := New("xml", configs, nil)
+	require.NoError(t, err)
+	p.TimeFunc = func() time.Time { return time.Unix(42, 0) }
+	return p
+}
+
+func TestParseDocument(t *testing.T) {
+	p := newParser(t, Config{
+		Timestamp: "/Gateway/Timestamp",
+		Tags: map[string]string{
+			"gateway": "substring-before(/Gateway/Name, ' ')",
+		},
+		Fields: map[string]string{
+			"seqnr": "number(/Gateway/Sequence)",
+			"ok":    "/Gateway/Status = 'ok'",
+		},
+		FieldsInt: map[string]string{
+			"sensors": "count(/Gateway/Bus/Sensor)",
+		},
+	})
+
+	metrics, err := p.Parse([]byte(sensorsDoc))
+	require.NoError(t, err)
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric("xml",
+			map[string]string{"gateway": "Main"},
+			map[string]interface{}{"seqnr": 12.0, "ok": true, "sensors": int64(2)},
+			time.Date(2020, 8, 1, 15, 4, 3, 0, time.UTC)),
+	}
+	testutil.RequireMetricsEqual(t, expected, metrics)
+}
+
+func TestParseMetricSelection(t *testing.T) {
+	p := newParser(t, Config{
+		MetricSelection: "/Gateway/Bus/Sensor",
+		MetricName:      "string('sensors')",
+		Tags: map[string]string{
+			"name": "substring-after(@name, ' ')",
+		},
+		Fields: map[string]string{
+			"temperature": "number(Variable/@temperature)",
+			"mode":        "Mode",
+		},
+		FieldsInt: map[string]string{
+			"consumers": "Variable/@consumers",
+		},
+	})
+
+	metrics, err := p.Parse([]byte(sensorsDoc))
+	require.NoError(t, err)
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric("sensors",
+			map[string]string{"name": "Facility A"},
+			map[string]interface{}{"temperature": 20.0, "mode": "busy", "consumers": int64(3)},
+			time.Unix(42, 0)),
+		testutil.MustMetric("sensors",
+			map[string]string{"name": "Facility B"},
+			map[string]interface{}{"temperature": 23.1, "mode": "standby", "consumers": int64(1)},
+			time.Unix(42, 0)),
+	}
+	testutil.RequireMetricsEqual(t, expected, metrics)
+}
+
+func TestParseMultipleConfigs(t *testing.T) {
+	p := newParser(t,
+		Config{
+			MetricName: "name(/*)",
+			FieldsInt:  map[string]string{"seqnr": "/Gateway/Sequence"},
+		},
+		Config{
+			MetricSelection: "//Sensor",
+			MetricName:      "string('sensor')",
+			Fields:          map[string]string{"power": "number(Variable/@power)"},
+		},
+	)
+
+	metrics, err := p.Parse([]byte(sensorsDoc))
+	require.NoError(t, err)
+	require.Len(t, metrics, 3)
+	require.Equal(t, "Gateway", metrics[0].Name())
+	require.Equal(t, map[string]interface{}{"seqnr": int64(12)}, metrics[0].Fields())
+	require.Equal(t, "sensor", metrics[1].Name())
+	require.Equal(t, map[string]interface{}{"power": 14.3}, metrics[2].Fields())
+}
+
+func TestParseUnixTimestamp(t *testing.T) {
+	p := newParser(t, Config{
+		Timestamp:       "number(/Device/@time)",
+		TimestampFormat: "unix_ms",
+		Fields:          map[string]string{"value": "number(/Device/Value)"},
+	})
+
+	m, err := p.ParseLine(`<Device time="1596294243000"><Value>1.5</Value></Device>`)
+	require.NoError(t, err)
+	require.Equal(t, time.Unix(1596294243, 0).UTC(), m.Time())
+	require.Equal(t, map[string]interface{}{"value": 1.5}, m.Fields())
+}
+
+func TestParseDefaultTags(t *testing.T) {
+	p := newParser(t, Config{
+		Tags:   map[string]string{"missing": "/Gateway/Missing"},
+		Fields: map[string]string{"status": "/Gateway/Status"},
+	})
+	p.SetDefaultTags(map[string]string{"source": "gateway"})
+
+	metrics, err := p.Parse([]byte(sensorsDoc))
+	require.NoError(t, err)
+	require.Len(t, metrics, 1)
+	require.Equal(t, map[string]string{"source": "gateway"}, metrics[0].Tags())
+	require.Equal(t, map[string]interface{}{"status": "ok"}, metrics[0].Fields())
+}
+
+func TestParseInvalidInteger(t *testing.T) {
+	p := newParser(t, Config{
+		FieldsInt: map[string]string{"status": "/Gateway/Status"},
+	})
+
+	_, err := p.Parse([]byte(sensorsDoc))
+	require.Error(t, err)
+}
+
+func TestParseNotANumberInteger(t *testing.T) {
+	for _, expr := range []string{"0 div 0", "number(/Gateway/Sequence) div 0"} {
+		p := newParser(t, Config{
+			FieldsInt: map[string]string{"ratio": expr},
+		})
+
+		_, err := p.Parse([]byte(sensorsDoc))
+		require.Error(t, err, expr)
+	}
+}
+
+func TestParseNodeWithoutFields(t *testing.T) {
+	p := newParser(t, Config{
+		MetricSelection: "/Gateway/Bus/Sensor",
+		Tags: map[string]string{
+			"name": "substring-after(@name, ' ')",
+		},
+		Fields: map[string]string{
+			"error": "Error",
+		},
+	})
+
+	metrics, err := p.Parse([]byte(sensorsDoc))
+	require.NoError(t, err)
+	require.Len(t, metrics, 0)
+}
+
+func TestParseInvalidDocument(t *testing.T) {
+	p := newParser(t, Config{})
+
+	_, err := p.Parse([]byte(`<Gateway><Name>`))
+	require.Error(t, err)
+}
+
+func TestNewInvalidExpression(t *testing.T) {
+	_, err := New("xml", []Config{{MetricSelection: "/Gateway["}}, nil)
+	require.Error(t, err)
+
+	_, err = New("xml", nil, nil)
+	require.Error(t, err)
+}
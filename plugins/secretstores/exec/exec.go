@@ -0,0 +1,113 @@
+package exec
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/secretstores"
+)
+
+type Exec struct {
+	Command []string          `toml:"command"`
+	Timeout internal.Duration `toml:"timeout"`
+}
+
+const sampleConfig = `
+  ## Unique identifier of the store, secrets are referenced as
+  ## "@{<id>:<key>}" in the plugin configuration.
+  id = "helper"
+
+  ## Helper command and its arguments.  The action and the key are appended
+  ## to the arguments:
+  ##   get <key>:  print the value of the secret to stdout
+  ##   set <key>:  store the value of the secret read from stdin
+  ##   list:       print the keys of the secrets, one per line
+  command = ["/usr/local/bin/telegraf-secrets"]
+
+  ## Timeout for each run of the command.
+  # timeout = "5s"
+`
+
+func (e *Exec) SampleConfig() string {
+	return sampleConfig
+}
+
+func (e *Exec) Description() string {
+	return "Read secrets from the output of a helper command"
+}
+
+func (e *Exec) Init() error {
+	if len(e.Command) == 0 {
+		return errors.New("command is required")
+	}
+	return nil
+}
+
+// Get returns the output of the helper, without the final line break.
+func (e *Exec) Get(key string) ([]byte, error) {
+	value, err := e.run(nil, "get", key)
+	if err != nil {
+		return nil, err
+	}
+	value = bytes.TrimSuffix(value, []byte("\n"))
+	value = bytes.TrimSuffix(value, []byte("\r"))
+	return value, nil
+}
+
+func (e *Exec) Set(key string, value []byte) error {
+	_, err := e.run(value, "set", key)
+	return err
+}
+
+func (e *Exec) List() ([]string, error) {
+	out, err := e.run(nil, "list")
+	if err != nil {
+		return nil, err
+	}
+
+	var keys []string
+	for _, line := range strings.Split(string(out), "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" {
+			keys = append(keys, line)
+		}
+	}
+	return keys, nil
+}
+
+// run runs the helper with the arguments and returns its standard output.
+// The error output is only used for the error message, so that a secret is
+// never logged.
+func (e *Exec) run(stdin []byte, args ...string) ([]byte, error) {
+	args = append(append([]string{}, e.Command[1:]...), args...)
+	cmd := exec.Command(e.Command[0], args...)
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdin = bytes.NewReader(stdin)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	err := internal.RunTimeout(cmd, e.Timeout.Duration)
+	if err != nil {
+		message := strings.TrimSpace(stderr.String())
+		if message == "" {
+			return nil, fmt.Errorf("running %s: %v", e.Command[0], err)
+		}
+		return nil, fmt.Errorf("running %s: %v: %s", e.Command[0], err, message)
+	}
+	return stdout.Bytes(), nil
+}
+
+func init() {
+	secretstores.Add("exec", func() telegraf.SecretStore {
+		return &Exec{
+			Timeout: internal.Duration{Duration: 5 * time.Second},
+		}
+	})
+}
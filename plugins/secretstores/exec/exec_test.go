@@ -0,0 +1,70 @@
+package exec
+
+import (
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf/internal"
+	"github.com/stretchr/testify/require"
+)
+
+// helper returns a store running the shell script, the action and key are
+// passed as $1 and $2.
+func helper(t *testing.T, script string) *Exec {
+	if runtime.GOOS == "windows" {
+		t.Skip("Skipping test on windows")
+	}
+
+	e := &Exec{
+		Command: []string{"sh", "-c", script, "helper"},
+		Timeout: internal.Duration{Duration: 5 * time.Second},
+	}
+	require.NoError(t, e.Init())
+	return e
+}
+
+func TestExec(t *testing.T) {
+	e := helper(t, `
+case "$1" in
+get) printf 'value of %s\n' "$2" ;;
+set) read -r value; [ "$value" = "abc" ] ;;
+list) printf 'password\n\ntoken\n' ;;
+esac`)
+
+	value, err := e.Get("password")
+	require.NoError(t, err)
+	require.Equal(t, "value of password", string(value))
+
+	require.NoError(t, e.Set("token", []byte("abc\n")))
+	require.Error(t, e.Set("token", []byte("xyz\n")))
+
+	keys, err := e.List()
+	require.NoError(t, err)
+	require.Equal(t, []string{"password", "token"}, keys)
+}
+
+func TestExecNonZeroExit(t *testing.T) {
+	e := helper(t, `echo "partial secret"; echo "no such key: $2" >&2; exit 3`)
+
+	value, err := e.Get("missing")
+	require.Error(t, err)
+	require.Nil(t, value)
+	require.Contains(t, err.Error(), "no such key: missing")
+	require.NotContains(t, err.Error(), "partial secret")
+}
+
+func TestExecTimeout(t *testing.T) {
+	e := helper(t, `exec sleep 10`)
+	e.Timeout = internal.Duration{Duration: 100 * time.Millisecond}
+
+	start := time.Now()
+	_, err := e.Get("password")
+	require.Error(t, err)
+	require.True(t, time.Since(start) < 5*time.Second)
+}
+
+func TestExecInit(t *testing.T) {
+	e := &Exec{}
+	require.Error(t, e.Init())
+}
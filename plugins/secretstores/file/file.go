@@ -0,0 +1,104 @@
+package file
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/secretstores"
+)
+
+type File struct {
+	Directory string `toml:"directory"`
+}
+
+const sampleConfig = `
+  ## Unique identifier of the store, secrets are referenced as
+  ## "@{<id>:<key>}" in the plugin configuration.
+  id = "secrets"
+
+  ## Directory containing one file per secret, the key of a secret is the
+  ## name of its file.  This is the layout used by Docker and Kubernetes
+  ## secrets.
+  directory = "/run/secrets"
+`
+
+func (f *File) SampleConfig() string {
+	return sampleConfig
+}
+
+func (f *File) Description() string {
+	return "Read secrets from a directory with one file per secret"
+}
+
+func (f *File) Init() error {
+	if f.Directory == "" {
+		return errors.New("directory is required")
+	}
+	return nil
+}
+
+// Get returns the contents of the file of the secret, without the final
+// line break.
+func (f *File) Get(key string) ([]byte, error) {
+	path, err := f.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	value, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	value = bytes.TrimSuffix(value, []byte("\n"))
+	value = bytes.TrimSuffix(value, []byte("\r"))
+	return value, nil
+}
+
+func (f *File) Set(key string, value []byte) error {
+	path, err := f.path(key)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, value, 0600)
+}
+
+// List returns the keys of the secrets.  Hidden files are skipped, these
+// include the data directories of Kubernetes secret volumes.
+func (f *File) List() ([]string, error) {
+	files, err := ioutil.ReadDir(f.Directory)
+	if err != nil {
+		return nil, err
+	}
+
+	var keys []string
+	for _, file := range files {
+		if strings.HasPrefix(file.Name(), ".") {
+			continue
+		}
+		info, err := os.Stat(filepath.Join(f.Directory, file.Name()))
+		if err != nil || info.IsDir() {
+			continue
+		}
+		keys = append(keys, file.Name())
+	}
+	return keys, nil
+}
+
+func (f *File) path(key string) (string, error) {
+	if key == "" || key == "." || key == ".." || strings.ContainsAny(key, `/\`) {
+		return "", fmt.Errorf("invalid key %q", key)
+	}
+	return filepath.Join(f.Directory, key), nil
+}
+
+func init() {
+	secretstores.Add("file", func() telegraf.SecretStore {
+		return &File{}
+	})
+}
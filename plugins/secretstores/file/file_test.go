@@ -0,0 +1,40 @@
+package file
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "telegraf-secrets")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "password"), []byte("secret\n"), 0600))
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "..data"), 0755))
+
+	f := &File{Directory: dir}
+	require.NoError(t, f.Init())
+
+	value, err := f.Get("password")
+	require.NoError(t, err)
+	require.Equal(t, "secret", string(value))
+
+	require.NoError(t, f.Set("token", []byte("abc")))
+	value, err = f.Get("token")
+	require.NoError(t, err)
+	require.Equal(t, "abc", string(value))
+
+	keys, err := f.List()
+	require.NoError(t, err)
+	require.Equal(t, []string{"password", "token"}, keys)
+
+	_, err = f.Get("missing")
+	require.Error(t, err)
+	_, err = f.Get("../password")
+	require.Error(t, err)
+}
@@ -0,0 +1,241 @@
+package keyring
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/secretstores"
+	"golang.org/x/crypto/pbkdf2"
+)
+
+const (
+	saltSize   = 16
+	keySize    = 32
+	iterations = 100000
+)
+
+// Keyring stores the secrets in a file, each one encrypted with AES-GCM
+// using a key derived from the password.
+type Keyring struct {
+	Path     string `toml:"path"`
+	Password string `toml:"password"`
+
+	mu   sync.Mutex
+	key  []byte
+	salt []byte
+}
+
+// keyringFile is the format of the keyring file.  The salt is that of the
+// key derivation, each secret holds the nonce followed by the ciphertext.
+type keyringFile struct {
+	Salt       []byte            `json:"salt"`
+	Iterations int               `json:"iterations"`
+	Secrets    map[string][]byte `json:"secrets"`
+}
+
+const sampleConfig = `
+  ## Unique identifier of the store, secrets are referenced as
+  ## "@{<id>:<key>}" in the plugin configuration.
+  id = "keyring"
+
+  ## File storing the encrypted secrets, it is created when the first secret
+  ## is set with "telegraf secrets set <id> <key>".
+  path = "/etc/telegraf/keyring.json"
+
+  ## Password the encryption key is derived from.  Use an environment
+  ## variable to keep it out of the configuration.
+  password = "${TELEGRAF_KEYRING_PASSWORD}"
+`
+
+func (k *Keyring) SampleConfig() string {
+	return sampleConfig
+}
+
+func (k *Keyring) Description() string {
+	return "Read secrets from an encrypted keyring file"
+}
+
+func (k *Keyring) Init() error {
+	if k.Path == "" {
+		return errors.New("path is required")
+	}
+	if k.Password == "" {
+		return errors.New("password is required")
+	}
+	return nil
+}
+
+func (k *Keyring) Get(key string) ([]byte, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	kf, err := k.load()
+	if err != nil {
+		return nil, err
+	}
+
+	sealed, ok := kf.Secrets[key]
+	if !ok {
+		return nil, fmt.Errorf("secret %q not found", key)
+	}
+
+	aead, err := k.cipher(kf)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < aead.NonceSize() {
+		return nil, fmt.Errorf("secret %q is corrupted", key)
+	}
+
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	value, err := aead.Open(nil, nonce, ciphertext, []byte(key))
+	if err != nil {
+		return nil, fmt.Errorf("decrypting secret %q: wrong password or corrupted keyring", key)
+	}
+	return value, nil
+}
+
+func (k *Keyring) Set(key string, value []byte) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	kf, err := k.load()
+	if os.IsNotExist(err) {
+		kf = &keyringFile{
+			Salt:       make([]byte, saltSize),
+			Iterations: iterations,
+			Secrets:    make(map[string][]byte),
+		}
+		_, err = io.ReadFull(rand.Reader, kf.Salt)
+	}
+	if err != nil {
+		return err
+	}
+
+	aead, err := k.cipher(kf)
+	if err != nil {
+		return err
+	}
+
+	// Verify the password on an existing secret so that a keyring is not
+	// filled with secrets encrypted with different passwords.
+	for name, sealed := range kf.Secrets {
+		if len(sealed) < aead.NonceSize() {
+			return fmt.Errorf("secret %q is corrupted", name)
+		}
+		nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+		if _, err := aead.Open(nil, nonce, ciphertext, []byte(name)); err != nil {
+			return errors.New("wrong password or corrupted keyring")
+		}
+		break
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return err
+	}
+	kf.Secrets[key] = aead.Seal(nonce, nonce, value, []byte(key))
+
+	return k.save(kf)
+}
+
+func (k *Keyring) List() ([]string, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	kf, err := k.load()
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, 0, len(kf.Secrets))
+	for key := range kf.Secrets {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+func (k *Keyring) load() (*keyringFile, error) {
+	data, err := ioutil.ReadFile(k.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	kf := &keyringFile{}
+	err = json.Unmarshal(data, kf)
+	if err != nil {
+		return nil, fmt.Errorf("parsing keyring %s: %v", k.Path, err)
+	}
+	if len(kf.Salt) == 0 || kf.Iterations <= 0 {
+		return nil, fmt.Errorf("parsing keyring %s: missing key derivation parameters", k.Path)
+	}
+	if kf.Secrets == nil {
+		kf.Secrets = make(map[string][]byte)
+	}
+	return kf, nil
+}
+
+// save writes the keyring to a temporary file first, so that the keyring is
+// never left partially written.
+func (k *Keyring) save(kf *keyringFile) error {
+	data, err := json.MarshalIndent(kf, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(k.Path), filepath.Base(k.Path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(data)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), k.Path)
+}
+
+// cipher returns the cipher for the keyring.  The key is only derived again
+// if the salt changed, as the derivation is deliberately slow.
+func (k *Keyring) cipher(kf *keyringFile) (cipher.AEAD, error) {
+	if k.key == nil || !bytes.Equal(k.salt, kf.Salt) {
+		k.key = pbkdf2.Key([]byte(k.Password), kf.Salt, kf.Iterations, keySize, sha256.New)
+		k.salt = kf.Salt
+	}
+
+	block, err := aes.NewCipher(k.key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func init() {
+	secretstores.Add("keyring", func() telegraf.SecretStore {
+		return &Keyring{}
+	})
+}
@@ -0,0 +1,59 @@
+package keyring
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeyring(t *testing.T) {
+	dir, err := ioutil.TempDir("", "telegraf-keyring")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "keyring.json")
+	k := &Keyring{Path: path, Password: "password"}
+	require.NoError(t, k.Init())
+
+	keys, err := k.List()
+	require.NoError(t, err)
+	require.Empty(t, keys)
+
+	require.NoError(t, k.Set("db", []byte("hunter2")))
+	require.NoError(t, k.Set("api", []byte("token")))
+
+	data, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	require.NotContains(t, string(data), "hunter2")
+
+	// A new instance must derive the same key from the stored salt.
+	k = &Keyring{Path: path, Password: "password"}
+	value, err := k.Get("db")
+	require.NoError(t, err)
+	require.Equal(t, "hunter2", string(value))
+
+	keys, err = k.List()
+	require.NoError(t, err)
+	require.Equal(t, []string{"api", "db"}, keys)
+
+	_, err = k.Get("missing")
+	require.Error(t, err)
+}
+
+func TestKeyring_WrongPassword(t *testing.T) {
+	dir, err := ioutil.TempDir("", "telegraf-keyring")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "keyring.json")
+	k := &Keyring{Path: path, Password: "password"}
+	require.NoError(t, k.Set("db", []byte("hunter2")))
+
+	k = &Keyring{Path: path, Password: "wrong"}
+	_, err = k.Get("db")
+	require.Error(t, err)
+	require.Error(t, k.Set("api", []byte("token")))
+}
@@ -0,0 +1,305 @@
+package prometheusremotewrite
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/snappy"
+	"github.com/influxdata/telegraf"
+)
+
+var (
+	invalidNameCharRE = regexp.MustCompile(`[^a-zA-Z0-9_:]`)
+	validNameCharRE   = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*`)
+)
+
+// Field numbers of the remote write protocol buffer messages.
+const (
+	// WriteRequest
+	fieldTimeSeries = 1
+	// TimeSeries
+	fieldLabels  = 1
+	fieldSamples = 2
+	// Label
+	fieldName  = 1
+	fieldValue = 2
+	// Sample
+	fieldSampleValue     = 1
+	fieldSampleTimestamp = 2
+
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+)
+
+type label struct {
+	name  string
+	value string
+}
+
+type sample struct {
+	value     float64
+	timestamp int64
+}
+
+type timeSeries struct {
+	labels  []label
+	samples []sample
+}
+
+// Serializer writes metrics as a snappy compressed Prometheus remote write
+// WriteRequest.  Metrics are converted to Prometheus metrics like by the
+// prometheus_client output.
+type Serializer struct {
+}
+
+func NewSerializer() (*Serializer, error) {
+	return &Serializer{}, nil
+}
+
+// Serialize returns a WriteRequest with the series of the metric.
+func (s *Serializer) Serialize(metric telegraf.Metric) ([]byte, error) {
+	return s.SerializeBatch([]telegraf.Metric{metric})
+}
+
+// SerializeBatch returns a WriteRequest with the series of the metrics.  The
+// samples of a series are sorted by time.
+func (s *Serializer) SerializeBatch(metrics []telegraf.Metric) ([]byte, error) {
+	series := make(map[string]*timeSeries)
+	for _, metric := range metrics {
+		addMetric(series, metric)
+	}
+
+	keys := make([]string, 0, len(series))
+	for key := range series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	buf := proto.NewBuffer(nil)
+	for _, key := range keys {
+		ts := series[key]
+		sort.SliceStable(ts.samples, func(i, j int) bool {
+			return ts.samples[i].timestamp < ts.samples[j].timestamp
+		})
+
+		msg, err := marshalTimeSeries(ts)
+		if err != nil {
+			return nil, err
+		}
+		if err := encodeBytes(buf, fieldTimeSeries, msg); err != nil {
+			return nil, err
+		}
+	}
+
+	return snappy.Encode(nil, buf.Bytes()), nil
+}
+
+// addMetric adds the samples of the metric to their series.
+func addMetric(series map[string]*timeSeries, metric telegraf.Metric) {
+	labels := make(map[string]string)
+	for _, tag := range metric.TagList() {
+		name := sanitize(tag.Key)
+		if !isValidName(name) {
+			continue
+		}
+		labels[name] = tag.Value
+	}
+
+	// Prometheus doesn't have a string value type, so convert string
+	// fields to labels.
+	for _, field := range metric.FieldList() {
+		if value, ok := field.Value.(string); ok {
+			name := sanitize(field.Key)
+			if !isValidName(name) {
+				continue
+			}
+			labels[name] = value
+		}
+	}
+
+	name := sanitize(metric.Name())
+	timestamp := metric.Time().UnixNano() / 1000000
+	add := func(name string, extra map[string]string, value float64) {
+		if !isValidName(name) {
+			return
+		}
+
+		ls := make([]label, 0, len(labels)+len(extra)+1)
+		ls = append(ls, label{name: "__name__", value: name})
+		for k, v := range labels {
+			if _, ok := extra[k]; !ok {
+				ls = append(ls, label{name: k, value: v})
+			}
+		}
+		for k, v := range extra {
+			ls = append(ls, label{name: k, value: v})
+		}
+		sort.Slice(ls, func(i, j int) bool { return ls[i].name < ls[j].name })
+
+		var key strings.Builder
+		for _, l := range ls {
+			key.WriteString(l.name)
+			key.WriteByte(0xff)
+			key.WriteString(l.value)
+			key.WriteByte(0xff)
+		}
+
+		ts, ok := series[key.String()]
+		if !ok {
+			ts = &timeSeries{labels: ls}
+			series[key.String()] = ts
+		}
+		ts.samples = append(ts.samples, sample{value: value, timestamp: timestamp})
+	}
+
+	switch metric.Type() {
+	case telegraf.Summary, telegraf.Histogram:
+		le := "quantile"
+		suffix := ""
+		if metric.Type() == telegraf.Histogram {
+			le = "le"
+			suffix = "_bucket"
+		}
+
+		var count float64
+		var hasInf bool
+		for _, field := range metric.FieldList() {
+			value, ok := toFloat(field.Value)
+			if !ok {
+				continue
+			}
+
+			switch field.Key {
+			case "sum":
+				add(name+"_sum", nil, value)
+			case "count":
+				count = value
+				add(name+"_count", nil, value)
+			default:
+				limit, err := strconv.ParseFloat(field.Key, 64)
+				if err != nil {
+					continue
+				}
+				if math.IsInf(limit, 1) {
+					hasInf = true
+				}
+				add(name+suffix, map[string]string{le: formatFloat(limit)}, value)
+			}
+		}
+
+		// Histograms always have a bucket with all samples.
+		if metric.Type() == telegraf.Histogram && !hasInf {
+			add(name+suffix, map[string]string{le: "+Inf"}, count)
+		}
+	default:
+		for _, field := range metric.FieldList() {
+			// Ignore string and bool fields.
+			value, ok := toFloat(field.Value)
+			if !ok {
+				continue
+			}
+
+			// Special handling of value field; supports passthrough from
+			// the prometheus input.
+			var mname string
+			switch {
+			case metric.Type() == telegraf.Counter && field.Key == "counter",
+				metric.Type() == telegraf.Gauge && field.Key == "gauge",
+				field.Key == "value":
+				mname = name
+			default:
+				mname = sanitize(fmt.Sprintf("%s_%s", metric.Name(), field.Key))
+			}
+			add(mname, nil, value)
+		}
+	}
+}
+
+func marshalTimeSeries(ts *timeSeries) ([]byte, error) {
+	buf := proto.NewBuffer(nil)
+	for _, l := range ts.labels {
+		msg := proto.NewBuffer(nil)
+		if err := encodeString(msg, fieldName, l.name); err != nil {
+			return nil, err
+		}
+		if err := encodeString(msg, fieldValue, l.value); err != nil {
+			return nil, err
+		}
+		if err := encodeBytes(buf, fieldLabels, msg.Bytes()); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, s := range ts.samples {
+		msg := proto.NewBuffer(nil)
+		if err := msg.EncodeVarint(fieldSampleValue<<3 | wireFixed64); err != nil {
+			return nil, err
+		}
+		if err := msg.EncodeFixed64(math.Float64bits(s.value)); err != nil {
+			return nil, err
+		}
+		if err := msg.EncodeVarint(fieldSampleTimestamp<<3 | wireVarint); err != nil {
+			return nil, err
+		}
+		if err := msg.EncodeVarint(uint64(s.timestamp)); err != nil {
+			return nil, err
+		}
+		if err := encodeBytes(buf, fieldSamples, msg.Bytes()); err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+func encodeBytes(buf *proto.Buffer, field uint64, b []byte) error {
+	if err := buf.EncodeVarint(field<<3 | wireBytes); err != nil {
+		return err
+	}
+	return buf.EncodeRawBytes(b)
+}
+
+func encodeString(buf *proto.Buffer, field uint64, s string) error {
+	if err := buf.EncodeVarint(field<<3 | wireBytes); err != nil {
+		return err
+	}
+	return buf.EncodeStringBytes(s)
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch v := v.(type) {
+	case int64:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case float64:
+		return v, true
+	default:
+		return 0, false
+	}
+}
+
+// formatFloat formats a bucket or quantile like the Prometheus client.
+func formatFloat(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "+Inf"
+	case math.IsInf(f, -1):
+		return "-Inf"
+	default:
+		return strconv.FormatFloat(f, 'g', -1, 64)
+	}
+}
+
+func sanitize(value string) string {
+	return invalidNameCharRE.ReplaceAllString(value, "_")
+}
+
+func isValidName(name string) bool {
+	return validNameCharRE.MatchString(name)
+}
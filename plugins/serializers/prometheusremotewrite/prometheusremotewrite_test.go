@@ -0,0 +1,198 @@
+package prometheusremotewrite
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/snappy"
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+// decode returns the series of a WriteRequest in the Prometheus text format,
+// one line per sample, sorted.
+func decode(t *testing.T, data []byte) []string {
+	raw, err := snappy.Decode(nil, data)
+	require.NoError(t, err)
+
+	var lines []string
+	req := proto.NewBuffer(raw)
+	for len(req.Unread()) > 0 {
+		series := readMessage(t, req, fieldTimeSeries)
+
+		var name string
+		var labels []string
+		var samples []string
+		for len(series.Unread()) > 0 {
+			key, err := series.DecodeVarint()
+			require.NoError(t, err)
+			msg, err := series.DecodeRawBytes(true)
+			require.NoError(t, err)
+			field := proto.NewBuffer(msg)
+
+			switch key >> 3 {
+			case fieldLabels:
+				var l label
+				for len(field.Unread()) > 0 {
+					k, err := field.DecodeVarint()
+					require.NoError(t, err)
+					s, err := field.DecodeStringBytes()
+					require.NoError(t, err)
+					if k>>3 == fieldName {
+						l.name = s
+					} else {
+						l.value = s
+					}
+				}
+				if l.name == "__name__" {
+					name = l.value
+				} else {
+					labels = append(labels, fmt.Sprintf("%s=%q", l.name, l.value))
+				}
+			case fieldSamples:
+				var s sample
+				for len(field.Unread()) > 0 {
+					k, err := field.DecodeVarint()
+					require.NoError(t, err)
+					if k>>3 == fieldSampleValue {
+						bits, err := field.DecodeFixed64()
+						require.NoError(t, err)
+						s.value = math.Float64frombits(bits)
+					} else {
+						ts, err := field.DecodeVarint()
+						require.NoError(t, err)
+						s.timestamp = int64(ts)
+					}
+				}
+				samples = append(samples, fmt.Sprintf("%v %d", s.value, s.timestamp))
+			}
+		}
+
+		require.True(t, sort.StringsAreSorted(labels), "labels are not sorted")
+		for _, s := range samples {
+			lines = append(lines, fmt.Sprintf("%s{%s} %s", name, strings.Join(labels, ","), s))
+		}
+	}
+	sort.Strings(lines)
+	return lines
+}
+
+func readMessage(t *testing.T, buf *proto.Buffer, field uint64) *proto.Buffer {
+	key, err := buf.DecodeVarint()
+	require.NoError(t, err)
+	require.Equal(t, field<<3|wireBytes, key)
+	msg, err := buf.DecodeRawBytes(true)
+	require.NoError(t, err)
+	return proto.NewBuffer(msg)
+}
+
+func TestSerializeUntyped(t *testing.T) {
+	s, err := NewSerializer()
+	require.NoError(t, err)
+
+	m := testutil.MustMetric("cpu",
+		map[string]string{"host": "example.org", "cpu": "cpu0"},
+		map[string]interface{}{"usage_idle": 91.5, "value": int64(2), "label": "ok", "on": true},
+		time.Unix(1600000000, 0))
+
+	data, err := s.Serialize(m)
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		`cpu_usage_idle{cpu="cpu0",host="example.org",label="ok"} 91.5 1600000000000`,
+		`cpu{cpu="cpu0",host="example.org",label="ok"} 2 1600000000000`,
+	}, decode(t, data))
+}
+
+func TestSerializeCounterAndGauge(t *testing.T) {
+	s, err := NewSerializer()
+	require.NoError(t, err)
+
+	metrics := []telegraf.Metric{
+		testutil.MustMetric("http_requests_total",
+			map[string]string{"code": "200"},
+			map[string]interface{}{"counter": 1027.0},
+			time.Unix(0, 1000000), telegraf.Counter),
+		testutil.MustMetric("go_goroutines",
+			map[string]string{"host.name": "a"},
+			map[string]interface{}{"gauge": 15.0},
+			time.Unix(0, 1000000), telegraf.Gauge),
+	}
+
+	data, err := s.SerializeBatch(metrics)
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		`go_goroutines{host_name="a"} 15 1`,
+		`http_requests_total{code="200"} 1027 1`,
+	}, decode(t, data))
+}
+
+func TestSerializeHistogram(t *testing.T) {
+	s, err := NewSerializer()
+	require.NoError(t, err)
+
+	m := testutil.MustMetric("request_size",
+		map[string]string{},
+		map[string]interface{}{"100": 2.0, "1000": 3.0, "count": 4.0, "sum": 1242.0},
+		time.Unix(0, 0), telegraf.Histogram)
+
+	data, err := s.Serialize(m)
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		`request_size_bucket{le="+Inf"} 4 0`,
+		`request_size_bucket{le="100"} 2 0`,
+		`request_size_bucket{le="1000"} 3 0`,
+		`request_size_count{} 4 0`,
+		`request_size_sum{} 1242 0`,
+	}, decode(t, data))
+}
+
+func TestSerializeSummary(t *testing.T) {
+	s, err := NewSerializer()
+	require.NoError(t, err)
+
+	m := testutil.MustMetric("rpc_duration_seconds",
+		map[string]string{"service": "a"},
+		map[string]interface{}{"0.5": 0.05, "0.99": 0.2, "count": 10.0, "sum": 1.5},
+		time.Unix(0, 0), telegraf.Summary)
+
+	data, err := s.Serialize(m)
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		`rpc_duration_seconds_count{service="a"} 10 0`,
+		`rpc_duration_seconds_sum{service="a"} 1.5 0`,
+		`rpc_duration_seconds{quantile="0.5",service="a"} 0.05 0`,
+		`rpc_duration_seconds{quantile="0.99",service="a"} 0.2 0`,
+	}, decode(t, data))
+}
+
+func TestSerializeBatchGroupsSeries(t *testing.T) {
+	s, err := NewSerializer()
+	require.NoError(t, err)
+
+	metrics := []telegraf.Metric{
+		testutil.MustMetric("mem", map[string]string{}, map[string]interface{}{"used": 2.0},
+			time.Unix(2, 0)),
+		testutil.MustMetric("mem", map[string]string{}, map[string]interface{}{"used": 1.0},
+			time.Unix(1, 0)),
+	}
+
+	data, err := s.SerializeBatch(metrics)
+	require.NoError(t, err)
+
+	raw, err := snappy.Decode(nil, data)
+	require.NoError(t, err)
+	req := proto.NewBuffer(raw)
+	readMessage(t, req, fieldTimeSeries)
+	require.Empty(t, req.Unread(), "expected a single series")
+
+	require.Equal(t, []string{
+		`mem_used{} 1 1000`,
+		`mem_used{} 2 2000`,
+	}, decode(t, data))
+}
@@ -0,0 +1,16 @@
+package telegraf
+
+// SecretStore provides the secrets referenced in the plugin configuration
+// with "@{<id>:<key>}", where id is the id of the store.
+type SecretStore interface {
+	// SampleConfig returns the default configuration of the SecretStore
+	SampleConfig() string
+	// Description returns a one-sentence description on the SecretStore
+	Description() string
+	// Get returns the value of the secret with the key.
+	Get(key string) ([]byte, error)
+	// Set stores the value of the secret with the key.
+	Set(key string, value []byte) error
+	// List returns the keys of the secrets in the store.
+	List() ([]string, error)
+}
@@ -0,0 +1,196 @@
+package selfstat
+
+import (
+	"strconv"
+	"sync"
+)
+
+// DefaultTimingBuckets are the upper bounds of the buckets of timing
+// histograms, from 1ms to 1m in nanoseconds.
+var DefaultTimingBuckets = []int64{
+	1e6, 5e6, 10e6, 25e6, 50e6, 100e6, 250e6, 500e6,
+	1e9, 2.5e9, 5e9, 10e9, 30e9, 60e9,
+}
+
+// quantiles are the percentiles reported for histograms, as the suffix of
+// the field and the quantile.
+var quantiles = []struct {
+	suffix   string
+	quantile float64
+}{
+	{"_p50", 0.5},
+	{"_p90", 0.9},
+	{"_p99", 0.99},
+}
+
+// histogramStat counts values in buckets.  Like a timing stat, Get returns
+// the average of the values since the previous call, and the percentiles of
+// these values are reported as additional fields.  The counts since the
+// stat was registered are kept for Prometheus histograms.
+type histogramStat struct {
+	measurement string
+	field       string
+	tags        map[string]string
+	key         uint64
+	buckets     []int64
+
+	mu sync.Mutex
+
+	// Values since registration, counts has one more entry for the values
+	// above the last bucket.
+	counts []uint64
+	sum    int64
+	count  uint64
+
+	// Values since the previous call to Get.
+	window      []uint64
+	windowSum   int64
+	windowCount int64
+	windowMax   int64
+	prev        int64
+}
+
+func newHistogramStat(measurement, field string, tags map[string]string, buckets []int64) *histogramStat {
+	return &histogramStat{
+		measurement: measurement,
+		field:       field,
+		tags:        tags,
+		buckets:     buckets,
+		counts:      make([]uint64, len(buckets)+1),
+		window:      make([]uint64, len(buckets)+1),
+	}
+}
+
+// Incr adds the value to the histogram.
+func (s *histogramStat) Incr(v int64) {
+	i := 0
+	for i < len(s.buckets) && v > s.buckets[i] {
+		i++
+	}
+
+	s.mu.Lock()
+	s.counts[i]++
+	s.sum += v
+	s.count++
+	s.window[i]++
+	s.windowSum += v
+	s.windowCount++
+	if s.windowCount == 1 || v > s.windowMax {
+		s.windowMax = v
+	}
+	s.mu.Unlock()
+}
+
+// Set adds the value to the histogram.
+func (s *histogramStat) Set(v int64) {
+	s.Incr(v)
+}
+
+// Get returns the average of the values since the previous call, or the
+// previous average if there were none.
+func (s *histogramStat) Get() int64 {
+	return s.fields(true)[s.field].(int64)
+}
+
+// fields returns the average and the percentiles of the values since the
+// previous call, and if reset is true starts a new window.  If there were no
+// values the previous average is returned without percentiles.
+func (s *histogramStat) fields(reset bool) map[string]interface{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	fields := make(map[string]interface{}, len(quantiles)+1)
+	if s.windowCount == 0 {
+		fields[s.field] = s.prev
+		return fields
+	}
+
+	fields[s.field] = s.windowSum / s.windowCount
+	for _, q := range quantiles {
+		fields[s.field+q.suffix] = s.quantile(q.quantile)
+	}
+	if !reset {
+		return fields
+	}
+
+	s.prev = s.windowSum / s.windowCount
+	for i := range s.window {
+		s.window[i] = 0
+	}
+	s.windowSum = 0
+	s.windowCount = 0
+	s.windowMax = 0
+	return fields
+}
+
+// quantile estimates the quantile of the window by linear interpolation
+// within the bucket holding it.  The largest value is the upper bound of the
+// values above the last bucket.
+func (s *histogramStat) quantile(q float64) int64 {
+	rank := q * float64(s.windowCount)
+
+	var cumulative uint64
+	for i, n := range s.window {
+		if n == 0 || float64(cumulative+n) < rank {
+			cumulative += n
+			continue
+		}
+
+		var lower, upper int64
+		if i > 0 {
+			lower = s.buckets[i-1]
+		}
+		if i < len(s.buckets) {
+			upper = s.buckets[i]
+		} else {
+			upper = s.windowMax
+		}
+		if upper > s.windowMax {
+			upper = s.windowMax
+		}
+		if upper < lower {
+			return upper
+		}
+		return lower + int64(float64(upper-lower)*(rank-float64(cumulative))/float64(n))
+	}
+	return s.windowMax
+}
+
+// prometheus returns the cumulative count of each bucket, keyed by its
+// upper bound, and the sum and count of the values since registration.
+func (s *histogramStat) prometheus() (bounds []string, counts []uint64, sum int64, count uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	bounds = make([]string, 0, len(s.counts))
+	counts = make([]uint64, 0, len(s.counts))
+	var cumulative uint64
+	for i, n := range s.counts {
+		cumulative += n
+		if i < len(s.buckets) {
+			bounds = append(bounds, strconv.FormatInt(s.buckets[i], 10))
+		} else {
+			bounds = append(bounds, "+Inf")
+		}
+		counts = append(counts, cumulative)
+	}
+	return bounds, counts, s.sum, s.count
+}
+
+func (s *histogramStat) Name() string {
+	return s.measurement
+}
+
+func (s *histogramStat) FieldName() string {
+	return s.field
+}
+
+// Tags returns a copy of the histogramStat's tags.
+// NOTE this allocates a new map every time it is called.
+func (s *histogramStat) Tags() map[string]string {
+	m := make(map[string]string, len(s.tags))
+	for k, v := range s.tags {
+		m[k] = v
+	}
+	return m
+}
@@ -0,0 +1,113 @@
+package selfstat
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// WritePrometheus writes all registered stats in the Prometheus text format.
+// The metrics are named "<measurement>_<field>", histogram stats are written
+// as histograms and all other stats as untyped metrics.  Writing the stats
+// does not reset timing and histogram stats.
+func WritePrometheus(w io.Writer) error {
+	type sample struct {
+		labels string
+		write  func(bw *bufio.Writer, name, labels string)
+	}
+
+	registry.mu.Lock()
+	families := make(map[string][]sample)
+	histograms := make(map[string]bool)
+	for _, stats := range registry.stats {
+		for _, stat := range stats {
+			name := prometheusName(stat.Name() + "_" + stat.FieldName())
+			labels := prometheusLabels(stat.Tags())
+
+			switch s := stat.(type) {
+			case *histogramStat:
+				bounds, counts, sum, count := s.prometheus()
+				histograms[name] = true
+				families[name] = append(families[name], sample{labels, func(bw *bufio.Writer, name, labels string) {
+					for i, bound := range bounds {
+						le := `le="` + bound + `"`
+						if labels != "" {
+							le = labels + "," + le
+						}
+						fmt.Fprintf(bw, "%s_bucket{%s} %d\n", name, le, counts[i])
+					}
+					fmt.Fprintf(bw, "%s_sum%s %d\n", name, braces(labels), sum)
+					fmt.Fprintf(bw, "%s_count%s %d\n", name, braces(labels), count)
+				}})
+			default:
+				var v int64
+				if t, ok := stat.(*timingStat); ok {
+					// Reading a timing stat clears it, use the last average
+					// so that the value is left for inputs.internal.
+					t.mu.Lock()
+					v = t.prev
+					t.mu.Unlock()
+				} else {
+					v = stat.Get()
+				}
+				families[name] = append(families[name], sample{labels, func(bw *bufio.Writer, name, labels string) {
+					fmt.Fprintf(bw, "%s%s %d\n", name, braces(labels), v)
+				}})
+			}
+		}
+	}
+	registry.mu.Unlock()
+
+	names := make([]string, 0, len(families))
+	for name := range families {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	bw := bufio.NewWriter(w)
+	for _, name := range names {
+		samples := families[name]
+		sort.Slice(samples, func(i, j int) bool { return samples[i].labels < samples[j].labels })
+
+		if histograms[name] {
+			fmt.Fprintf(bw, "# TYPE %s histogram\n", name)
+		} else {
+			fmt.Fprintf(bw, "# TYPE %s untyped\n", name)
+		}
+		for _, s := range samples {
+			s.write(bw, name, s.labels)
+		}
+	}
+	return bw.Flush()
+}
+
+// prometheusName replaces the characters not allowed in Prometheus metric
+// and label names with underscores.
+func prometheusName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' {
+			return r
+		}
+		return '_'
+	}, name)
+}
+
+// prometheusLabels returns the tags as sorted label pairs without braces.
+func prometheusLabels(tags map[string]string) string {
+	pairs := make([]string, 0, len(tags))
+	for k, v := range tags {
+		v = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(v)
+		pairs = append(pairs, prometheusName(k)+`="`+v+`"`)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+func braces(labels string) string {
+	if labels == "" {
+		return ""
+	}
+	return "{" + labels + "}"
+}
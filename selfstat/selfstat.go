@@ -76,8 +76,40 @@ func RegisterTiming(measurement, field string, tags map[string]string) Stat {
 	return registry.registerTiming("internal_"+measurement, field, tags)
 }
 
+// RegisterHistogram registers the given measurement, field, and tags in the
+// selfstat registry. If given an identical measurement, it will return the stat
+// that's already been registered.
+//
+// Histogram stats count the values added to them in buckets, given by their
+// upper bounds in increasing order.  Like timing stats, Get() returns the
+// average of the values since the previous call to Get().  In addition the
+// 50th, 90th and 99th percentiles of these values are returned as fields with
+// a "_p50", "_p90" and "_p99" suffix by Metrics(), and the buckets are
+// written as a Prometheus histogram by WritePrometheus().
+func RegisterHistogram(measurement, field string, tags map[string]string, buckets []int64) Stat {
+	return registry.registerHistogram("internal_"+measurement, field, tags, buckets)
+}
+
+// Unregister removes all stats of the given measurement and tags from the
+// selfstat registry, they are no longer returned by Metrics().  Registering
+// them again returns new stats.
+func Unregister(measurement string, tags map[string]string) {
+	registry.unregister("internal_"+measurement, tags)
+}
+
 // Metrics returns all registered stats as telegraf metrics.
 func Metrics() []telegraf.Metric {
+	return metrics(true)
+}
+
+// Snapshot returns all registered stats as telegraf metrics like Metrics,
+// but reading timing and histogram stats does not start a new window, so
+// the values reported by inputs.internal are left unchanged.
+func Snapshot() []telegraf.Metric {
+	return metrics(false)
+}
+
+func metrics(reset bool) []telegraf.Metric {
 	registry.mu.Lock()
 	now := time.Now()
 	metrics := make([]telegraf.Metric, len(registry.stats))
@@ -93,7 +125,20 @@ func Metrics() []telegraf.Metric {
 					tags = stat.Tags()
 					name = stat.Name()
 				}
-				fields[fieldname] = stat.Get()
+				switch st := stat.(type) {
+				case *histogramStat:
+					for k, v := range st.fields(reset) {
+						fields[k] = v
+					}
+				case *timingStat:
+					if reset {
+						fields[fieldname] = st.Get()
+					} else {
+						fields[fieldname] = st.peek()
+					}
+				default:
+					fields[fieldname] = stat.Get()
+				}
 				j++
 			}
 			metric, err := metric.New(name, tags, fields, now)
@@ -160,6 +205,32 @@ func (r *rgstry) registerTiming(measurement, field string, tags map[string]strin
 	return s
 }
 
+func (r *rgstry) registerHistogram(measurement, field string, tags map[string]string, buckets []int64) Stat {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := key(measurement, tags)
+	if stat, ok := registry.get(key, field); ok {
+		return stat
+	}
+
+	t := make(map[string]string, len(tags))
+	for k, v := range tags {
+		t[k] = v
+	}
+
+	s := newHistogramStat(measurement, field, t, buckets)
+	registry.set(key, s)
+	return s
+}
+
+func (r *rgstry) unregister(measurement string, tags map[string]string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.stats, key(measurement, tags))
+}
+
 func (r *rgstry) get(key uint64, field string) (Stat, bool) {
 	if _, ok := r.stats[key]; !ok {
 		return nil, false
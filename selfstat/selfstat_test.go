@@ -1,6 +1,7 @@
 package selfstat
 
 import (
+	"bytes"
 	"sync"
 	"testing"
 
@@ -108,6 +109,121 @@ func TestRegisterTimingAndIncrAndSet(t *testing.T) {
 	assert.Equal(t, "internal_test", foo.Name())
 }
 
+func TestRegisterHistogram(t *testing.T) {
+	testLock.Lock()
+	defer testCleanup()
+	s := RegisterHistogram("test", "test_field_ns", map[string]string{"test": "foo"},
+		[]int64{10, 100, 1000})
+	require.Equal(t, int64(0), s.Get())
+
+	for v := int64(1); v <= 100; v++ {
+		s.Incr(v * 10)
+	}
+
+	metrics := Metrics()
+	require.Len(t, metrics, 1)
+	fields := metrics[0].Fields()
+	require.Equal(t, int64(505), fields["test_field_ns"])
+	require.InDelta(t, 500, fields["test_field_ns_p50"], 10)
+	require.InDelta(t, 900, fields["test_field_ns_p90"], 10)
+	require.InDelta(t, 990, fields["test_field_ns_p99"], 10)
+
+	// Without new values the previous average is kept, and no percentiles
+	// are reported.
+	fields = Metrics()[0].Fields()
+	require.Equal(t, map[string]interface{}{"test_field_ns": int64(505)}, fields)
+
+	// Values above the last bucket are bounded by the largest value
+	s.Incr(5000)
+	fields = Metrics()[0].Fields()
+	require.InDelta(t, 5000, fields["test_field_ns_p99"], 50)
+
+	// make sure that the same field returns the same metric
+	foo := RegisterHistogram("test", "test_field_ns", map[string]string{"test": "foo"}, nil)
+	require.Equal(t, s, foo)
+}
+
+func TestUnregister(t *testing.T) {
+	testLock.Lock()
+	defer testCleanup()
+
+	foo := Register("test", "test_field", map[string]string{"test": "foo"})
+	foo.Incr(1)
+	bar := Register("test", "test_field", map[string]string{"test": "bar"})
+	bar.Incr(2)
+
+	Unregister("test", map[string]string{"test": "foo"})
+	metrics := Metrics()
+	require.Len(t, metrics, 1)
+	require.Equal(t, map[string]string{"test": "bar"}, metrics[0].Tags())
+
+	// Registering again returns a new stat
+	foo = Register("test", "test_field", map[string]string{"test": "foo"})
+	require.Equal(t, int64(0), foo.Get())
+}
+
+func TestSnapshot(t *testing.T) {
+	testLock.Lock()
+	defer testCleanup()
+
+	h := RegisterHistogram("gather", "gather_time_ns", map[string]string{}, []int64{10, 100})
+	h.Incr(20)
+	h.Incr(40)
+	timing := RegisterTiming("write", "write_time_ns", map[string]string{})
+	timing.Incr(42)
+
+	for i := 0; i < 2; i++ {
+		for _, m := range Snapshot() {
+			switch m.Name() {
+			case "internal_gather":
+				require.Equal(t, int64(30), m.Fields()["gather_time_ns"])
+				require.Contains(t, m.Fields(), "gather_time_ns_p50")
+			case "internal_write":
+				require.Equal(t, int64(42), m.Fields()["write_time_ns"])
+			}
+		}
+	}
+
+	// Taking a snapshot does not reset the stats
+	require.Equal(t, int64(30), h.Get())
+	require.Equal(t, int64(42), timing.Get())
+}
+
+func TestWritePrometheus(t *testing.T) {
+	testLock.Lock()
+	defer testCleanup()
+
+	h := RegisterHistogram("gather", "gather_time_ns", map[string]string{"input": "cpu"},
+		[]int64{10, 100})
+	h.Incr(5)
+	h.Incr(50)
+	h.Incr(500)
+	c := Register("agent", "metrics_gathered", map[string]string{})
+	c.Incr(3)
+	timing := RegisterTiming("write", "write_time_ns", map[string]string{"output": `a"b`})
+	timing.Incr(42)
+	timing.Get()
+
+	var buf bytes.Buffer
+	require.NoError(t, WritePrometheus(&buf))
+
+	expected := `# TYPE internal_agent_metrics_gathered untyped
+internal_agent_metrics_gathered 3
+# TYPE internal_gather_gather_time_ns histogram
+internal_gather_gather_time_ns_bucket{input="cpu",le="10"} 1
+internal_gather_gather_time_ns_bucket{input="cpu",le="100"} 2
+internal_gather_gather_time_ns_bucket{input="cpu",le="+Inf"} 3
+internal_gather_gather_time_ns_sum{input="cpu"} 555
+internal_gather_gather_time_ns_count{input="cpu"} 3
+# TYPE internal_write_write_time_ns untyped
+internal_write_write_time_ns{output="a\"b"} 42
+`
+	require.Equal(t, expected, buf.String())
+
+	// Writing does not reset the stats
+	require.Equal(t, int64(185), h.Get())
+}
+
 func TestStatKeyConsistency(t *testing.T) {
 	lhs := key("internal_stats", map[string]string{
 		"foo":   "bar",